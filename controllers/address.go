@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type addressController struct {
+	addressService services.AddressServiceInterface
+}
+
+type AddressControllerInterface interface {
+	GetMyAddresses(c *gin.Context)
+	GetMyAddressById(c *gin.Context)
+	CreateMyAddress(c *gin.Context)
+	UpdateMyAddress(c *gin.Context)
+	DeleteMyAddress(c *gin.Context)
+}
+
+func NewAddressController(addressService services.AddressServiceInterface) AddressControllerInterface {
+	return &addressController{
+		addressService: addressService,
+	}
+}
+
+// newAddress copies an address register into an address of customer
+func newAddress(addressRegister models.AddressRegister, customer *models.CustomerClaims) models.Address {
+	return models.Address{
+		CustomerID:    customer.ID,
+		Label:         addressRegister.Label,
+		RecipientName: addressRegister.RecipientName,
+		Phone:         addressRegister.Phone,
+		Street:        addressRegister.Street,
+		City:          addressRegister.City,
+		Province:      addressRegister.Province,
+		PostalCode:    addressRegister.PostalCode,
+		Region:        addressRegister.Region,
+		IsDefault:     addressRegister.IsDefault,
+	}
+}
+
+// GetMyAddresses godoc
+// @Summary List my addresses
+// @Description Lists the address book of the logged in customer, the default address first
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me/addresses [get]
+func (ac *addressController) GetMyAddresses(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	customerID := v.(*models.CustomerClaims).ID
+	response, err := ac.addressService.GetAddresses(customerID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, customerID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, customerID, *response)
+}
+
+// GetMyAddressById godoc
+// @Summary Get my address
+// @Description Get an address of the logged in customer
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Address ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me/addresses/{id} [get]
+func (ac *addressController) GetMyAddressById(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	response, err := ac.addressService.GetAddressById(id, v.(*models.CustomerClaims).ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
+
+// CreateMyAddress godoc
+// @Summary Add an address
+// @Description Adds an address to the address book of the logged in customer, the first address is the default one
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param address body models.AddressRegister true "Address"
+// @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me/addresses [post]
+func (ac *addressController) CreateMyAddress(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var addressRegister models.AddressRegister
+	if err := c.ShouldBindJSON(&addressRegister); err != nil {
+		middleware.Response(c, addressRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	address := newAddress(addressRegister, customer)
+	address.CreatedBy = customer.Name
+
+	response, err := ac.addressService.CreateAddress(&address)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, addressRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, addressRegister, *response)
+}
+
+// UpdateMyAddress godoc
+// @Summary Update my address
+// @Description Replaces an address of the logged in customer, is_default makes it the default address. Orders keep the address they were shipped to.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Address ID"
+// @Param address body models.AddressRegister true "Address"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me/addresses/{id} [put]
+func (ac *addressController) UpdateMyAddress(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var addressRegister models.AddressRegister
+	if err := c.ShouldBindJSON(&addressRegister); err != nil {
+		middleware.Response(c, addressRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	address := newAddress(addressRegister, customer)
+	address.ID = c.Param("id")
+	address.UpdatedBy = &customer.Name
+
+	response, err := ac.addressService.UpdateAddress(&address)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, addressRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, addressRegister, *response)
+}
+
+// DeleteMyAddress godoc
+// @Summary Delete my address
+// @Description Deletes an address of the logged in customer, the latest remaining address becomes the default one when it was the default
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Address ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me/addresses/{id} [delete]
+func (ac *addressController) DeleteMyAddress(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	response, err := ac.addressService.DeleteAddress(id, v.(*models.CustomerClaims).ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
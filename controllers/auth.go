@@ -16,6 +16,13 @@ type authController struct {
 
 type AuthControllerInterface interface {
 	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	VerifyEmail(c *gin.Context)
+	ResendVerifyEmail(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	JWKS(c *gin.Context)
 }
 
 func NewAuthController(authService services.AuthServiceInterface) AuthControllerInterface {
@@ -33,6 +40,9 @@ func NewAuthController(authService services.AuthServiceInterface) AuthController
 // @Param body body models.AuthLogin true "Auth"
 // @Success 200 {object} models.Response
 // @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 429 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /auth/login [post]
 func (ac *authController) Login(c *gin.Context) {
@@ -51,7 +61,7 @@ func (ac *authController) Login(c *gin.Context) {
 		Password: authLogin.Password,
 	}
 
-	response, err := ac.authService.Login(&auth)
+	response, err := ac.authService.Login(&auth, c.ClientIP())
 	if err != nil {
 		logger.Err(err.Error())
 		middleware.Response(c, authLogin, models.Response{
@@ -63,3 +73,242 @@ func (ac *authController) Login(c *gin.Context) {
 
 	middleware.Response(c, authLogin, *response)
 }
+
+// Refresh godoc
+// @Summary Refresh the access token
+// @Description Exchange a refresh token for a new access token and a new refresh token
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body models.AuthRefresh true "Refresh token"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /auth/refresh [post]
+func (ac *authController) Refresh(c *gin.Context) {
+	var authRefresh models.AuthRefresh
+	if err := c.ShouldBindJSON(&authRefresh); err != nil {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := ac.authService.Refresh(authRefresh.RefreshToken)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	middleware.Response(c, "", *response)
+}
+
+// Logout godoc
+// @Summary Logout a customer
+// @Description Revoke the access token and the given refresh token
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param body body models.AuthLogout false "Refresh token"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /auth/logout [post]
+func (ac *authController) Logout(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	// the body is optional, without refresh token only the access token is revoked
+	var authLogout models.AuthLogout
+	_ = c.ShouldBindJSON(&authLogout)
+
+	customer := v.(*models.CustomerClaims)
+	response, err := ac.authService.Logout(customer, authLogout.RefreshToken)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, customer.ID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	middleware.Response(c, customer.ID, *response)
+}
+
+// VerifyEmail godoc
+// @Summary Verify the email of a customer
+// @Description Use the token mailed at registration to verify the email
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body models.AuthVerifyEmail true "Verification token"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /auth/verify-email [post]
+func (ac *authController) VerifyEmail(c *gin.Context) {
+	var authVerifyEmail models.AuthVerifyEmail
+	if err := c.ShouldBindJSON(&authVerifyEmail); err != nil {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := ac.authService.VerifyEmail(authVerifyEmail.Token)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	middleware.Response(c, "", *response)
+}
+
+// ResendVerifyEmail godoc
+// @Summary Resend the verification email
+// @Description Mail a new verification token, the previous ones stop working
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body models.AuthEmail true "Email"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /auth/verify-email/resend [post]
+func (ac *authController) ResendVerifyEmail(c *gin.Context) {
+	var authEmail models.AuthEmail
+	if err := c.ShouldBindJSON(&authEmail); err != nil {
+		middleware.Response(c, authEmail, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := ac.authService.ResendVerifyEmail(authEmail.Email)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, authEmail, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	middleware.Response(c, authEmail, *response)
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Mail a password reset token to the customer
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body models.AuthEmail true "Email"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /auth/forgot-password [post]
+func (ac *authController) ForgotPassword(c *gin.Context) {
+	var authEmail models.AuthEmail
+	if err := c.ShouldBindJSON(&authEmail); err != nil {
+		middleware.Response(c, authEmail, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := ac.authService.ForgotPassword(authEmail.Email)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, authEmail, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	middleware.Response(c, authEmail, *response)
+}
+
+// ResetPassword godoc
+// @Summary Reset the password of a customer
+// @Description Use the mailed token to set a new password, every session of the customer is signed out
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param body body models.AuthResetPassword true "Token and new password"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /auth/reset-password [post]
+func (ac *authController) ResetPassword(c *gin.Context) {
+	var authResetPassword models.AuthResetPassword
+	if err := c.ShouldBindJSON(&authResetPassword); err != nil {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := ac.authService.ResetPassword(&authResetPassword)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	middleware.Response(c, "", *response)
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys to verify the access tokens, empty when tokens are signed with HS256
+// @Tags auth
+// @Produce  json
+// @Success 200 {object} middleware.JWKS
+// @Failure 500 {object} models.Response
+// @Router /.well-known/jwks.json [get]
+func (ac *authController) JWKS(c *gin.Context) {
+	jwks, err := middleware.GetJWKS()
+	if err != nil {
+		logger.Err(err.Error())
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
@@ -19,6 +19,8 @@ type CartControllerInterface interface {
 	UpdateCart(c *gin.Context)
 	GetCartByCustomerID(c *gin.Context)
 	DeleteCart(c *gin.Context)
+	ApplyCoupon(c *gin.Context)
+	RemoveCoupon(c *gin.Context)
 }
 
 func NewCartController(cartService services.CartServiceInterface) CartControllerInterface {
@@ -29,7 +31,7 @@ func NewCartController(cartService services.CartServiceInterface) CartController
 
 // CreateCart godoc
 // @Summary Create a cart
-// @Description Create a cart
+// @Description Create a cart, a product with variants is added as one of them with variant_id. The line is priced with the current price of the product.
 // @Tags carts
 // @Accept  json
 // @Produce  json
@@ -38,6 +40,9 @@ func NewCartController(cartService services.CartServiceInterface) CartController
 // @Failure 500 {object} models.Response
 // @Failure 400 {object} models.Response
 // @Failure 302 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Router /carts [post]
 func (cc *cartController) CreateCart(c *gin.Context) {
 	v, ok := c.Get("customer")
@@ -64,10 +69,12 @@ func (cc *cartController) CreateCart(c *gin.Context) {
 		CustomerID: customer.ID,
 		ProductID:  cartRegister.ProductID,
 		Qty:        cartRegister.Qty,
-		Price:      cartRegister.Price,
 		Status:     cartRegister.Status,
 		CreatedBy:  customer.Email,
 	}
+	if cartRegister.VariantID != "" {
+		cart.VariantID = &cartRegister.VariantID
+	}
 
 	response, err := cc.cartService.CreateCart(&cart)
 	if err != nil {
@@ -91,7 +98,10 @@ func (cc *cartController) CreateCart(c *gin.Context) {
 // @Produce  json
 // @Security ApiKeyAuth
 // @Param id path string true "Cart ID"
+// @Param currency query string false "Currency of the prices, the X-Currency header works too"
+// @Param region query string false "Tax region, the X-Region header works too"
 // @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
 // @Failure 404 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /carts/{id} [get]
@@ -107,7 +117,7 @@ func (cc *cartController) GetCartByCustomerID(c *gin.Context) {
 
 	customer := v.(*models.CustomerClaims)
 
-	response, err := cc.cartService.GetCartByCustomerID(customer.ID)
+	response, err := cc.cartService.GetCartByCustomerID(customer.ID, middleware.RequestCurrency(c), middleware.RequestRegion(c))
 	if err != nil {
 		logger.Err(err.Error())
 		middleware.Response(c, customer.ID, models.Response{
@@ -123,7 +133,7 @@ func (cc *cartController) GetCartByCustomerID(c *gin.Context) {
 
 // UpdateCart godoc
 // @Summary Update a cart
-// @Description Update a cart
+// @Description Update the qty of a cart, the line is priced again with the current price of the product
 // @Tags carts
 // @Accept  json
 // @Produce  json
@@ -132,6 +142,9 @@ func (cc *cartController) GetCartByCustomerID(c *gin.Context) {
 // @Failure 500 {object} models.Response
 // @Failure 400 {object} models.Response
 // @Failure 302 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Router /carts/{id} [put]
 func (cc *cartController) UpdateCart(c *gin.Context) {
 	v, ok := c.Get("customer")
@@ -164,6 +177,7 @@ func (cc *cartController) UpdateCart(c *gin.Context) {
 	}
 
 	cartUpdate.ID = id
+	cartUpdate.CustomerID = v.(*models.CustomerClaims).ID
 	cartUpdate.UpdatedBy = v.(*models.CustomerClaims).Name
 	response, err := cc.cartService.UpdateCart(&cartUpdate)
 	if err != nil {
@@ -222,8 +236,9 @@ func (cc *cartController) DeleteCart(c *gin.Context) {
 	}
 
 	cartDelete := models.CartUpdate{
-		ID:        id,
-		UpdatedBy: customer.Name,
+		ID:         id,
+		CustomerID: customer.ID,
+		UpdatedBy:  customer.Name,
 	}
 	response, err := cc.cartService.DeleteCart(&cartDelete)
 	if err != nil {
@@ -238,3 +253,90 @@ func (cc *cartController) DeleteCart(c *gin.Context) {
 
 	middleware.Response(c, id, *response)
 }
+
+// ApplyCoupon godoc
+// @Summary Apply a coupon to the cart
+// @Description Applies the promotion of a code to the cart of the logged in customer, it is redeemed at checkout
+// @Tags carts
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param coupon body models.CouponRegister true "Coupon"
+// @Param currency query string false "Currency of the prices, the X-Currency header works too"
+// @Param region query string false "Tax region, the X-Region header works too"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /carts/coupon [post]
+func (cc *cartController) ApplyCoupon(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	var couponRegister models.CouponRegister
+	if err := c.ShouldBindJSON(&couponRegister); err != nil {
+		middleware.Response(c, couponRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := cc.cartService.ApplyCoupon(customer.ID, couponRegister.Code, customer.Name, middleware.RequestCurrency(c), middleware.RequestRegion(c))
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, couponRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, couponRegister, *response)
+}
+
+// RemoveCoupon godoc
+// @Summary Remove the coupon of the cart
+// @Description Removes the coupon applied to the cart of the logged in customer
+// @Tags carts
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /carts/coupon [delete]
+func (cc *cartController) RemoveCoupon(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	response, err := cc.cartService.RemoveCoupon(customer.ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, customer.ID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, customer.ID, *response)
+}
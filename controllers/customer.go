@@ -20,6 +20,9 @@ type CustomerControllerInterface interface {
 	GetCustomers(c *gin.Context)
 	UpdateCustomer(c *gin.Context)
 	DeleteCustomer(c *gin.Context)
+	GetMe(c *gin.Context)
+	UpdateMe(c *gin.Context)
+	ChangeMyPassword(c *gin.Context)
 }
 
 func NewCustomerController(customerService services.CustomerServiceInterface) CustomerControllerInterface {
@@ -39,6 +42,7 @@ func NewCustomerController(customerService services.CustomerServiceInterface) Cu
 // @Failure 500 {object} models.Response
 // @Failure 400 {object} models.Response
 // @Failure 302 {object} models.Response
+// @Failure 409 {object} models.Response
 // @Router /customers [post]
 func (cc *customerController) CreateCustomer(c *gin.Context) {
 	var customerRegister models.CustomerRegister
@@ -83,8 +87,18 @@ func (cc *customerController) CreateCustomer(c *gin.Context) {
 // @Success 200 {object} models.Response
 // @Failure 404 {object} models.Response
 // @Failure 500 {object} models.Response
+// @Failure 403 {object} models.Response
 // @Router /customers/{id} [get]
 func (cc *customerController) GetCustomerById(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
 	id := c.Param("id")
 	if id == "" {
 		middleware.Response(c, id, models.Response{
@@ -95,6 +109,15 @@ func (cc *customerController) GetCustomerById(c *gin.Context) {
 		return
 	}
 
+	customerClaims := v.(*models.CustomerClaims)
+	if !customerClaims.IsAdmin() && customerClaims.ID != id {
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusForbidden,
+			Message: http.StatusText(http.StatusForbidden),
+		})
+		return
+	}
+
 	response, err := cc.customerService.GetCustomerById(id)
 	if err != nil {
 		logger.Err(err.Error())
@@ -120,6 +143,7 @@ func (cc *customerController) GetCustomerById(c *gin.Context) {
 // @Success 200 {object} models.Response
 // @Failure 404 {object} models.Response
 // @Failure 500 {object} models.Response
+// @Failure 403 {object} models.Response
 // @Router /customers [get]
 func (cc *customerController) GetCustomers(c *gin.Context) {
 	filter := c.Request.URL.Query()
@@ -148,6 +172,7 @@ func (cc *customerController) GetCustomers(c *gin.Context) {
 // @Failure 500 {object} models.Response
 // @Failure 400 {object} models.Response
 // @Failure 302 {object} models.Response
+// @Failure 403 {object} models.Response
 // @Router /customers/{id} [put]
 func (cc *customerController) UpdateCustomer(c *gin.Context) {
 	v, ok := c.Get("customer")
@@ -179,8 +204,22 @@ func (cc *customerController) UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	customerClaims := v.(*models.CustomerClaims)
+	if !customerClaims.IsAdmin() {
+		if customerClaims.ID != id {
+			middleware.Response(c, id, models.Response{
+				Code:    http.StatusForbidden,
+				Message: http.StatusText(http.StatusForbidden),
+			})
+			return
+		}
+		// only admins can change roles and statuses
+		customerUpdate.Role = ""
+		customerUpdate.Status = ""
+	}
+
 	customerUpdate.ID = id
-	customerUpdate.UpdatedBy = v.(*models.CustomerClaims).Name
+	customerUpdate.UpdatedBy = customerClaims.Name
 	response, err := cc.customerService.UpdateCustomer(&customerUpdate)
 	if err != nil {
 		logger.Err(err.Error())
@@ -206,6 +245,7 @@ func (cc *customerController) UpdateCustomer(c *gin.Context) {
 // @Success 200 {object} models.Response
 // @Failure 404 {object} models.Response
 // @Failure 500 {object} models.Response
+// @Failure 403 {object} models.Response
 // @Router /customers/{id} [delete]
 func (cc *customerController) DeleteCustomer(c *gin.Context) {
 	v, ok := c.Get("customer")
@@ -237,6 +277,14 @@ func (cc *customerController) DeleteCustomer(c *gin.Context) {
 		return
 	}
 
+	if !customerClaims.IsAdmin() && customerClaims.ID != id {
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusForbidden,
+			Message: http.StatusText(http.StatusForbidden),
+		})
+		return
+	}
+
 	customerDelete := models.CustomerUpdate{
 		ID:        id,
 		UpdatedBy: customerClaims.Name,
@@ -254,3 +302,141 @@ func (cc *customerController) DeleteCustomer(c *gin.Context) {
 
 	middleware.Response(c, id, *response)
 }
+
+// GetMe godoc
+// @Summary Get my profile
+// @Description Get the profile of the logged in customer
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me [get]
+func (cc *customerController) GetMe(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	customerClaims := v.(*models.CustomerClaims)
+	response, err := cc.customerService.GetCustomerById(customerClaims.ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, customerClaims.ID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, customerClaims.ID, *response)
+}
+
+// UpdateMe godoc
+// @Summary Update my profile
+// @Description Update the name and the email of the logged in customer, a new email has to be verified again
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param profile body models.CustomerProfileUpdate true "Profile"
+// @Success 200 {object} models.Response
+// @Failure 302 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Router /me [put]
+func (cc *customerController) UpdateMe(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var profile models.CustomerProfileUpdate
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		middleware.Response(c, profile, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	customerClaims := v.(*models.CustomerClaims)
+	response, err := cc.customerService.UpdateProfile(customerClaims, &profile)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, profile, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, profile, *response)
+}
+
+// ChangeMyPassword godoc
+// @Summary Change my password
+// @Description Change the password of the logged in customer, every other session is signed out and a new token pair is returned
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param body body models.CustomerPasswordChange true "Current and new password"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /me/password [post]
+func (cc *customerController) ChangeMyPassword(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	customerClaims := v.(*models.CustomerClaims)
+
+	var passwordChange models.CustomerPasswordChange
+	if err := c.ShouldBindJSON(&passwordChange); err != nil {
+		middleware.Response(c, customerClaims.ID, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := cc.customerService.ChangePassword(customerClaims, &passwordChange)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, customerClaims.ID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, customerClaims.ID, *response)
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/money"
+	"mvp-shop-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type exchangeRateController struct {
+	exchangeRateService services.ExchangeRateServiceInterface
+}
+
+type ExchangeRateControllerInterface interface {
+	GetExchangeRates(c *gin.Context)
+	SaveExchangeRate(c *gin.Context)
+	DeleteExchangeRate(c *gin.Context)
+}
+
+func NewExchangeRateController(exchangeRateService services.ExchangeRateServiceInterface) ExchangeRateControllerInterface {
+	return &exchangeRateController{
+		exchangeRateService: exchangeRateService,
+	}
+}
+
+// GetExchangeRates godoc
+// @Summary List the exchange rates
+// @Description Lists the currencies the shop sells in with the amount one unit of the default currency buys
+// @Tags exchangeRates
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /exchange-rates [get]
+func (ec *exchangeRateController) GetExchangeRates(c *gin.Context) {
+	response, err := ec.exchangeRateService.GetExchangeRates()
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, "", *response)
+}
+
+// SaveExchangeRate godoc
+// @Summary Set an exchange rate
+// @Description Creates or replaces the rate of a currency, the amount one unit of the default currency buys
+// @Tags exchangeRates
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param currency path string true "Currency"
+// @Param rate body models.ExchangeRateUpdate true "Rate"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /exchange-rates/{currency} [put]
+func (ec *exchangeRateController) SaveExchangeRate(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	currency, err := money.ParseCurrency(c.Param("currency"))
+	if err != nil {
+		middleware.Response(c, c.Param("currency"), models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var exchangeRateUpdate models.ExchangeRateUpdate
+	if err := c.ShouldBindJSON(&exchangeRateUpdate); err != nil {
+		middleware.Response(c, exchangeRateUpdate, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	rate := models.ExchangeRate{
+		Currency:  currency,
+		Rate:      exchangeRateUpdate.Rate,
+		CreatedBy: v.(*models.CustomerClaims).Name,
+	}
+	response, err := ec.exchangeRateService.SaveExchangeRate(&rate)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, exchangeRateUpdate, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, exchangeRateUpdate, *response)
+}
+
+// DeleteExchangeRate godoc
+// @Summary Delete an exchange rate
+// @Description Deletes the rate of a currency, the shop stops selling in it
+// @Tags exchangeRates
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param currency path string true "Currency"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /exchange-rates/{currency} [delete]
+func (ec *exchangeRateController) DeleteExchangeRate(c *gin.Context) {
+	currency, err := money.ParseCurrency(c.Param("currency"))
+	if err != nil {
+		middleware.Response(c, c.Param("currency"), models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response, err := ec.exchangeRateService.DeleteExchangeRate(currency)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, currency, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, currency, *response)
+}
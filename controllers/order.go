@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"mvp-shop-backend/middleware"
 	"mvp-shop-backend/models"
 	"mvp-shop-backend/pkg/logger"
@@ -16,6 +18,12 @@ type orderController struct {
 
 type OrderControllerInterface interface {
 	CreateOrder(c *gin.Context)
+	CheckoutOrder(c *gin.Context)
+	GetMyOrders(c *gin.Context)
+	GetMyOrderByInvoice(c *gin.Context)
+	CancelOrder(c *gin.Context)
+	ShipOrder(c *gin.Context)
+	DeliverOrder(c *gin.Context)
 }
 
 func NewOrderController(orderService services.OrderServiceInterface) OrderControllerInterface {
@@ -26,14 +34,18 @@ func NewOrderController(orderService services.OrderServiceInterface) OrderContro
 
 // CreateOrder godoc
 // @Summary Create an order
-// @Description Creates a new order
+// @Description Creates a new order shipped to address_id, or to the default address, with shipping_method
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Param order body models.OrderRegister true "Order"
+// @Param currency query string false "Currency of the order, the X-Currency header works too"
+// @Param region query string false "Tax region of an order without shipping address, the X-Region header works too"
 // @Success 201 {object} models.Response
 // @Failure 400 {object} models.Response
 // @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /orders [post]
 func (oc *orderController) CreateOrder(c *gin.Context) {
@@ -57,10 +69,16 @@ func (oc *orderController) CreateOrder(c *gin.Context) {
 	}
 
 	order := models.Order{
-		Payment:    orderRegister.Payment,
 		CustomerID: v.(*models.CustomerClaims).ID,
+		Currency:   middleware.RequestCurrency(c),
 		CreatedBy:  v.(*models.CustomerClaims).Name,
 	}
+	if region := middleware.RequestRegion(c); region != "" {
+		order.Region = &region
+	}
+	if orderRegister.CouponCode != "" {
+		order.CouponCode = &orderRegister.CouponCode
+	}
 
 	orderDetail := make([]models.OrderDetail, len(orderRegister.Products))
 	for i, e := range orderRegister.Products {
@@ -68,9 +86,13 @@ func (oc *orderController) CreateOrder(c *gin.Context) {
 			ProductID: e.ProductID,
 			Qty:       e.Qty,
 		}
+		if e.VariantID != "" {
+			variantID := e.VariantID
+			orderDetail[i].VariantID = &variantID
+		}
 	}
 
-	response, err := oc.orderService.CreateOrder(&order, &orderDetail)
+	response, err := oc.orderService.CreateOrder(&order, &orderDetail, orderRegister.AddressID, orderRegister.ShippingMethod)
 	if err != nil {
 		logger.Err(err.Error())
 		middleware.Response(c, orderRegister, models.Response{
@@ -83,3 +105,292 @@ func (oc *orderController) CreateOrder(c *gin.Context) {
 
 	middleware.Response(c, orderRegister, *response)
 }
+
+// CheckoutOrder godoc
+// @Summary Checkout the cart
+// @Description Creates a new order from the active cart of the logged in customer, the coupon applied to the cart is redeemed. The order is shipped to address_id, or to the default address, with shipping_method.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param checkout body models.OrderCheckout false "Address and shipping method"
+// @Param currency query string false "Currency of the order, the X-Currency header works too"
+// @Param region query string false "Tax region of an order without shipping address, the X-Region header works too"
+// @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders/checkout [post]
+func (oc *orderController) CheckoutOrder(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	// the body is optional, the cart is then shipped to the default address
+	var orderCheckout models.OrderCheckout
+	if err := c.ShouldBindJSON(&orderCheckout); err != nil && !errors.Is(err, io.EOF) {
+		middleware.Response(c, orderCheckout, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	order := models.Order{
+		CustomerID: customer.ID,
+		Currency:   middleware.RequestCurrency(c),
+		CreatedBy:  customer.Name,
+	}
+	if region := middleware.RequestRegion(c); region != "" {
+		order.Region = &region
+	}
+
+	response, err := oc.orderService.CheckoutOrder(&order, orderCheckout.AddressID, orderCheckout.ShippingMethod)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, customer.ID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, customer.ID, *response)
+}
+
+// GetMyOrders godoc
+// @Summary List my orders
+// @Description List orders of the logged in customer
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param collection query []string false "string collection" collectionFormat(multi)
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders [get]
+func (oc *orderController) GetMyOrders(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	filter := c.Request.URL.Query()
+	response, err := oc.orderService.GetMyOrders(filter, v.(*models.CustomerClaims).ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, filter, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, filter, *response)
+}
+
+// GetMyOrderByInvoice godoc
+// @Summary Get my order by invoice
+// @Description Get an order of the logged in customer with its details, the invoice must be url encoded
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param invoice path string true "Invoice"
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders/{invoice} [get]
+func (oc *orderController) GetMyOrderByInvoice(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	invoice := c.Param("invoice")
+	if invoice == "" {
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := oc.orderService.GetMyOrderByInvoice(invoice, v.(*models.CustomerClaims).ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, invoice, *response)
+}
+
+// CancelOrder godoc
+// @Summary Cancel my order
+// @Description Cancel a pending or paid order of the logged in customer and restore the product stock, the payment of a paid order is refunded
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param invoice path string true "Invoice"
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders/{invoice}/cancel [post]
+func (oc *orderController) CancelOrder(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	invoice := c.Param("invoice")
+	if invoice == "" {
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	response, err := oc.orderService.CancelOrder(invoice, customer.ID, customer.Name)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, invoice, *response)
+}
+
+// ShipOrder godoc
+// @Summary Ship an order
+// @Description Move a paid order to shipped
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param invoice path string true "Invoice"
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders/{invoice}/ship [post]
+func (oc *orderController) ShipOrder(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	invoice := c.Param("invoice")
+	if invoice == "" {
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	response, err := oc.orderService.ShipOrder(invoice, customer.Name)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, invoice, *response)
+}
+
+// DeliverOrder godoc
+// @Summary Deliver an order
+// @Description Move a shipped order to delivered
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param invoice path string true "Invoice"
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders/{invoice}/deliver [post]
+func (oc *orderController) DeliverOrder(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	invoice := c.Param("invoice")
+	if invoice == "" {
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	response, err := oc.orderService.DeliverOrder(invoice, customer.Name)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, invoice, *response)
+}
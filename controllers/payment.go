@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type paymentController struct {
+	paymentService services.PaymentServiceInterface
+}
+
+type PaymentControllerInterface interface {
+	PayOrder(c *gin.Context)
+	ConfirmPayment(c *gin.Context)
+	Webhook(c *gin.Context)
+}
+
+func NewPaymentController(paymentService services.PaymentServiceInterface) PaymentControllerInterface {
+	return &paymentController{
+		paymentService: paymentService,
+	}
+}
+
+// PayOrder godoc
+// @Summary Pay my order
+// @Description Creates a payment intent with the payment provider for a pending order of the logged in customer
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param invoice path string true "Invoice"
+// @Success 201 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /orders/{invoice}/pay [post]
+func (pc *paymentController) PayOrder(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	invoice := c.Param("invoice")
+	if invoice == "" {
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Data:    nil,
+		})
+		return
+	}
+
+	customer := v.(*models.CustomerClaims)
+	response, err := pc.paymentService.PayOrder(invoice, customer.ID, customer.Name)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, invoice, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, invoice, *response)
+}
+
+// ConfirmPayment godoc
+// @Summary Confirm my payment
+// @Description Asks the payment provider to capture a pending payment, the order is paid once the provider webhook is received
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Payment ID"
+// @Success 200 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /payments/{id}/confirm [post]
+func (pc *paymentController) ConfirmPayment(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	if id == "" {
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := pc.paymentService.ConfirmPayment(id, v.(*models.CustomerClaims).ID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
+
+// Webhook godoc
+// @Summary Payment provider webhook
+// @Description Receives the signed events of the payment provider
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param X-Payment-Signature header string true "Payload signature"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /payments/webhook [post]
+func (pc *paymentController) Webhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := pc.paymentService.HandleWebhook(payload, c.GetHeader("X-Payment-Signature"))
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, string(payload), models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, string(payload), *response)
+}
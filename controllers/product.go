@@ -4,6 +4,7 @@ import (
 	"mvp-shop-backend/middleware"
 	"mvp-shop-backend/models"
 	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/money"
 	"mvp-shop-backend/services"
 	"net/http"
 
@@ -20,6 +21,14 @@ type ProductControllerInterface interface {
 	GetProductById(c *gin.Context)
 	UpdateProduct(c *gin.Context)
 	DeleteProduct(c *gin.Context)
+	SaveProductPrice(c *gin.Context)
+	DeleteProductPrice(c *gin.Context)
+	CreateProductVariant(c *gin.Context)
+	UpdateProductVariant(c *gin.Context)
+	DeleteProductVariant(c *gin.Context)
+	UploadProductImage(c *gin.Context)
+	ReorderProductImages(c *gin.Context)
+	DeleteProductImage(c *gin.Context)
 }
 
 func NewProductController(productService services.ProductServiceInterface) ProductControllerInterface {
@@ -30,15 +39,30 @@ func NewProductController(productService services.ProductServiceInterface) Produ
 
 // CreateProduct godoc
 // @Summary Create a product
-// @Description Create a product
+// @Description Create a product, the slug is made from the name when it is empty and the attributes are checked against the attribute schema of the category
 // @Tags products
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param product body models.ProductRegister true "Product"
 // @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
 // @Failure 500 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Router /products [post]
 func (pc *productController) CreateProduct(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
 	var productRegister models.ProductRegister
 	if err := c.ShouldBindJSON(&productRegister); err != nil {
 		middleware.Response(c, productRegister, models.Response{
@@ -50,11 +74,22 @@ func (pc *productController) CreateProduct(c *gin.Context) {
 	}
 
 	product := models.Product{
-		Name:       productRegister.Name,
-		Price:      productRegister.Price,
-		Stock:      productRegister.Stock,
-		CategoryID: productRegister.CategoryID,
-		Status:     productRegister.Status,
+		Name:        productRegister.Name,
+		Description: productRegister.Description,
+		Slug:        productRegister.Slug,
+		Price:       productRegister.Price,
+		Stock:       productRegister.Stock,
+		Weight:      productRegister.Weight,
+		Length:      productRegister.Length,
+		Width:       productRegister.Width,
+		Height:      productRegister.Height,
+		Attributes:  productRegister.Attributes,
+		CategoryID:  productRegister.CategoryID,
+		Status:      productRegister.Status,
+		CreatedBy:   v.(*models.CustomerClaims).Name,
+	}
+	if productRegister.SKU != "" {
+		product.SKU = &productRegister.SKU
 	}
 
 	response, err := pc.productService.CreateProduct(&product)
@@ -73,19 +108,22 @@ func (pc *productController) CreateProduct(c *gin.Context) {
 
 // GetProducts godoc
 // @Summary List a product
-// @Description List a product
+// @Description List a product, search filters on id, name, category_id, category_name, sku, slug and any attribute of the products like search=brand=acme,colour=red. q searches the name, category and description of the products, "quoted phrases", or and -excluded words work, the results are ranked by relevance with a snippet highlighting the matched words in <mark> tags. When no word matches, the names most similar to q are listed.
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Security ApiKeyAuth
 // @Param collection query []string false "string collection" collectionFormat(multi)
+// @Param q query string false "Full text search, at most 200 characters"
+// @Param currency query string false "Currency of the prices, the X-Currency header works too"
 // @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
 // @Failure 404 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /products [get]
 func (pc *productController) GetProducts(c *gin.Context) {
 	filter := c.Request.URL.Query()
-	response, err := pc.productService.GetProducts(filter)
+	response, err := pc.productService.GetProducts(filter, middleware.RequestCurrency(c))
 	if err != nil {
 		logger.Err(err.Error())
 		middleware.Response(c, filter, models.Response{
@@ -101,12 +139,14 @@ func (pc *productController) GetProducts(c *gin.Context) {
 
 // GetProductById godoc
 // @Summary Get a product by id
-// @Description Get a product by id
+// @Description Get a product by id with its ordered images and its variant matrix, the option names with their values and the variants with their SKU, stock and price
 // @Tags products
 // @Accept  json
 // @Produce  json
 // @Security ApiKeyAuth
+// @Param currency query string false "Currency of the price, the X-Currency header works too"
 // @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
 // @Failure 404 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /products/{id} [get]
@@ -121,7 +161,7 @@ func (pc *productController) GetProductById(c *gin.Context) {
 		return
 	}
 
-	response, err := pc.productService.GetProductById(id)
+	response, err := pc.productService.GetProductById(id, middleware.RequestCurrency(c))
 	if err != nil {
 		logger.Err(err.Error())
 		middleware.Response(c, id, models.Response{
@@ -146,6 +186,8 @@ func (pc *productController) GetProductById(c *gin.Context) {
 // @Failure 500 {object} models.Response
 // @Failure 400 {object} models.Response
 // @Failure 302 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Router /products/{id} [put]
 func (pc *productController) UpdateProduct(c *gin.Context) {
 	v, ok := c.Get("customer")
@@ -195,7 +237,7 @@ func (pc *productController) UpdateProduct(c *gin.Context) {
 
 // DeleteProduct godoc
 // @Summary Delete a product
-// @Description Delete a product
+// @Description Delete a product, its images are deleted with their files
 // @Tags products
 // @Accept  json
 // @Produce  json
@@ -241,3 +283,414 @@ func (pc *productController) DeleteProduct(c *gin.Context) {
 
 	middleware.Response(c, id, *response)
 }
+
+// SaveProductPrice godoc
+// @Summary Set the price of a product in a currency
+// @Description Sets the price of a product in a currency other than the default one, instead of its converted default currency price
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param currency path string true "Currency"
+// @Param price body models.ProductPriceUpdate true "Price"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/prices/{currency} [put]
+func (pc *productController) SaveProductPrice(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	currency, err := money.ParseCurrency(c.Param("currency"))
+	if err != nil {
+		middleware.Response(c, c.Param("currency"), models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var productPriceUpdate models.ProductPriceUpdate
+	if err := c.ShouldBindJSON(&productPriceUpdate); err != nil {
+		middleware.Response(c, productPriceUpdate, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	price := models.ProductPrice{
+		ProductID: c.Param("id"),
+		Currency:  currency,
+		Price:     productPriceUpdate.Price,
+		CreatedBy: v.(*models.CustomerClaims).Name,
+	}
+	response, err := pc.productService.SaveProductPrice(&price)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, productPriceUpdate, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, productPriceUpdate, *response)
+}
+
+// DeleteProductPrice godoc
+// @Summary Delete the price of a product in a currency
+// @Description Deletes the price of a product in a currency, the product is then sold at its converted default currency price
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param currency path string true "Currency"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/prices/{currency} [delete]
+func (pc *productController) DeleteProductPrice(c *gin.Context) {
+	id := c.Param("id")
+	currency, err := money.ParseCurrency(c.Param("currency"))
+	if err != nil {
+		middleware.Response(c, c.Param("currency"), models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response, err := pc.productService.DeleteProductPrice(id, currency)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
+
+// newProductVariant copies a variant register into a variant of the product of the request
+func newProductVariant(c *gin.Context, variantRegister models.ProductVariantRegister) models.ProductVariant {
+	return models.ProductVariant{
+		ProductID: c.Param("id"),
+		SKU:       variantRegister.SKU,
+		Options:   variantRegister.Options,
+		Price:     variantRegister.Price,
+		Stock:     variantRegister.Stock,
+	}
+}
+
+// CreateProductVariant godoc
+// @Summary Create a product variant
+// @Description Adds a variant like a size and colour to a product, with its SKU, stock and optionally its own price in the default currency. Every variant of a product has the same option names.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param variant body models.ProductVariantRegister true "Variant"
+// @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/variants [post]
+func (pc *productController) CreateProductVariant(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var variantRegister models.ProductVariantRegister
+	if err := c.ShouldBindJSON(&variantRegister); err != nil {
+		middleware.Response(c, variantRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	variant := newProductVariant(c, variantRegister)
+	variant.CreatedBy = v.(*models.CustomerClaims).Name
+
+	response, err := pc.productService.CreateProductVariant(&variant)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, variantRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, variantRegister, *response)
+}
+
+// UpdateProductVariant godoc
+// @Summary Update a product variant
+// @Description Replaces a variant of a product, orders keep the price they were created with
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param variantId path string true "Variant id"
+// @Param variant body models.ProductVariantRegister true "Variant"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/variants/{variantId} [put]
+func (pc *productController) UpdateProductVariant(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var variantRegister models.ProductVariantRegister
+	if err := c.ShouldBindJSON(&variantRegister); err != nil {
+		middleware.Response(c, variantRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	updatedBy := v.(*models.CustomerClaims).Name
+	variant := newProductVariant(c, variantRegister)
+	variant.ID = c.Param("variantId")
+	variant.UpdatedBy = &updatedBy
+
+	response, err := pc.productService.UpdateProductVariant(&variant)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, variantRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, variantRegister, *response)
+}
+
+// DeleteProductVariant godoc
+// @Summary Delete a product variant
+// @Description Deletes a variant of a product, orders keep their lines of the variant
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param variantId path string true "Variant id"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/variants/{variantId} [delete]
+func (pc *productController) DeleteProductVariant(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	updatedBy := v.(*models.CustomerClaims).Name
+	variant := models.ProductVariant{
+		ID:        c.Param("variantId"),
+		ProductID: c.Param("id"),
+		UpdatedBy: &updatedBy,
+	}
+
+	response, err := pc.productService.DeleteProductVariant(&variant)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, variant.ID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, variant.ID, *response)
+}
+
+// UploadProductImage godoc
+// @Summary Upload a product image
+// @Description Uploads a JPEG, PNG or GIF image of a product as the image form field, it is added after the other images with a generated thumbnail. The type is read from the content, not from the file name.
+// @Tags products
+// @Accept  multipart/form-data
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param image formData file true "Image"
+// @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 413 {object} models.Response
+// @Failure 415 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/images [post]
+func (pc *productController) UploadProductImage(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Image file required",
+			Data:    nil,
+		})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+	defer file.Close()
+
+	response, err := pc.productService.UploadProductImage(id, file, v.(*models.CustomerClaims).Name)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
+
+// ReorderProductImages godoc
+// @Summary Reorder the product images
+// @Description Orders the images of a product like image_ids, which lists every image of the product once. The first image is the cover.
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param order body models.ProductImageOrder true "Image order"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/images/order [put]
+func (pc *productController) ReorderProductImages(c *gin.Context) {
+	var order models.ProductImageOrder
+	if err := c.ShouldBindJSON(&order); err != nil {
+		middleware.Response(c, order, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	response, err := pc.productService.ReorderProductImages(c.Param("id"), order.ImageIDs)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, order, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, order, *response)
+}
+
+// DeleteProductImage godoc
+// @Summary Delete a product image
+// @Description Deletes an image of a product with its files
+// @Tags products
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Product id"
+// @Param imageId path string true "Image id"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /products/{id}/images/{imageId} [delete]
+func (pc *productController) DeleteProductImage(c *gin.Context) {
+	imageID := c.Param("imageId")
+	response, err := pc.productService.DeleteProductImage(c.Param("id"), imageID)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, imageID, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, imageID, *response)
+}
@@ -18,6 +18,7 @@ type ProductCategoryControllerInterface interface {
 	CreateProductCategory(c *gin.Context)
 	GetProductCategories(c *gin.Context)
 	GetProductCategoryById(c *gin.Context)
+	GetProductCategoryTree(c *gin.Context)
 	UpdateProductCategory(c *gin.Context)
 	DeleteProductCategory(c *gin.Context)
 }
@@ -30,15 +31,29 @@ func NewProductCategoryController(productCategoryService services.ProductCategor
 
 // CreateProductCategory godoc
 // @Summary Create a productCategory
-// @Description Create a productCategory
+// @Description Create a productCategory under the parent_id category, or as a root category without it, with the attribute_schema of its products, a list of attributes with a name, a type (string, number, boolean or enum), whether they are required and the values of an enum
 // @Tags productCategories
 // @Accept  json
 // @Produce  json
+// @Security ApiKeyAuth
 // @Param productCategory body models.ProductCategoryRegister true "ProductCategory"
 // @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /products/categories [post]
 func (pc *productCategoryController) CreateProductCategory(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
 	var productCategoryRegister models.ProductCategoryRegister
 	if err := c.ShouldBindJSON(&productCategoryRegister); err != nil {
 		middleware.Response(c, productCategoryRegister, models.Response{
@@ -50,7 +65,12 @@ func (pc *productCategoryController) CreateProductCategory(c *gin.Context) {
 	}
 
 	productCategory := models.ProductCategory{
-		Name: productCategoryRegister.Name,
+		Name:            productCategoryRegister.Name,
+		AttributeSchema: productCategoryRegister.AttributeSchema,
+		CreatedBy:       v.(*models.CustomerClaims).Name,
+	}
+	if productCategoryRegister.ParentID != "" {
+		productCategory.ParentID = &productCategoryRegister.ParentID
 	}
 
 	response, err := pc.productCategoryService.CreateProductCategory(&productCategory)
@@ -131,9 +151,34 @@ func (pc *productCategoryController) GetProductCategoryById(c *gin.Context) {
 	middleware.Response(c, id, *response)
 }
 
+// GetProductCategoryTree godoc
+// @Summary Get the productCategory tree
+// @Description Get the productCategories nested under their parent, root categories first
+// @Tags productCategories
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Response{data=[]models.ProductCategoryNode}
+// @Failure 500 {object} models.Response
+// @Router /products/categories/tree [get]
+func (pc *productCategoryController) GetProductCategoryTree(c *gin.Context) {
+	response, err := pc.productCategoryService.GetProductCategoryTree()
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, nil, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, nil, *response)
+}
+
 // UpdateProductCategory godoc
 // @Summary Update a productCategory
-// @Description Update a productCategory
+// @Description Update a productCategory, a parent_id missing or empty makes it a root category, it cannot be one of its subcategories. The attribute_schema is replaced and products are checked against it when they are updated
 // @Tags productCategories
 // @Accept  json
 // @Produce  json
@@ -141,6 +186,8 @@ func (pc *productCategoryController) GetProductCategoryById(c *gin.Context) {
 // @Success 201 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Failure 400 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 422 {object} models.Response
 // @Failure 302 {object} models.Response
 // @Router /products/categories/{id} [put]
 func (pc *productCategoryController) UpdateProductCategory(c *gin.Context) {
@@ -191,13 +238,14 @@ func (pc *productCategoryController) UpdateProductCategory(c *gin.Context) {
 
 // DeleteProductCategory godoc
 // @Summary Delete a productCategory
-// @Description Delete a productCategory
+// @Description Delete a productCategory without subcategories and products
 // @Tags productCategories
 // @Accept  json
 // @Produce  json
 // @Security ApiKeyAuth
 // @Success 200 {object} models.Response
 // @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
 // @Failure 500 {object} models.Response
 // @Router /products/categories/{id} [delete]
 func (pc *productCategoryController) DeleteProductCategory(c *gin.Context) {
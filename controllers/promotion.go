@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type promotionController struct {
+	promotionService services.PromotionServiceInterface
+}
+
+type PromotionControllerInterface interface {
+	CreatePromotion(c *gin.Context)
+	GetPromotions(c *gin.Context)
+	GetPromotionById(c *gin.Context)
+	UpdatePromotion(c *gin.Context)
+	DeletePromotion(c *gin.Context)
+}
+
+func NewPromotionController(promotionService services.PromotionServiceInterface) PromotionControllerInterface {
+	return &promotionController{
+		promotionService: promotionService,
+	}
+}
+
+// newPromotion copies a promotion register into a promotion
+func newPromotion(promotionRegister models.PromotionRegister) models.Promotion {
+	return models.Promotion{
+		Code:                  promotionRegister.Code,
+		Name:                  promotionRegister.Name,
+		Type:                  promotionRegister.Type,
+		Percent:               promotionRegister.Percent,
+		Amount:                promotionRegister.Amount,
+		BuyQty:                promotionRegister.BuyQty,
+		GetQty:                promotionRegister.GetQty,
+		MinSpend:              promotionRegister.MinSpend,
+		CategoryID:            promotionRegister.CategoryID,
+		ProductID:             promotionRegister.ProductID,
+		StartsAt:              promotionRegister.StartsAt,
+		EndsAt:                promotionRegister.EndsAt,
+		UsageLimit:            promotionRegister.UsageLimit,
+		UsageLimitPerCustomer: promotionRegister.UsageLimitPerCustomer,
+	}
+}
+
+// CreatePromotion godoc
+// @Summary Create a promotion
+// @Description Creates a percentage, fixed or buy_x_get_y promotion customers get with its code
+// @Tags promotions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param promotion body models.PromotionRegister true "Promotion"
+// @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /promotions [post]
+func (pc *promotionController) CreatePromotion(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var promotionRegister models.PromotionRegister
+	if err := c.ShouldBindJSON(&promotionRegister); err != nil {
+		middleware.Response(c, promotionRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	promotion := newPromotion(promotionRegister)
+	promotion.CreatedBy = v.(*models.CustomerClaims).Name
+
+	response, err := pc.promotionService.CreatePromotion(&promotion)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, promotionRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, promotionRegister, *response)
+}
+
+// GetPromotions godoc
+// @Summary List promotions
+// @Description Lists the promotions, filtered by code
+// @Tags promotions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Param code query string false "Code"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /promotions [get]
+func (pc *promotionController) GetPromotions(c *gin.Context) {
+	filter := c.Request.URL.Query()
+	response, err := pc.promotionService.GetPromotions(filter)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, filter, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, filter, *response)
+}
+
+// GetPromotionById godoc
+// @Summary Get a promotion
+// @Description Get a promotion by id
+// @Tags promotions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /promotions/{id} [get]
+func (pc *promotionController) GetPromotionById(c *gin.Context) {
+	id := c.Param("id")
+	response, err := pc.promotionService.GetPromotionById(id)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
+
+// UpdatePromotion godoc
+// @Summary Update a promotion
+// @Description Replaces a promotion, its usage count is kept and orders keep the discount they were created with
+// @Tags promotions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Promotion ID"
+// @Param promotion body models.PromotionRegister true "Promotion"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /promotions/{id} [put]
+func (pc *promotionController) UpdatePromotion(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var promotionRegister models.PromotionRegister
+	if err := c.ShouldBindJSON(&promotionRegister); err != nil {
+		middleware.Response(c, promotionRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	updatedBy := v.(*models.CustomerClaims).Name
+	promotion := newPromotion(promotionRegister)
+	promotion.ID = c.Param("id")
+	promotion.UpdatedBy = &updatedBy
+
+	response, err := pc.promotionService.UpdatePromotion(&promotion)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, promotionRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, promotionRegister, *response)
+}
+
+// DeletePromotion godoc
+// @Summary Delete a promotion
+// @Description Deletes a promotion, its code cannot be applied anymore
+// @Tags promotions
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /promotions/{id} [delete]
+func (pc *promotionController) DeletePromotion(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	response, err := pc.promotionService.DeletePromotion(id, v.(*models.CustomerClaims).Name)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
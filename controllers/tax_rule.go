@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type taxRuleController struct {
+	taxRuleService services.TaxRuleServiceInterface
+}
+
+type TaxRuleControllerInterface interface {
+	GetTaxRules(c *gin.Context)
+	CreateTaxRule(c *gin.Context)
+	UpdateTaxRule(c *gin.Context)
+	DeleteTaxRule(c *gin.Context)
+}
+
+func NewTaxRuleController(taxRuleService services.TaxRuleServiceInterface) TaxRuleControllerInterface {
+	return &taxRuleController{
+		taxRuleService: taxRuleService,
+	}
+}
+
+// GetTaxRules godoc
+// @Summary List the tax rules
+// @Description Lists the tax rules of every region and category
+// @Tags taxRules
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /tax-rules [get]
+func (tc *taxRuleController) GetTaxRules(c *gin.Context) {
+	response, err := tc.taxRuleService.GetTaxRules()
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, "", models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, "", *response)
+}
+
+// CreateTaxRule godoc
+// @Summary Create a tax rule
+// @Description Taxes the products of a category, or of every category, sold in a region, or in every region
+// @Tags taxRules
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param rule body models.TaxRuleRegister true "Tax rule"
+// @Success 201 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /tax-rules [post]
+func (tc *taxRuleController) CreateTaxRule(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var taxRuleRegister models.TaxRuleRegister
+	if err := c.ShouldBindJSON(&taxRuleRegister); err != nil {
+		middleware.Response(c, taxRuleRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	rule := models.TaxRule{
+		Name:       taxRuleRegister.Name,
+		Region:     taxRuleRegister.Region,
+		CategoryID: taxRuleRegister.CategoryID,
+		Rate:       taxRuleRegister.Rate,
+		Inclusive:  taxRuleRegister.Inclusive,
+		CreatedBy:  v.(*models.CustomerClaims).Name,
+	}
+
+	response, err := tc.taxRuleService.CreateTaxRule(&rule)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, taxRuleRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, taxRuleRegister, *response)
+}
+
+// UpdateTaxRule godoc
+// @Summary Update a tax rule
+// @Description Replaces a tax rule, orders keep the tax they were created with
+// @Tags taxRules
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Tax rule ID"
+// @Param rule body models.TaxRuleRegister true "Tax rule"
+// @Success 200 {object} models.Response
+// @Failure 400 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 409 {object} models.Response
+// @Failure 422 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /tax-rules/{id} [put]
+func (tc *taxRuleController) UpdateTaxRule(c *gin.Context) {
+	v, ok := c.Get("customer")
+	if !ok {
+		c.JSON(401, models.Response{
+			Code:    http.StatusUnauthorized,
+			Message: http.StatusText(http.StatusUnauthorized),
+		})
+		return
+	}
+
+	var taxRuleRegister models.TaxRuleRegister
+	if err := c.ShouldBindJSON(&taxRuleRegister); err != nil {
+		middleware.Response(c, taxRuleRegister, models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Data:    nil,
+		})
+		return
+	}
+
+	updatedBy := v.(*models.CustomerClaims).Name
+	rule := models.TaxRule{
+		ID:         c.Param("id"),
+		Name:       taxRuleRegister.Name,
+		Region:     taxRuleRegister.Region,
+		CategoryID: taxRuleRegister.CategoryID,
+		Rate:       taxRuleRegister.Rate,
+		Inclusive:  taxRuleRegister.Inclusive,
+		UpdatedBy:  &updatedBy,
+	}
+
+	response, err := tc.taxRuleService.UpdateTaxRule(&rule)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, taxRuleRegister, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, taxRuleRegister, *response)
+}
+
+// DeleteTaxRule godoc
+// @Summary Delete a tax rule
+// @Description Deletes a tax rule, orders keep the tax they were created with
+// @Tags taxRules
+// @Accept  json
+// @Produce  json
+// @Security ApiKeyAuth
+// @Param id path string true "Tax rule ID"
+// @Success 200 {object} models.Response
+// @Failure 401 {object} models.Response
+// @Failure 403 {object} models.Response
+// @Failure 404 {object} models.Response
+// @Failure 500 {object} models.Response
+// @Router /tax-rules/{id} [delete]
+func (tc *taxRuleController) DeleteTaxRule(c *gin.Context) {
+	id := c.Param("id")
+	response, err := tc.taxRuleService.DeleteTaxRule(id)
+	if err != nil {
+		logger.Err(err.Error())
+		middleware.Response(c, id, models.Response{
+			Code:    http.StatusInternalServerError,
+			Message: http.StatusText(http.StatusInternalServerError),
+			Data:    nil,
+		})
+		return
+	}
+
+	middleware.Response(c, id, *response)
+}
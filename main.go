@@ -5,11 +5,23 @@ import (
 	"fmt"
 	"log"
 	"mvp-shop-backend/controllers"
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/migration"
 	"mvp-shop-backend/pkg/database"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/mailer"
+	"mvp-shop-backend/pkg/media"
+	"mvp-shop-backend/pkg/money"
+	"mvp-shop-backend/pkg/payment"
+	"mvp-shop-backend/pkg/shipping"
+	"mvp-shop-backend/pkg/storage"
+	"mvp-shop-backend/pkg/utils"
 	"mvp-shop-backend/repositories"
 	"mvp-shop-backend/routes"
 	"mvp-shop-backend/services"
 	"os"
+	"strings"
+	"time"
 
 	_ "mvp-shop-backend/docs"
 
@@ -43,22 +55,129 @@ func main() {
 		panic(err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+
+	migrator, err := migration.NewMigrator(sqlDB)
+	if err != nil {
+		panic(err)
+	}
+
+	// mvp-shop-backend migrate up|down [steps]|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, migrator, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = migrateOnStart(ctx, migrator)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = middleware.LoadKeys()
+	if err != nil {
+		panic(err)
+	}
+
+	err = utils.LoadPasswordHashing()
+	if err != nil {
+		panic(err)
+	}
+
+	err = utils.LoadPasswordPolicy()
+	if err != nil {
+		panic(err)
+	}
+
+	err = money.LoadCurrency()
+	if err != nil {
+		panic(err)
+	}
+
+	paymentProvider, err := payment.NewProvider()
+	if err != nil {
+		panic(err)
+	}
+
+	shippingMethods, err := shipping.LoadMethods()
+	if err != nil {
+		panic(err)
+	}
+
+	mail, err := mailer.NewMailer()
+	if err != nil {
+		panic(err)
+	}
+
+	blobStore, err := storage.NewBlobStore()
+	if err != nil {
+		panic(err)
+	}
+
+	mediaOptions, err := media.LoadOptions()
+	if err != nil {
+		panic(err)
+	}
+
 	gin.SetMode(gin.DebugMode)
 
 	// Repositories
 	customerRepository := repositories.NewCustomerRepository(db)
 	productCategoryRepository := repositories.NewProductCategoryRepository(db)
 	productRepository := repositories.NewProductRepository(db)
+	productPriceRepository := repositories.NewProductPriceRepository(db)
+	productVariantRepository := repositories.NewProductVariantRepository(db)
+	productImageRepository := repositories.NewProductImageRepository(db)
+	productSearcher := repositories.NewProductSearcher(db)
+	exchangeRateRepository := repositories.NewExchangeRateRepository(db)
+	taxRuleRepository := repositories.NewTaxRuleRepository(db)
+	promotionRepository := repositories.NewPromotionRepository(db)
 	cartRepository := repositories.NewCartRepository(db)
+	addressRepository := repositories.NewAddressRepository(db)
 	orderRepository := repositories.NewOrderRepository(db)
+	paymentRepository := repositories.NewPaymentRepository(db)
+	tokenRepository := repositories.NewTokenRepository(db)
+	loginAttemptRepository, err := repositories.NewLoginAttemptRepository(db, os.Getenv("LOGIN_ATTEMPT_STORE"))
+	if err != nil {
+		panic(err)
+	}
+	loginAuditRepository := repositories.NewLoginAuditRepository(db)
+	customerTokenRepository := repositories.NewCustomerTokenRepository(db)
 
 	// Services
-	customerService := services.NewCustomerService(customerRepository)
-	authService := services.NewAuthService(customerRepository)
+	customerService := services.NewCustomerService(customerRepository, tokenRepository, customerTokenRepository, mail)
+	authService := services.NewAuthService(customerRepository, tokenRepository, loginAttemptRepository, loginAuditRepository, customerTokenRepository, mail)
 	productCategoryService := services.NewProductCategoryService(productCategoryRepository)
-	productService := services.NewProductService(productRepository)
-	cartService := services.NewCartService(cartRepository)
-	orderService := services.NewOrderService(orderRepository, productRepository)
+	productService := services.NewProductService(productRepository, productCategoryRepository, productPriceRepository, productVariantRepository, productImageRepository, productSearcher, blobStore, mediaOptions)
+	cartService := services.NewCartService(cartRepository, productRepository, productPriceRepository, productVariantRepository, taxRuleRepository, promotionRepository)
+	orderService := services.NewOrderService(orderRepository, productRepository, addressRepository, shippingMethods, paymentRepository, paymentProvider)
+	paymentService := services.NewPaymentService(paymentRepository, orderRepository, paymentProvider)
+	exchangeRateService := services.NewExchangeRateService(exchangeRateRepository)
+	taxRuleService := services.NewTaxRuleService(taxRuleRepository)
+	promotionService := services.NewPromotionService(promotionRepository)
+	addressService := services.NewAddressService(addressRepository)
+
+	// the fake provider delivers its webhooks in-process instead of calling /payments/webhook
+	if fakeProvider, ok := paymentProvider.(*payment.FakeProvider); ok {
+		fakeProvider.SetWebhook(func(payload []byte, signature string) {
+			if _, err := paymentService.HandleWebhook(payload, signature); err != nil {
+				logger.Err(err.Error())
+			}
+		})
+	}
+
+	// refunds of cancelled orders the provider failed are retried until they go through
+	go func() {
+		for ; ; time.Sleep(time.Minute) {
+			if err := paymentService.RetryRefunds(); err != nil {
+				logger.Err(err.Error())
+			}
+		}
+	}()
 
 	// Controllers
 	customerController := controllers.NewCustomerController(customerService)
@@ -67,11 +186,21 @@ func main() {
 	productController := controllers.NewProductController(productService)
 	cartController := controllers.NewCartController(cartService)
 	orderController := controllers.NewOrderController(orderService)
+	paymentController := controllers.NewPaymentController(paymentService)
+	exchangeRateController := controllers.NewExchangeRateController(exchangeRateService)
+	taxRuleController := controllers.NewTaxRuleController(taxRuleService)
+	promotionController := controllers.NewPromotionController(promotionService)
+	addressController := controllers.NewAddressController(addressService)
 
-	router := routes.NewRouter(customerController, authController, productCategoryController, productController, cartController, orderController)
+	router := routes.NewRouter(customerController, authController, productCategoryController, productController, cartController, orderController, paymentController, exchangeRateController, taxRuleController, promotionController, addressController, tokenRepository)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// the local blob store is served by the api itself, other stores serve their blobs
+	if localStore, ok := blobStore.(*storage.LocalStore); ok && strings.HasPrefix(localStore.BaseURL, "/") {
+		router.Static(localStore.BaseURL, localStore.Dir)
+	}
+
 	port := fmt.Sprintf(":%s", os.Getenv("HTTP_PORT"))
 	router.Run(port)
 }
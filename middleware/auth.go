@@ -2,15 +2,31 @@ package middleware
 
 import (
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// TokenDenyList reports whether an access token was revoked before its expiry, by a logout or a password change
+type TokenDenyList interface {
+	IsRevoked(token *models.CustomerClaims) (bool, error)
+}
+
+// BearerToken returns the token of an Authorization header, the Bearer scheme is optional for older clients
+func BearerToken(header string) string {
+	header = strings.TrimSpace(header)
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
 // AuthMiddleware is a sample middleware for authentication and authorization using JWT
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(denyList TokenDenyList) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString := c.GetHeader("Authorization")
+		tokenString := BearerToken(c.GetHeader("Authorization"))
 		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, models.Response{
 				Code:    http.StatusUnauthorized,
@@ -30,8 +46,58 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := denyList.IsRevoked(decodes)
+		if err != nil {
+			logger.Err(err.Error())
+			c.JSON(http.StatusInternalServerError, models.Response{
+				Code:    http.StatusInternalServerError,
+				Message: http.StatusText(http.StatusInternalServerError),
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, models.Response{
+				Code:    http.StatusUnauthorized,
+				Message: http.StatusText(http.StatusUnauthorized),
+			})
+			c.Abort()
+			return
+		}
+
 		c.Set("customer", decodes)
 
 		c.Next()
 	}
 }
+
+// RequireRole aborts with 403 unless the customer set by AuthMiddleware has one of roles
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, ok := c.Get("customer")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.Response{
+				Code:    http.StatusUnauthorized,
+				Message: http.StatusText(http.StatusUnauthorized),
+			})
+			c.Abort()
+			return
+		}
+
+		customer, ok := v.(*models.CustomerClaims)
+		if ok {
+			for _, role := range roles {
+				if customer.Role == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, models.Response{
+			Code:    http.StatusForbidden,
+			Message: http.StatusText(http.StatusForbidden),
+		})
+		c.Abort()
+	}
+}
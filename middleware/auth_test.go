@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"errors"
+	"mvp-shop-backend/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("JWT_ALGORITHM", "HS256")
+	os.Setenv("SECRET_KEY", "middleware-test-secret-of-at-least-32-bytes")
+	os.Exit(m.Run())
+}
+
+// denyList revokes the jtis it holds and fails every lookup with err when set
+type denyList struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (dl denyList) IsRevoked(token *models.CustomerClaims) (bool, error) {
+	return dl.revoked[token.Id], dl.err
+}
+
+func testToken(t *testing.T) (tokenString string, claims *models.CustomerClaims) {
+	t.Helper()
+
+	tokenString, _, err := GenerateToken(models.CustomerClaims{
+		ID:     "customer-1",
+		Email:  "customer@example.com",
+		Name:   "Customer",
+		Role:   models.RoleCustomer,
+		Status: models.StatusActive,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err = JwtClaim(tokenString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenString, claims
+}
+
+func TestAuthMiddlewareDenyList(t *testing.T) {
+	tokenString, claims := testToken(t)
+
+	tests := []struct {
+		name     string
+		denyList denyList
+		want     int
+	}{
+		{name: "not revoked", denyList: denyList{revoked: map[string]bool{"other": true}}, want: http.StatusOK},
+		{name: "revoked", denyList: denyList{revoked: map[string]bool{claims.Id: true}}, want: http.StatusUnauthorized},
+		{name: "deny-list unavailable", denyList: denyList{err: errors.New("connection refused")}, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", AuthMiddleware(tt.denyList), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CurrencyHeader selects the currency prices are returned and orders are created in, like the currency query parameter
+const CurrencyHeader = "X-Currency"
+
+// CurrencyMiddleware sets "currency" to the currency query parameter, the X-Currency header or the default currency
+func CurrencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.Query("currency")
+		if value == "" {
+			value = c.GetHeader(CurrencyHeader)
+		}
+
+		currency := money.DefaultCurrency()
+		if value != "" {
+			var err error
+			if currency, err = money.ParseCurrency(value); err != nil {
+				c.JSON(http.StatusBadRequest, models.Response{
+					Code:    http.StatusBadRequest,
+					Message: err.Error(),
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("currency", currency)
+		c.Next()
+	}
+}
+
+// RequestCurrency returns the currency set by CurrencyMiddleware
+func RequestCurrency(c *gin.Context) money.Currency {
+	if v, ok := c.Get("currency"); ok {
+		return v.(money.Currency)
+	}
+	return money.DefaultCurrency()
+}
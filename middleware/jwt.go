@@ -1,70 +1,81 @@
 package middleware
 
 import (
+	"errors"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/utils"
 	"os"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
 )
 
-// GenerateToken is a JWT New With Claims SigningMethodHS256
-func GenerateToken(customer models.CustomerClaims) (string, error) {
-	jwtExpired := os.Getenv("JWT_EXPIRED")
-	uom := jwtExpired[len(jwtExpired)-1:]
-	expiredToken, _ := strconv.Atoi(strings.Replace(jwtExpired, uom, "", -1))
+// ErrInvalidClaims is returned by JwtClaim when a valid token misses the customer claims
+var ErrInvalidClaims = errors.New("token claims are not valid")
 
-	timeHourMinutes := time.Hour
-	if uom == "d" {
-		timeHourMinutes = time.Hour * 24
+// AccessTokenLifetime returns the access token lifetime set by JWT_EXPIRED, 15 minutes by default
+func AccessTokenLifetime() time.Duration {
+	return utils.ParseDuration(os.Getenv("JWT_EXPIRED"), 15*time.Minute)
+}
+
+// RefreshTokenLifetime returns the refresh token lifetime set by JWT_REFRESH_EXPIRED, 7 days by default
+func RefreshTokenLifetime() time.Duration {
+	return utils.ParseDuration(os.Getenv("JWT_REFRESH_EXPIRED"), 7*24*time.Hour)
+}
+
+// GenerateToken is a JWT New With Claims signed with the configured key, every token gets a unique jti so it can be revoked
+func GenerateToken(customer models.CustomerClaims) (tokenString string, expiredAt int64, err error) {
+	ks, err := getKeySet()
+	if err != nil {
+		return "", 0, err
 	}
 
-	expiredAt := time.Now().Add(timeHourMinutes * time.Duration(expiredToken)).Unix()
+	expiredAt = time.Now().Add(AccessTokenLifetime()).Unix()
 	claims := models.CustomerClaims{
 		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
 			ExpiresAt: expiredAt,
 			Issuer:    "mvp-shop-backend",
 			Subject:   "customer",
 		},
-		ID:     customer.ID,
-		Name:   customer.Name,
-		Email:  customer.Email,
-		Status: customer.Status,
+		ID:           customer.ID,
+		Name:         customer.Name,
+		Email:        customer.Email,
+		Role:         customer.Role,
+		Status:       customer.Status,
+		TokenVersion: customer.TokenVersion,
 	}
 
-	var signingKey = []byte(os.Getenv("SECRET_KEY"))
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(signingKey)
-
+	tokenString, err = ks.sign(claims)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiredAt, nil
 }
 
-// JwtClaim is a JWT Parse With Claims Token
+// JwtClaim is a JWT Parse With Claims Token, only the configured algorithms are accepted
 func JwtClaim(token string) (customer *models.CustomerClaims, err error) {
-	var signingKey = []byte(os.Getenv("SECRET_KEY"))
-	claims := jwt.MapClaims{}
-	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(signingKey), nil
-	})
+	ks, err := getKeySet()
 	if err != nil {
 		return nil, err
 	}
 
-	id := claims["id"].(string)
-	name := claims["name"].(string)
-	email := claims["email"].(string)
-	status := claims["status"].(string)
-	customer = &models.CustomerClaims{
-		ID:     id,
-		Name:   name,
-		Email:  email,
-		Status: models.Status(status),
+	parser := jwt.Parser{ValidMethods: ks.validMethods()}
+	customer = &models.CustomerClaims{}
+	_, err = parser.ParseWithClaims(token, customer, ks.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer.ID == "" || customer.Email == "" {
+		return nil, ErrInvalidClaims
+	}
+
+	// tokens issued before roles existed carry no role claim
+	if customer.Role == "" {
+		customer.Role = models.RoleCustomer
 	}
-	return
+	return customer, nil
 }
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// verificationKey is a public key accepted to verify tokens signed with alg
+type verificationKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// KeySet holds the key used to sign new tokens and every key still accepted to verify them.
+// Keys are rotated by adding the new public key to JWT_VERIFY_KEYS_DIR, switching the signing key,
+// then removing the old public key once the tokens it signed are expired.
+type KeySet struct {
+	alg        string
+	kid        string
+	signingKey interface{}
+	verifyKeys map[string]verificationKey
+}
+
+// JWK is a public key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// minSecretKeyLength is the shortest SECRET_KEY accepted for HS256, the size of its SHA-256 output
+const minSecretKeyLength = 32
+
+var (
+	keySet     *KeySet
+	keySetErr  error
+	keySetOnce sync.Once
+)
+
+// LoadKeys loads the signing and verification keys, it is called by main to fail fast on a bad configuration
+func LoadKeys() error {
+	_, err := getKeySet()
+	return err
+}
+
+func getKeySet() (*KeySet, error) {
+	keySetOnce.Do(func() {
+		keySet, keySetErr = newKeySet()
+	})
+	return keySet, keySetErr
+}
+
+// newKeySet reads JWT_ALGORITHM (HS256, RS256 or EdDSA), HS256 signs with SECRET_KEY of at least 32 bytes while
+// RS256 and EdDSA sign with the PEM private key of JWT_SIGNING_KEY_FILE identified by JWT_SIGNING_KEY_ID
+// and verify with its public key plus every <kid>.pem public key of JWT_VERIFY_KEYS_DIR
+func newKeySet() (*KeySet, error) {
+	alg := os.Getenv("JWT_ALGORITHM")
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+
+	ks := &KeySet{
+		alg:        alg,
+		verifyKeys: make(map[string]verificationKey),
+	}
+
+	switch alg {
+	case jwt.SigningMethodHS256.Alg():
+		secretKey := os.Getenv("SECRET_KEY")
+		if len(secretKey) < minSecretKeyLength {
+			return nil, fmt.Errorf("SECRET_KEY must be at least %d bytes for %s", minSecretKeyLength, alg)
+		}
+		ks.signingKey = []byte(secretKey)
+		return ks, nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q", alg)
+	}
+
+	ks.kid = os.Getenv("JWT_SIGNING_KEY_ID")
+	if ks.kid == "" {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_ID is required for %s", alg)
+	}
+
+	pem, err := os.ReadFile(os.Getenv("JWT_SIGNING_KEY_FILE"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading JWT_SIGNING_KEY_FILE, %v", err)
+	}
+
+	if alg == jwt.SigningMethodRS256.Alg() {
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		ks.signingKey = privateKey
+		ks.verifyKeys[ks.kid] = verificationKey{alg: alg, key: &privateKey.PublicKey}
+	} else {
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		ks.signingKey = privateKey
+		ks.verifyKeys[ks.kid] = verificationKey{alg: alg, key: privateKey.(ed25519.PrivateKey).Public()}
+	}
+
+	dir := os.Getenv("JWT_VERIFY_KEYS_DIR")
+	if dir == "" {
+		return ks, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		if kid == ks.kid {
+			continue
+		}
+
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+			ks.verifyKeys[kid] = verificationKey{alg: jwt.SigningMethodRS256.Alg(), key: publicKey}
+			continue
+		}
+		publicKey, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing verification key %s, %v", file, err)
+		}
+		ks.verifyKeys[kid] = verificationKey{alg: jwt.SigningMethodEdDSA.Alg(), key: publicKey}
+	}
+
+	return ks, nil
+}
+
+// sign signs claims with the current signing key and sets its kid header
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ks.alg), claims)
+	if ks.kid != "" {
+		token.Header["kid"] = ks.kid
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// validMethods lists the algorithms accepted when parsing, a HS256 token is never accepted with asymmetric keys
+func (ks *KeySet) validMethods() []string {
+	if ks.alg == jwt.SigningMethodHS256.Alg() {
+		return []string{ks.alg}
+	}
+
+	var methods []string
+	seen := make(map[string]bool)
+	for _, v := range ks.verifyKeys {
+		if !seen[v.alg] {
+			seen[v.alg] = true
+			methods = append(methods, v.alg)
+		}
+	}
+	return methods
+}
+
+// keyFunc returns the verification key of the token kid, checking the token algorithm matches the key
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if ks.alg == jwt.SigningMethodHS256.Alg() {
+		if token.Method.Alg() != ks.alg {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return ks.signingKey, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	v, ok := ks.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != v.alg {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return v.key, nil
+}
+
+// GetJWKS returns the public verification keys, empty when tokens are signed with HS256
+func GetJWKS() (JWKS, error) {
+	ks, err := getKeySet()
+	if err != nil {
+		return JWKS{}, err
+	}
+	return ks.jwks(), nil
+}
+
+func (ks *KeySet) jwks() JWKS {
+	kids := make([]string, 0, len(ks.verifyKeys))
+	for kid := range ks.verifyKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		v := ks.verifyKeys[kid]
+		switch key := v.key.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: v.alg,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: v.alg,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(key),
+			})
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,304 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"mvp-shop-backend/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// testKeys are the paths of a PEM encoded private and public key
+type testKeys struct {
+	private, public string
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// generateRSAKeys writes a RSA key pair in dir as <kid>.key and <kid>.pem
+func generateRSAKeys(t *testing.T, dir, kid string) testKeys {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{
+		private: writePEM(t, dir, kid+".key", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(privateKey)),
+		public:  writePEM(t, dir, kid+".pem", "PUBLIC KEY", publicDER),
+	}
+}
+
+// generateEdKeys writes an Ed25519 key pair in dir as <kid>.key and <kid>.pem
+func generateEdKeys(t *testing.T, dir, kid string) testKeys {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{
+		private: writePEM(t, dir, kid+".key", "PRIVATE KEY", privateDER),
+		public:  writePEM(t, dir, kid+".pem", "PUBLIC KEY", publicDER),
+	}
+}
+
+// setKeyEnv replaces the key configuration read by newKeySet for the duration of the test
+func setKeyEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+
+	for _, name := range []string{"JWT_ALGORITHM", "SECRET_KEY", "JWT_SIGNING_KEY_ID", "JWT_SIGNING_KEY_FILE", "JWT_VERIFY_KEYS_DIR"} {
+		t.Setenv(name, env[name])
+	}
+}
+
+func mustKeySet(t *testing.T, env map[string]string) *KeySet {
+	t.Helper()
+
+	setKeyEnv(t, env)
+	ks, err := newKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// parseWith parses tokenString the way JwtClaim does with ks
+func parseWith(ks *KeySet, tokenString string) error {
+	parser := jwt.Parser{ValidMethods: ks.validMethods()}
+	_, err := parser.ParseWithClaims(tokenString, &models.CustomerClaims{}, ks.keyFunc)
+	return err
+}
+
+func testClaims() models.CustomerClaims {
+	return models.CustomerClaims{
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		ID:             "customer-1",
+		Email:          "customer@example.com",
+		Role:           models.RoleCustomer,
+	}
+}
+
+// signWith signs the test claims with method and key, setting the kid header unless it is empty
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, testClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenString
+}
+
+func TestNewKeySet(t *testing.T) {
+	rsaKeys := generateRSAKeys(t, t.TempDir(), "current")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "32 bytes", env: map[string]string{"JWT_ALGORITHM": "HS256", "SECRET_KEY": strings.Repeat("k", 32)}},
+		{name: "default algorithm", env: map[string]string{"SECRET_KEY": strings.Repeat("k", 64)}},
+		{name: "empty", env: map[string]string{"JWT_ALGORITHM": "HS256"}, wantErr: true},
+		{name: "31 bytes", env: map[string]string{"JWT_ALGORITHM": "HS256", "SECRET_KEY": strings.Repeat("k", 31)}, wantErr: true},
+		{name: "unsupported algorithm", env: map[string]string{"JWT_ALGORITHM": "none", "SECRET_KEY": strings.Repeat("k", 32)}, wantErr: true},
+		{name: "missing signing key id", env: map[string]string{"JWT_ALGORITHM": "RS256", "JWT_SIGNING_KEY_FILE": rsaKeys.private}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setKeyEnv(t, tt.env)
+			_, err := newKeySet()
+			if tt.wantErr && err == nil {
+				t.Error("newKeySet() accepted an invalid configuration")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("newKeySet(): %v", err)
+			}
+		})
+	}
+}
+
+// TestKeySetAlgorithmConfusion signs HS256 tokens with the public key as the HMAC secret, asymmetric key sets must refuse them
+func TestKeySetAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKeys := generateRSAKeys(t, dir, "rsa")
+	tests := []struct {
+		name string
+		alg  string
+		keys testKeys
+	}{
+		{name: "RS256", alg: "RS256", keys: rsaKeys},
+		{name: "EdDSA", alg: "EdDSA", keys: generateEdKeys(t, dir, "ed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks := mustKeySet(t, map[string]string{
+				"JWT_ALGORITHM":        tt.alg,
+				"JWT_SIGNING_KEY_ID":   "current",
+				"JWT_SIGNING_KEY_FILE": tt.keys.private,
+			})
+
+			publicPEM, err := os.ReadFile(tt.keys.public)
+			if err != nil {
+				t.Fatal(err)
+			}
+			forged := signWith(t, jwt.SigningMethodHS256, "current", publicPEM)
+			if err := parseWith(ks, forged); err == nil {
+				t.Error("a HS256 token signed with the public key was accepted")
+			}
+
+			signed, err := ks.sign(testClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := parseWith(ks, signed); err != nil {
+				t.Errorf("a token signed by the key set was refused, %v", err)
+			}
+		})
+	}
+
+	t.Run("HS256 key set", func(t *testing.T) {
+		ks := mustKeySet(t, map[string]string{"JWT_ALGORITHM": "HS256", "SECRET_KEY": strings.Repeat("k", 32)})
+
+		rsaKey, err := os.ReadFile(rsaKeys.private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(rsaKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := parseWith(ks, signWith(t, jwt.SigningMethodRS256, "", privateKey)); err == nil {
+			t.Error("a RS256 token was accepted by a HS256 key set")
+		}
+		if err := parseWith(ks, signWith(t, jwt.SigningMethodHS384, "", []byte(strings.Repeat("k", 32)))); err == nil {
+			t.Error("a HS384 token was accepted by a HS256 key set")
+		}
+	})
+}
+
+func TestKeySetKeyID(t *testing.T) {
+	edKeys := generateEdKeys(t, t.TempDir(), "current")
+	ks := mustKeySet(t, map[string]string{
+		"JWT_ALGORITHM":        "EdDSA",
+		"JWT_SIGNING_KEY_ID":   "current",
+		"JWT_SIGNING_KEY_FILE": edKeys.private,
+	})
+
+	privatePEM, err := os.ReadFile(edKeys.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := jwt.ParseEdPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		kid     string
+		wantErr bool
+	}{
+		{name: "signing key id", kid: "current"},
+		{name: "unknown key id", kid: "unknown", wantErr: true},
+		{name: "missing key id", kid: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseWith(ks, signWith(t, jwt.SigningMethodEdDSA, tt.kid, privateKey))
+			if tt.wantErr && err == nil {
+				t.Errorf("a token of kid %q was accepted", tt.kid)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("a token of kid %q was refused, %v", tt.kid, err)
+			}
+		})
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	rsaKeys := generateRSAKeys(t, t.TempDir(), "current")
+	verifyDir := t.TempDir()
+	previous := generateEdKeys(t, verifyDir, "previous")
+	if err := os.Remove(previous.private); err != nil {
+		t.Fatal(err)
+	}
+
+	ks := mustKeySet(t, map[string]string{
+		"JWT_ALGORITHM":        "RS256",
+		"JWT_SIGNING_KEY_ID":   "current",
+		"JWT_SIGNING_KEY_FILE": rsaKeys.private,
+		"JWT_VERIFY_KEYS_DIR":  verifyDir,
+	})
+	jwks := ks.jwks()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(jwks.Keys))
+	}
+
+	rsaKey := jwks.Keys[0]
+	privatePEM, err := os.ReadFile(rsaKeys.private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantN := base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes())
+	if rsaKey.Kid != "current" || rsaKey.Kty != "RSA" || rsaKey.Alg != "RS256" || rsaKey.Use != "sig" || rsaKey.N != wantN || rsaKey.E != "AQAB" {
+		t.Errorf("RSA JWK = %+v, want kid current with the signing key modulus", rsaKey)
+	}
+
+	edKey := jwks.Keys[1]
+	publicPEM, err := os.ReadFile(previous.public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := jwt.ParseEdPublicKeyFromPEM(publicPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantX := base64.RawURLEncoding.EncodeToString(publicKey.(ed25519.PublicKey))
+	if edKey.Kid != "previous" || edKey.Kty != "OKP" || edKey.Alg != "EdDSA" || edKey.Crv != "Ed25519" || edKey.X != wantX {
+		t.Errorf("Ed25519 JWK = %+v, want kid previous with the verification key", edKey)
+	}
+
+	hs := mustKeySet(t, map[string]string{"JWT_ALGORITHM": "HS256", "SECRET_KEY": strings.Repeat("k", 32)})
+	if keys := hs.jwks().Keys; keys == nil || len(keys) != 0 {
+		t.Errorf("HS256 JWKS keys = %v, want an empty list", keys)
+	}
+}
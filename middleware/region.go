@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"mvp-shop-backend/models"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegionHeader selects the tax region of carts and orders, like the region query parameter
+const RegionHeader = "X-Region"
+
+// RegionMiddleware sets "region" to the region query parameter, the X-Region header or TAX_REGION. The region is
+// empty when none is set, only the tax rules of every region apply then.
+func RegionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.Query("region")
+		if value == "" {
+			value = c.GetHeader(RegionHeader)
+		}
+		if value == "" {
+			value = os.Getenv("TAX_REGION")
+		}
+
+		region := ""
+		if value != "" {
+			var err error
+			if region, err = models.ParseRegion(value); err != nil {
+				c.JSON(http.StatusBadRequest, models.Response{
+					Code:    http.StatusBadRequest,
+					Message: err.Error(),
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("region", region)
+		c.Next()
+	}
+}
+
+// RequestRegion returns the region set by RegionMiddleware
+func RequestRegion(c *gin.Context) string {
+	return c.GetString("region")
+}
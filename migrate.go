@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mvp-shop-backend/migration"
+	"mvp-shop-backend/pkg/logger"
+	"os"
+	"strconv"
+	"time"
+)
+
+func runMigrate(ctx context.Context, migrator *migration.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [steps]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migration")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid steps %q", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, m := range rolledBack {
+			fmt.Printf("rolled back %d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, usage: migrate up|down [steps]|status", args[0])
+	}
+}
+
+// migrateOnStart applies the pending migrations when DB_MIGRATE_ON_START is true, otherwise it refuses
+// to start on a schema that is behind the code
+func migrateOnStart(ctx context.Context, migrator *migration.Migrator) error {
+	if os.Getenv("DB_MIGRATE_ON_START") == "true" {
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			logger.Infof("applied migration %d_%s", m.Version, m.Name)
+		}
+		return err
+	}
+
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d pending migrations, run `migrate up` or set DB_MIGRATE_ON_START=true", pending)
+	}
+	return nil
+}
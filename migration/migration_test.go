@@ -0,0 +1,268 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"mvp-shop-backend/models"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// allModels must list every table the migrations create
+var allModels = []interface{}{
+	&models.Customer{},
+	&models.ProductCategory{},
+	&models.Product{},
+	&models.Cart{},
+	&models.Order{},
+	&models.OrderDetail{},
+	&models.OrderStatusHistory{},
+	&models.Payment{},
+	&models.RefreshToken{},
+	&models.RevokedToken{},
+	&models.LoginAttempt{},
+	&models.LoginAudit{},
+	&models.CustomerToken{},
+	&models.ExchangeRate{},
+	&models.ProductPrice{},
+	&models.TaxRule{},
+	&models.Promotion{},
+	&models.PromotionRedemption{},
+	&models.CartCoupon{},
+	&models.Address{},
+	&models.ProductVariant{},
+	&models.ProductImage{},
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migration embedded")
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			t.Fatalf("duplicated migration version %d", migrations[i].Version)
+		}
+	}
+}
+
+// TestMigrationsMatchModels applies every migration to a local Postgres and compares the schema with the one
+// AutoMigrate creates from the models. It runs when TEST_DATABASE_DSN is set, e.g.
+// TEST_DATABASE_DSN="host=localhost user=postgres password=postgres dbname=mvp_shop_test sslmode=disable"
+func TestMigrationsMatchModels(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	ctx := context.Background()
+	migrated := openSchema(t, dsn, "migration_test_sql")
+	automigrated := openSchema(t, dsn, "migration_test_gorm")
+
+	sqlDB, err := migrated.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := automigrated.AutoMigrate(allModels...); err != nil {
+		t.Fatal(err)
+	}
+
+	compare(t, "column", schemaColumns(t, migrated, "migration_test_sql"), schemaColumns(t, automigrated, "migration_test_gorm"))
+	compare(t, "index", schemaIndexes(t, migrated, "migration_test_sql"), schemaIndexes(t, automigrated, "migration_test_gorm"))
+
+	// every down file has to undo its up file
+	migrations, _ := Load()
+	if _, err := migrator.Down(ctx, len(migrations)); err != nil {
+		t.Fatal(err)
+	}
+	if columns := schemaColumns(t, migrated, "migration_test_sql"); len(columns) != 0 {
+		t.Fatalf("tables left after rolling back every migration: %v", columns)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMigrationsAdoptBaseline applies every migration to a database created by the former ddl.sql and compares the
+// schema with the one AutoMigrate creates from the models. It runs when TEST_DATABASE_DSN is set.
+func TestMigrationsAdoptBaseline(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	ctx := context.Background()
+	baseline := openSchema(t, dsn, "migration_test_baseline")
+	automigrated := openSchema(t, dsn, "migration_test_baseline_gorm")
+
+	ddl, err := os.ReadFile("testdata/baseline_ddl.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := baseline.Exec(string(ddl)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := baseline.Exec(`INSERT INTO customers (id, email, "name", "password", "status", created_by)
+		VALUES ('c1', 'customer@example.com', 'Customer', 'hash', 'active', 'test');
+		INSERT INTO "orders" (invoice, customer_id, amount, payment, "status", created_by)
+		VALUES ('INV-1', 'c1', 10, true, 'active', 'test'), ('INV-2', 'c1', 20, false, 'active', 'test')`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB, err := baseline.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := automigrated.AutoMigrate(allModels...); err != nil {
+		t.Fatal(err)
+	}
+
+	compare(t, "column", schemaColumns(t, baseline, "migration_test_baseline"), schemaColumns(t, automigrated, "migration_test_baseline_gorm"))
+	compare(t, "index", schemaIndexes(t, baseline, "migration_test_baseline"), schemaIndexes(t, automigrated, "migration_test_baseline_gorm"))
+
+	var role string
+	if err := baseline.Raw(`SELECT "role" FROM customers WHERE id = 'c1'`).Scan(&role).Error; err != nil {
+		t.Fatal(err)
+	}
+	if role != "customer" {
+		t.Errorf("role of an existing customer = %q, want customer", role)
+	}
+	var statuses []string
+	if err := baseline.Raw(`SELECT order_status FROM "orders" ORDER BY invoice`).Scan(&statuses).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(statuses, ",") != "paid,pending" {
+		t.Errorf("order_status of existing orders = %v, want paid and pending", statuses)
+	}
+}
+
+// openSchema connects with search_path set to a new empty schema, dropped when the test ends. public stays in the
+// search_path for the operator classes of the extensions, which both schemas share.
+func openSchema(t *testing.T, dsn string, schema string) *gorm.DB {
+	t.Helper()
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s", schema, schema)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "&"
+		if !strings.Contains(dsn, "?") {
+			separator = "?"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+separator+"search_path="+schema+",public"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema))
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func schemaColumns(t *testing.T, db *gorm.DB, schema string) map[string]string {
+	t.Helper()
+
+	var columns []struct {
+		TableName     string
+		ColumnName    string
+		DataType      string
+		MaxLength     int
+		IsNullable    string
+		ColumnDefault string
+	}
+	if err := db.Raw(`SELECT table_name, column_name, data_type, coalesce(character_maximum_length, 0) AS max_length,
+		is_nullable, coalesce(column_default, '') AS column_default
+		FROM information_schema.columns WHERE table_schema = ? AND table_name <> 'schema_migrations'`, schema).
+		Scan(&columns).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[string]string, len(columns))
+	for _, c := range columns {
+		// DEFAULT NULL and no default are the same
+		if strings.HasPrefix(c.ColumnDefault, "NULL::") {
+			c.ColumnDefault = ""
+		}
+		result[c.TableName+"."+c.ColumnName] = fmt.Sprintf("%s(%d) nullable=%s default=%s", c.DataType, c.MaxLength, c.IsNullable, c.ColumnDefault)
+	}
+	return result
+}
+
+func schemaIndexes(t *testing.T, db *gorm.DB, schema string) map[string]string {
+	t.Helper()
+
+	var indexes []struct {
+		IndexName string
+		IndexDef  string
+	}
+	if err := db.Raw(`SELECT indexname AS index_name, indexdef AS index_def
+		FROM pg_indexes WHERE schemaname = ? AND tablename <> 'schema_migrations'`, schema).
+		Scan(&indexes).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(map[string]string, len(indexes))
+	for _, i := range indexes {
+		result[i.IndexName] = strings.ReplaceAll(i.IndexDef, schema+".", "")
+	}
+	return result
+}
+
+func compare(t *testing.T, kind string, migrated, automigrated map[string]string) {
+	t.Helper()
+
+	for name, want := range automigrated {
+		got, ok := migrated[name]
+		if !ok {
+			t.Errorf("%s %s is missing from the migrations, models have %s", kind, name, want)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s %s is %s in the migrations, models have %s", kind, name, got, want)
+		}
+	}
+	for name, got := range migrated {
+		if _, ok := automigrated[name]; !ok {
+			t.Errorf("%s %s is not in the models, migrations have %s", kind, name, got)
+		}
+	}
+}
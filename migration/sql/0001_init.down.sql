@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS customer_tokens;
+DROP TABLE IF EXISTS login_audits;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS order_details;
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS "orders";
+DROP TABLE IF EXISTS carts;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS product_categories;
+DROP TABLE IF EXISTS customers;
@@ -0,0 +1,240 @@
+-- Baseline schema. Every statement is idempotent so databases created by the former
+-- AutoMigrate or ddl.sql can adopt the migrations by applying it.
+
+CREATE TABLE IF NOT EXISTS customers (
+	id varchar(36) NOT NULL,
+	email varchar(100) NOT NULL,
+	"name" varchar(250) NOT NULL,
+	"password" varchar(150) NOT NULL,
+	"role" varchar(10) DEFAULT 'customer'::character varying NOT NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	email_verified_at timestamptz NULL,
+	failed_login_attempts int8 DEFAULT 0 NOT NULL,
+	locked_until timestamptz NULL,
+	CONSTRAINT customers_pkey PRIMARY KEY (id),
+	CONSTRAINT uni_customers_email UNIQUE (email)
+);
+-- the customers of the former ddl.sql lack the columns added since
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS "role" varchar(10) DEFAULT 'customer'::character varying NOT NULL;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS email_verified_at timestamptz NULL;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS failed_login_attempts int8 DEFAULT 0 NOT NULL;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS locked_until timestamptz NULL;
+CREATE INDEX IF NOT EXISTS idx_customers_email ON customers USING btree (email);
+CREATE INDEX IF NOT EXISTS idx_customers_id ON customers USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_customers_name ON customers USING btree ("name");
+CREATE INDEX IF NOT EXISTS idx_customers_password ON customers USING btree ("password");
+CREATE INDEX IF NOT EXISTS idx_customers_role ON customers USING btree ("role");
+CREATE INDEX IF NOT EXISTS idx_customers_status ON customers USING btree ("status");
+
+CREATE TABLE IF NOT EXISTS product_categories (
+	id varchar(36) NOT NULL,
+	"name" varchar(250) NOT NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT product_categories_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_product_categories_id ON product_categories USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_product_categories_name ON product_categories USING btree ("name");
+CREATE INDEX IF NOT EXISTS idx_product_categories_status ON product_categories USING btree ("status");
+
+CREATE TABLE IF NOT EXISTS products (
+	id varchar(36) NOT NULL,
+	"name" varchar(250) NOT NULL,
+	price numeric NULL,
+	stock numeric NULL,
+	"status" varchar(10) NOT NULL,
+	"category_id" varchar(36) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT products_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_products_id ON products USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_products_name ON products USING btree ("name");
+CREATE INDEX IF NOT EXISTS idx_products_price ON products USING btree (price);
+CREATE INDEX IF NOT EXISTS idx_products_stock ON products USING btree (stock);
+CREATE INDEX IF NOT EXISTS idx_products_status ON products USING btree ("status");
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products USING btree (category_id);
+
+CREATE TABLE IF NOT EXISTS carts (
+	id varchar(36) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	product_id varchar(36) NOT NULL,
+	qty numeric NULL,
+	price numeric NULL,
+	amount numeric NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT carts_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_carts_id ON carts USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_carts_customer_id ON carts USING btree (customer_id);
+CREATE INDEX IF NOT EXISTS idx_carts_product_id ON carts USING btree (product_id);
+CREATE INDEX IF NOT EXISTS idx_carts_qty ON carts USING btree (qty);
+CREATE INDEX IF NOT EXISTS idx_carts_price ON carts USING btree (price);
+CREATE INDEX IF NOT EXISTS idx_carts_amount ON carts USING btree (amount);
+CREATE INDEX IF NOT EXISTS idx_carts_status ON carts USING btree ("status");
+
+-- databases created from the former ddl.sql have an integer qty
+ALTER TABLE carts ALTER COLUMN qty TYPE numeric;
+
+CREATE TABLE IF NOT EXISTS "orders" (
+	invoice varchar(100) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	amount numeric NULL,
+	payment bool DEFAULT false NOT NULL,
+	order_status varchar(10) DEFAULT 'pending'::character varying NOT NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT orders_pkey PRIMARY KEY (invoice)
+);
+-- the orders of the former ddl.sql have no status, the paid ones are paid and the others pending
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'orders' AND column_name = 'order_status') THEN
+		ALTER TABLE "orders" ADD COLUMN order_status varchar(10) DEFAULT 'pending'::character varying NOT NULL;
+		UPDATE "orders" SET order_status = 'paid' WHERE payment;
+	END IF;
+END
+$$;
+CREATE INDEX IF NOT EXISTS idx_orders_amount ON "orders" USING btree (amount);
+CREATE INDEX IF NOT EXISTS idx_orders_invoice ON "orders" USING btree (invoice);
+CREATE INDEX IF NOT EXISTS idx_orders_payment ON "orders" USING btree (payment);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON "orders" USING btree ("status");
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON "orders" USING btree (customer_id);
+CREATE INDEX IF NOT EXISTS idx_orders_order_status ON "orders" USING btree (order_status);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+	id varchar(36) NOT NULL,
+	invoice varchar(100) NOT NULL,
+	from_status varchar(10) NULL,
+	to_status varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	CONSTRAINT order_status_history_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_id ON order_status_history USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_invoice ON order_status_history USING btree (invoice);
+
+CREATE TABLE IF NOT EXISTS order_details (
+	invoice varchar(100) NOT NULL,
+	product_id varchar(36) NOT NULL,
+	qty numeric NULL,
+	price numeric NULL,
+	amount numeric NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL
+);
+CREATE INDEX IF NOT EXISTS idx_order_details_amount ON order_details USING btree (amount);
+CREATE INDEX IF NOT EXISTS idx_order_details_invoice ON order_details USING btree (invoice);
+CREATE INDEX IF NOT EXISTS idx_order_details_price ON order_details USING btree (price);
+CREATE INDEX IF NOT EXISTS idx_order_details_product_id ON order_details USING btree (product_id);
+CREATE INDEX IF NOT EXISTS idx_order_details_qty ON order_details USING btree (qty);
+CREATE INDEX IF NOT EXISTS idx_order_details_status ON order_details USING btree ("status");
+
+CREATE TABLE IF NOT EXISTS payments (
+	id varchar(36) NOT NULL,
+	invoice varchar(100) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	provider varchar(50) NOT NULL,
+	intent_id varchar(100) NOT NULL,
+	amount numeric NULL,
+	payment_status varchar(10) DEFAULT 'pending'::character varying NOT NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT payments_pkey PRIMARY KEY (id),
+	CONSTRAINT uni_payments_intent_id UNIQUE (intent_id)
+);
+CREATE INDEX IF NOT EXISTS idx_payments_id ON payments USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_payments_invoice ON payments USING btree (invoice);
+CREATE INDEX IF NOT EXISTS idx_payments_customer_id ON payments USING btree (customer_id);
+CREATE INDEX IF NOT EXISTS idx_payments_intent_id ON payments USING btree (intent_id);
+CREATE INDEX IF NOT EXISTS idx_payments_amount ON payments USING btree (amount);
+CREATE INDEX IF NOT EXISTS idx_payments_payment_status ON payments USING btree (payment_status);
+CREATE INDEX IF NOT EXISTS idx_payments_status ON payments USING btree ("status");
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id varchar(36) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	family_id varchar(36) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	expires_at timestamptz NOT NULL,
+	revoked_at timestamptz NULL,
+	replaced_by varchar(36) DEFAULT NULL::character varying NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT refresh_tokens_pkey PRIMARY KEY (id),
+	CONSTRAINT uni_refresh_tokens_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_id ON refresh_tokens USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_customer_id ON refresh_tokens USING btree (customer_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens USING btree (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens USING btree (token_hash);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti varchar(36) NOT NULL,
+	expires_at timestamptz NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT revoked_tokens_pkey PRIMARY KEY (jti)
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens USING btree (jti);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens USING btree (expires_at);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+	"key" varchar(150) NOT NULL,
+	failures int8 DEFAULT 0 NOT NULL,
+	locked_until timestamptz NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT login_attempts_pkey PRIMARY KEY ("key")
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_key ON login_attempts USING btree ("key");
+
+CREATE TABLE IF NOT EXISTS login_audits (
+	id varchar(36) NOT NULL,
+	"key" varchar(150) NOT NULL,
+	"event" varchar(20) NOT NULL,
+	ip varchar(45) NULL,
+	failures int8 NULL,
+	locked_until timestamptz NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT login_audits_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_login_audits_id ON login_audits USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_login_audits_key ON login_audits USING btree ("key");
+CREATE INDEX IF NOT EXISTS idx_login_audits_event ON login_audits USING btree ("event");
+
+CREATE TABLE IF NOT EXISTS customer_tokens (
+	id varchar(36) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	purpose varchar(20) NOT NULL,
+	token_hash varchar(64) NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	CONSTRAINT customer_tokens_pkey PRIMARY KEY (id),
+	CONSTRAINT uni_customer_tokens_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX IF NOT EXISTS idx_customer_tokens_id ON customer_tokens USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_customer_tokens_customer_id ON customer_tokens USING btree (customer_id);
+CREATE INDEX IF NOT EXISTS idx_customer_tokens_purpose ON customer_tokens USING btree (purpose);
+CREATE INDEX IF NOT EXISTS idx_customer_tokens_token_hash ON customer_tokens USING btree (token_hash);
@@ -0,0 +1,17 @@
+ALTER TABLE customer_tokens DROP CONSTRAINT IF EXISTS fk_customer_tokens_customer_id;
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_customer_id;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS fk_payments_customer_id;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS fk_payments_invoice;
+ALTER TABLE order_status_history DROP CONSTRAINT IF EXISTS fk_order_status_history_invoice;
+ALTER TABLE order_details DROP CONSTRAINT IF EXISTS fk_order_details_product_id;
+ALTER TABLE order_details DROP CONSTRAINT IF EXISTS fk_order_details_invoice;
+ALTER TABLE "orders" DROP CONSTRAINT IF EXISTS fk_orders_customer_id;
+ALTER TABLE carts DROP CONSTRAINT IF EXISTS fk_carts_product_id;
+ALTER TABLE carts DROP CONSTRAINT IF EXISTS fk_carts_customer_id;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS fk_products_category_id;
+
+DROP INDEX IF EXISTS uni_carts_customer_id_product_id;
+
+DROP INDEX IF EXISTS idx_order_details_id;
+ALTER TABLE order_details DROP CONSTRAINT IF EXISTS order_details_pkey;
+ALTER TABLE order_details DROP COLUMN IF EXISTS id;
@@ -0,0 +1,31 @@
+-- order_details gets a surrogate key, existing lines are numbered with random uuids
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS id varchar(36) NULL;
+UPDATE order_details SET id = gen_random_uuid()::varchar WHERE id IS NULL;
+ALTER TABLE order_details ALTER COLUMN id SET NOT NULL;
+ALTER TABLE order_details ADD CONSTRAINT order_details_pkey PRIMARY KEY (id);
+CREATE INDEX IF NOT EXISTS idx_order_details_id ON order_details USING btree (id);
+
+-- keep the newest open line of a product when a cart already has duplicates
+UPDATE carts SET "status" = 'deleted', updated_at = now(), updated_by = 'migration'
+WHERE id IN (
+	SELECT id FROM (
+		SELECT id, row_number() OVER (PARTITION BY customer_id, product_id ORDER BY created_at DESC) AS n
+		FROM carts WHERE "status" <> 'deleted' AND "status" <> 'checkout'
+	) lines WHERE n > 1
+);
+-- a customer has at most one open cart line per product, deleted and checked out lines are history
+CREATE UNIQUE INDEX IF NOT EXISTS uni_carts_customer_id_product_id ON carts USING btree (customer_id, product_id)
+	WHERE "status" <> 'deleted' AND "status" <> 'checkout';
+
+-- the statements below fail on rows referencing missing parents, fix or delete them before migrating
+ALTER TABLE products ADD CONSTRAINT fk_products_category_id FOREIGN KEY (category_id) REFERENCES product_categories (id);
+ALTER TABLE carts ADD CONSTRAINT fk_carts_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id);
+ALTER TABLE carts ADD CONSTRAINT fk_carts_product_id FOREIGN KEY (product_id) REFERENCES products (id);
+ALTER TABLE "orders" ADD CONSTRAINT fk_orders_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id);
+ALTER TABLE order_details ADD CONSTRAINT fk_order_details_invoice FOREIGN KEY (invoice) REFERENCES "orders" (invoice) ON DELETE CASCADE;
+ALTER TABLE order_details ADD CONSTRAINT fk_order_details_product_id FOREIGN KEY (product_id) REFERENCES products (id);
+ALTER TABLE order_status_history ADD CONSTRAINT fk_order_status_history_invoice FOREIGN KEY (invoice) REFERENCES "orders" (invoice) ON DELETE CASCADE;
+ALTER TABLE payments ADD CONSTRAINT fk_payments_invoice FOREIGN KEY (invoice) REFERENCES "orders" (invoice);
+ALTER TABLE payments ADD CONSTRAINT fk_payments_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id);
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE;
+ALTER TABLE customer_tokens ADD CONSTRAINT fk_customer_tokens_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE;
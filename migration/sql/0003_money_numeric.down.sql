@@ -0,0 +1,7 @@
+ALTER TABLE payments ALTER COLUMN amount TYPE numeric;
+ALTER TABLE order_details ALTER COLUMN amount TYPE numeric;
+ALTER TABLE order_details ALTER COLUMN price TYPE numeric;
+ALTER TABLE "orders" ALTER COLUMN amount TYPE numeric;
+ALTER TABLE carts ALTER COLUMN amount TYPE numeric;
+ALTER TABLE carts ALTER COLUMN price TYPE numeric;
+ALTER TABLE products ALTER COLUMN price TYPE numeric;
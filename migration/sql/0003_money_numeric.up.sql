@@ -0,0 +1,8 @@
+-- money is kept with 4 decimals, amounts with more decimals are rounded half away from zero like the application does
+ALTER TABLE products ALTER COLUMN price TYPE numeric(19,4) USING round(price, 4);
+ALTER TABLE carts ALTER COLUMN price TYPE numeric(19,4) USING round(price, 4);
+ALTER TABLE carts ALTER COLUMN amount TYPE numeric(19,4) USING round(amount, 4);
+ALTER TABLE "orders" ALTER COLUMN amount TYPE numeric(19,4) USING round(amount, 4);
+ALTER TABLE order_details ALTER COLUMN price TYPE numeric(19,4) USING round(price, 4);
+ALTER TABLE order_details ALTER COLUMN amount TYPE numeric(19,4) USING round(amount, 4);
+ALTER TABLE payments ALTER COLUMN amount TYPE numeric(19,4) USING round(amount, 4);
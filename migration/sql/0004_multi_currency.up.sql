@@ -0,0 +1,31 @@
+-- a currency can be sold in once it has a rate, the amount of it one unit of the default currency buys
+CREATE TABLE IF NOT EXISTS exchange_rates (
+	currency varchar(3) NOT NULL,
+	rate numeric(19,8) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT exchange_rates_pkey PRIMARY KEY (currency)
+);
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_currency ON exchange_rates USING btree (currency);
+
+-- explicit prices replace the converted default currency price of a product
+CREATE TABLE IF NOT EXISTS product_prices (
+	product_id varchar(36) NOT NULL,
+	currency varchar(3) NOT NULL,
+	price numeric(19,4) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT product_prices_pkey PRIMARY KEY (product_id, currency),
+	CONSTRAINT fk_product_prices_product_id FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_product_prices_product_id ON product_prices USING btree (product_id);
+CREATE INDEX IF NOT EXISTS idx_product_prices_currency ON product_prices USING btree (currency);
+
+-- orders and payments created before are in the default currency, a null currency reads as it
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS currency varchar(3) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS exchange_rate numeric(19,8) DEFAULT 1 NOT NULL;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS currency varchar(3) NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE order_details DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE order_details DROP COLUMN IF EXISTS tax_inclusive;
+ALTER TABLE order_details DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_amount;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS subtotal;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS region;
+DROP TABLE IF EXISTS tax_rules;
@@ -0,0 +1,30 @@
+-- a rule without region applies to every region and a rule without category to every category
+CREATE TABLE IF NOT EXISTS tax_rules (
+	id varchar(36) NOT NULL,
+	"name" varchar(100) NOT NULL,
+	region varchar(10) NULL,
+	category_id varchar(36) NULL,
+	rate numeric(7,4) NOT NULL,
+	inclusive bool DEFAULT false NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT tax_rules_pkey PRIMARY KEY (id),
+	CONSTRAINT fk_tax_rules_category_id FOREIGN KEY (category_id) REFERENCES product_categories (id)
+);
+CREATE INDEX IF NOT EXISTS idx_tax_rules_id ON tax_rules USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_tax_rules_region ON tax_rules USING btree (region);
+CREATE INDEX IF NOT EXISTS idx_tax_rules_category_id ON tax_rules USING btree (category_id);
+
+-- orders created before were not taxed, their subtotal is their amount
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS region varchar(10) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS subtotal numeric(19,4) DEFAULT 0 NOT NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS tax_amount numeric(19,4) DEFAULT 0 NOT NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS discount_amount numeric(19,4) DEFAULT 0 NOT NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_amount numeric(19,4) DEFAULT 0 NOT NULL;
+UPDATE "orders" SET subtotal = amount WHERE amount IS NOT NULL;
+
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS tax_rate numeric(7,4) DEFAULT 0 NOT NULL;
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS tax_inclusive bool DEFAULT false NOT NULL;
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS tax_amount numeric(19,4) DEFAULT 0 NOT NULL;
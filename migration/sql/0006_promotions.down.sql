@@ -0,0 +1,5 @@
+ALTER TABLE order_details DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS coupon_code;
+DROP TABLE IF EXISTS cart_coupons;
+DROP TABLE IF EXISTS promotion_redemptions;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,67 @@
+-- amount and min_spend are in the default currency, a deleted promotion frees its code
+CREATE TABLE IF NOT EXISTS promotions (
+	id varchar(36) NOT NULL,
+	code varchar(50) NOT NULL,
+	"name" varchar(100) NOT NULL,
+	"type" varchar(20) NOT NULL,
+	"percent" numeric(7,4) DEFAULT 0 NOT NULL,
+	amount numeric(19,4) DEFAULT 0 NOT NULL,
+	buy_qty int8 DEFAULT 0 NOT NULL,
+	get_qty int8 DEFAULT 0 NOT NULL,
+	min_spend numeric(19,4) DEFAULT 0 NOT NULL,
+	category_id varchar(36) NULL,
+	product_id varchar(36) NULL,
+	starts_at timestamptz NULL,
+	ends_at timestamptz NULL,
+	usage_limit int8 NULL,
+	usage_limit_per_customer int8 NULL,
+	used_count int8 DEFAULT 0 NOT NULL,
+	status varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT promotions_pkey PRIMARY KEY (id),
+	CONSTRAINT fk_promotions_category_id FOREIGN KEY (category_id) REFERENCES product_categories (id),
+	CONSTRAINT fk_promotions_product_id FOREIGN KEY (product_id) REFERENCES products (id)
+);
+CREATE INDEX IF NOT EXISTS idx_promotions_id ON promotions USING btree (id);
+CREATE UNIQUE INDEX IF NOT EXISTS uni_promotions_code ON promotions USING btree (code) WHERE status <> 'deleted';
+CREATE INDEX IF NOT EXISTS idx_promotions_category_id ON promotions USING btree (category_id);
+CREATE INDEX IF NOT EXISTS idx_promotions_product_id ON promotions USING btree (product_id);
+CREATE INDEX IF NOT EXISTS idx_promotions_status ON promotions USING btree (status);
+
+-- an order redeems at most one promotion, the redemption is deleted when the order is cancelled
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+	id varchar(36) NOT NULL,
+	promotion_id varchar(36) NOT NULL,
+	invoice varchar(100) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	discount_amount numeric(19,4) NOT NULL,
+	currency varchar(3) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	CONSTRAINT promotion_redemptions_pkey PRIMARY KEY (id),
+	CONSTRAINT fk_promotion_redemptions_promotion_id FOREIGN KEY (promotion_id) REFERENCES promotions (id),
+	CONSTRAINT fk_promotion_redemptions_invoice FOREIGN KEY (invoice) REFERENCES "orders" (invoice) ON DELETE CASCADE,
+	CONSTRAINT fk_promotion_redemptions_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id)
+);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_id ON promotion_redemptions USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_promotion_id ON promotion_redemptions USING btree (promotion_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uni_promotion_redemptions_invoice ON promotion_redemptions USING btree (invoice);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_customer_id ON promotion_redemptions USING btree (customer_id);
+
+CREATE TABLE IF NOT EXISTS cart_coupons (
+	customer_id varchar(36) NOT NULL,
+	promotion_id varchar(36) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	CONSTRAINT cart_coupons_pkey PRIMARY KEY (customer_id),
+	CONSTRAINT fk_cart_coupons_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE,
+	CONSTRAINT fk_cart_coupons_promotion_id FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_cart_coupons_customer_id ON cart_coupons USING btree (customer_id);
+CREATE INDEX IF NOT EXISTS idx_cart_coupons_promotion_id ON cart_coupons USING btree (promotion_id);
+
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS coupon_code varchar(50) NULL;
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS discount_amount numeric(19,4) DEFAULT 0 NOT NULL;
@@ -0,0 +1,10 @@
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_region;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_postal_code;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_province;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_city;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_street;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_phone;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_recipient_name;
+ALTER TABLE "orders" DROP COLUMN IF EXISTS shipping_method;
+ALTER TABLE products DROP COLUMN IF EXISTS weight;
+DROP TABLE IF EXISTS addresses;
@@ -0,0 +1,36 @@
+-- a customer has at most one default address
+CREATE TABLE IF NOT EXISTS addresses (
+	id varchar(36) NOT NULL,
+	customer_id varchar(36) NOT NULL,
+	"label" varchar(50) NOT NULL,
+	recipient_name varchar(150) NOT NULL,
+	phone varchar(20) NOT NULL,
+	street varchar(250) NOT NULL,
+	city varchar(100) NOT NULL,
+	province varchar(100) NOT NULL,
+	postal_code varchar(20) NOT NULL,
+	region varchar(10) NOT NULL,
+	is_default bool DEFAULT false NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT addresses_pkey PRIMARY KEY (id),
+	CONSTRAINT fk_addresses_customer_id FOREIGN KEY (customer_id) REFERENCES customers (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_addresses_id ON addresses USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_addresses_customer_id ON addresses USING btree (customer_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uni_addresses_customer_id_default ON addresses USING btree (customer_id) WHERE is_default;
+
+-- weight in kg
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight numeric DEFAULT 0 NOT NULL;
+
+-- the address is copied on the order, orders placed before have no shipping address
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_method varchar(20) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_recipient_name varchar(150) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_phone varchar(20) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_street varchar(250) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_city varchar(100) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_province varchar(100) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_postal_code varchar(20) NULL;
+ALTER TABLE "orders" ADD COLUMN IF NOT EXISTS shipping_region varchar(10) NULL;
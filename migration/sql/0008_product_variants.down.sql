@@ -0,0 +1,17 @@
+ALTER TABLE order_details DROP COLUMN IF EXISTS variant_id;
+
+-- open lines of different variants of a product collapse into the newest one
+UPDATE carts SET "status" = 'deleted', updated_at = now(), updated_by = 'migration'
+WHERE id IN (
+	SELECT id FROM (
+		SELECT id, row_number() OVER (PARTITION BY customer_id, product_id ORDER BY created_at DESC) AS n
+		FROM carts WHERE "status" <> 'deleted' AND "status" <> 'checkout'
+	) lines WHERE n > 1
+);
+DROP INDEX IF EXISTS uni_carts_customer_id_variant_id;
+DROP INDEX IF EXISTS uni_carts_customer_id_product_id;
+ALTER TABLE carts DROP COLUMN IF EXISTS variant_id;
+CREATE UNIQUE INDEX IF NOT EXISTS uni_carts_customer_id_product_id ON carts USING btree (customer_id, product_id)
+	WHERE "status" <> 'deleted' AND "status" <> 'checkout';
+
+DROP TABLE IF EXISTS product_variants;
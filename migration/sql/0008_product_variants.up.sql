@@ -0,0 +1,37 @@
+-- variants are soft deleted, a deleted variant frees its sku and options for a new one
+CREATE TABLE IF NOT EXISTS product_variants (
+	id varchar(36) NOT NULL,
+	product_id varchar(36) NOT NULL,
+	sku varchar(100) NOT NULL,
+	"options" jsonb NOT NULL,
+	price numeric(19,4) NULL,
+	stock numeric DEFAULT 0 NOT NULL,
+	"status" varchar(10) NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	updated_at timestamptz NULL,
+	updated_by varchar(150) DEFAULT NULL::character varying NULL,
+	CONSTRAINT product_variants_pkey PRIMARY KEY (id),
+	CONSTRAINT fk_product_variants_product_id FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_product_variants_id ON product_variants USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants USING btree (product_id);
+CREATE INDEX IF NOT EXISTS idx_product_variants_status ON product_variants USING btree ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS uni_product_variants_sku ON product_variants USING btree (sku)
+	WHERE "status" <> 'deleted';
+CREATE UNIQUE INDEX IF NOT EXISTS uni_product_variants_product_id_options ON product_variants USING btree (product_id, "options")
+	WHERE "status" <> 'deleted';
+
+-- a customer has at most one open cart line per product without variant and one per variant
+ALTER TABLE carts ADD COLUMN IF NOT EXISTS variant_id varchar(36) NULL;
+ALTER TABLE carts ADD CONSTRAINT fk_carts_variant_id FOREIGN KEY (variant_id) REFERENCES product_variants (id);
+CREATE INDEX IF NOT EXISTS idx_carts_variant_id ON carts USING btree (variant_id);
+DROP INDEX IF EXISTS uni_carts_customer_id_product_id;
+CREATE UNIQUE INDEX IF NOT EXISTS uni_carts_customer_id_product_id ON carts USING btree (customer_id, product_id)
+	WHERE "status" <> 'deleted' AND "status" <> 'checkout' AND variant_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uni_carts_customer_id_variant_id ON carts USING btree (customer_id, variant_id)
+	WHERE "status" <> 'deleted' AND "status" <> 'checkout';
+
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS variant_id varchar(36) NULL;
+ALTER TABLE order_details ADD CONSTRAINT fk_order_details_variant_id FOREIGN KEY (variant_id) REFERENCES product_variants (id);
+CREATE INDEX IF NOT EXISTS idx_order_details_variant_id ON order_details USING btree (variant_id);
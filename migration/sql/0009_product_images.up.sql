@@ -0,0 +1,18 @@
+-- the files are in the blob store, rows are deleted with their blobs
+CREATE TABLE IF NOT EXISTS product_images (
+	id varchar(36) NOT NULL,
+	product_id varchar(36) NOT NULL,
+	"position" bigint NOT NULL,
+	"key" varchar(250) NOT NULL,
+	thumbnail_key varchar(250) NOT NULL,
+	content_type varchar(50) NOT NULL,
+	"size" bigint NOT NULL,
+	width bigint NOT NULL,
+	height bigint NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	created_by varchar(150) NOT NULL,
+	CONSTRAINT product_images_pkey PRIMARY KEY (id),
+	CONSTRAINT fk_product_images_product_id FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_product_images_id ON product_images USING btree (id);
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images USING btree (product_id);
@@ -0,0 +1,10 @@
+ALTER TABLE product_categories DROP COLUMN IF EXISTS attribute_schema;
+DROP INDEX IF EXISTS uni_products_slug;
+DROP INDEX IF EXISTS uni_products_sku;
+ALTER TABLE products DROP COLUMN IF EXISTS slug;
+ALTER TABLE products DROP COLUMN IF EXISTS "attributes";
+ALTER TABLE products DROP COLUMN IF EXISTS height;
+ALTER TABLE products DROP COLUMN IF EXISTS width;
+ALTER TABLE products DROP COLUMN IF EXISTS length;
+ALTER TABLE products DROP COLUMN IF EXISTS sku;
+ALTER TABLE products DROP COLUMN IF EXISTS description;
@@ -0,0 +1,20 @@
+-- existing products get an empty description, new ones always set it
+ALTER TABLE products ADD COLUMN IF NOT EXISTS description text DEFAULT '' NOT NULL;
+ALTER TABLE products ALTER COLUMN description DROP DEFAULT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku varchar(100) NULL;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS length numeric DEFAULT 0 NOT NULL;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS width numeric DEFAULT 0 NOT NULL;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS height numeric DEFAULT 0 NOT NULL;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS "attributes" jsonb DEFAULT '{}' NOT NULL;
+
+-- existing products get a slug made from their name and id, unique without looking at the other names
+ALTER TABLE products ADD COLUMN IF NOT EXISTS slug varchar(250) NULL;
+UPDATE products SET slug = trim(BOTH '-' FROM left(regexp_replace(lower("name"), '[^a-z0-9]+', '-', 'g'), 200)) || '-' || left(id, 8)
+WHERE slug IS NULL;
+ALTER TABLE products ALTER COLUMN slug SET NOT NULL;
+
+-- sku and slug of deleted products can be reused
+CREATE UNIQUE INDEX IF NOT EXISTS uni_products_sku ON products USING btree (sku) WHERE "status" <> 'deleted';
+CREATE UNIQUE INDEX IF NOT EXISTS uni_products_slug ON products USING btree (slug) WHERE "status" <> 'deleted';
+
+ALTER TABLE product_categories ADD COLUMN IF NOT EXISTS attribute_schema jsonb DEFAULT '[]' NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_product_categories_parent_id;
+ALTER TABLE product_categories DROP CONSTRAINT IF EXISTS fk_product_categories_parent_id;
+ALTER TABLE product_categories DROP COLUMN IF EXISTS parent_id;
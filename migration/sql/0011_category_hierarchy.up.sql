@@ -0,0 +1,4 @@
+-- existing categories become root categories
+ALTER TABLE product_categories ADD COLUMN IF NOT EXISTS parent_id varchar(36) NULL;
+ALTER TABLE product_categories ADD CONSTRAINT fk_product_categories_parent_id FOREIGN KEY (parent_id) REFERENCES product_categories (id);
+CREATE INDEX IF NOT EXISTS idx_product_categories_parent_id ON product_categories USING btree (parent_id);
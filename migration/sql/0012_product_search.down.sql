@@ -0,0 +1,9 @@
+-- pg_trgm is kept, other objects of the database may use it
+DROP TRIGGER IF EXISTS product_categories_search_vector ON product_categories;
+DROP FUNCTION IF EXISTS product_categories_search_vector_trigger();
+DROP TRIGGER IF EXISTS products_search_vector ON products;
+DROP FUNCTION IF EXISTS products_search_vector_trigger();
+DROP INDEX IF EXISTS idx_products_name_trgm;
+DROP INDEX IF EXISTS idx_products_search_vector;
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS product_search_vector(text, text, varchar);
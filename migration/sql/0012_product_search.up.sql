@@ -0,0 +1,37 @@
+-- trigram similarity of names for the fuzzy search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- the search document of a product, its name weighs most, then its category, then its description
+CREATE OR REPLACE FUNCTION product_search_vector(text, text, varchar) RETURNS tsvector AS $$
+	SELECT setweight(to_tsvector('english', coalesce($1, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce((SELECT "name" FROM product_categories WHERE id = $3), '')), 'B') ||
+		setweight(to_tsvector('english', coalesce($2, '')), 'C')
+$$ LANGUAGE sql STABLE;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector NULL;
+UPDATE products SET search_vector = product_search_vector("name", description, category_id);
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING gin ("name" gin_trgm_ops);
+
+CREATE OR REPLACE FUNCTION products_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+	NEW.search_vector := product_search_vector(NEW."name", NEW.description, NEW.category_id);
+	RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_search_vector ON products;
+CREATE TRIGGER products_search_vector BEFORE INSERT OR UPDATE OF "name", description, category_id ON products
+FOR EACH ROW EXECUTE FUNCTION products_search_vector_trigger();
+
+-- renaming a category updates the search document of its products
+CREATE OR REPLACE FUNCTION product_categories_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+	UPDATE products SET search_vector = product_search_vector("name", description, category_id) WHERE category_id = NEW.id;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_categories_search_vector ON product_categories;
+CREATE TRIGGER product_categories_search_vector AFTER UPDATE OF "name" ON product_categories
+FOR EACH ROW WHEN (OLD."name" IS DISTINCT FROM NEW."name") EXECUTE FUNCTION product_categories_search_vector_trigger();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_login_attempts_updated_at;
+ALTER TABLE customers DROP COLUMN IF EXISTS last_failed_login_at;
@@ -0,0 +1,5 @@
+-- the failure window of an account restarts after the lockout max without failure, it needs the last failure time
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS last_failed_login_at timestamptz NULL;
+
+-- stale login attempts are purged by their last failure
+CREATE INDEX IF NOT EXISTS idx_login_attempts_updated_at ON login_attempts USING btree (updated_at);
@@ -0,0 +1,3 @@
+-- the money of a pending refund was not given back yet
+UPDATE payments SET payment_status = 'succeeded' WHERE payment_status = 'refund_pending';
+ALTER TABLE payments ALTER COLUMN payment_status TYPE varchar(10);
@@ -0,0 +1,2 @@
+-- payments of cancelled orders are refund_pending until the provider refunds them
+ALTER TABLE payments ALTER COLUMN payment_status TYPE varchar(20);
@@ -0,0 +1,2 @@
+-- a password change bumps the token version, access tokens of an older version are refused
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS token_version int8 DEFAULT 0 NOT NULL;
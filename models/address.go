@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Address is an entry of a customer address book, Region is its ISO 3166 code like "ID-JK" used for taxes and shipping
+// zones. A customer has at most one default address.
+type Address struct {
+	ID            string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	CustomerID    string     `json:"customer_id" gorm:"not null;type:varchar(36);index;uniqueIndex:uni_addresses_customer_id_default,where:is_default"`
+	Label         string     `json:"label" gorm:"not null;type:varchar(50)"`
+	RecipientName string     `json:"recipient_name" gorm:"not null;type:varchar(150)"`
+	Phone         string     `json:"phone" gorm:"not null;type:varchar(20)"`
+	Street        string     `json:"street" gorm:"not null;type:varchar(250)"`
+	City          string     `json:"city" gorm:"not null;type:varchar(100)"`
+	Province      string     `json:"province" gorm:"not null;type:varchar(100)"`
+	PostalCode    string     `json:"postal_code" gorm:"not null;type:varchar(20)"`
+	Region        string     `json:"region" gorm:"not null;type:varchar(10)"`
+	IsDefault     bool       `json:"is_default" gorm:"not null;default:false"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy     string     `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy     *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (Address) TableName() string {
+	return "addresses"
+}
+
+type AddressRegister struct {
+	Label         string `json:"label" binding:"required,max=50"`
+	RecipientName string `json:"recipient_name" binding:"required,max=150"`
+	Phone         string `json:"phone" binding:"required,max=20"`
+	Street        string `json:"street" binding:"required,max=250"`
+	City          string `json:"city" binding:"required,max=100"`
+	Province      string `json:"province" binding:"max=100"`
+	PostalCode    string `json:"postal_code" binding:"required,max=20"`
+	Region        string `json:"region" binding:"required"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+// ShippingAddress is the address an order is shipped to, copied from the address book so editing or deleting the
+// address does not change the order
+type ShippingAddress struct {
+	RecipientName string `json:"recipient_name" gorm:"type:varchar(150)"`
+	Phone         string `json:"phone" gorm:"type:varchar(20)"`
+	Street        string `json:"street" gorm:"type:varchar(250)"`
+	City          string `json:"city" gorm:"type:varchar(100)"`
+	Province      string `json:"province" gorm:"type:varchar(100)"`
+	PostalCode    string `json:"postal_code" gorm:"type:varchar(20)"`
+	Region        string `json:"region" gorm:"type:varchar(10)"`
+}
+
+// Snapshot returns the shipping address of an order shipped to a
+func (a Address) Snapshot() ShippingAddress {
+	return ShippingAddress{
+		RecipientName: a.RecipientName,
+		Phone:         a.Phone,
+		Street:        a.Street,
+		City:          a.City,
+		Province:      a.Province,
+		PostalCode:    a.PostalCode,
+		Region:        a.Region,
+	}
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+type AttributeType string
+
+const (
+	AttributeTypeString  AttributeType = "string"
+	AttributeTypeNumber  AttributeType = "number"
+	AttributeTypeBoolean AttributeType = "boolean"
+	AttributeTypeEnum    AttributeType = "enum"
+)
+
+// attributeName keeps attribute names usable as search keys, like search=brand=acme
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ProductFilters are the search keys of the product list that are not attributes, attributes cannot be named like them
+var ProductFilters = map[string]bool{
+	"id":            true,
+	"name":          true,
+	"category_id":   true,
+	"category_name": true,
+	"sku":           true,
+	"slug":          true,
+}
+
+// AttributeDefinition is an attribute the products of a category may have, Values lists the values of an enum
+type AttributeDefinition struct {
+	Name     string        `json:"name"`
+	Type     AttributeType `json:"type"`
+	Required bool          `json:"required"`
+	Values   []string      `json:"values,omitempty"`
+}
+
+// AttributeSchema is the list of attributes of the products of a category
+type AttributeSchema []AttributeDefinition
+
+// Check refuses schemas with invalid, duplicated or untyped attributes and enums without values
+func (s AttributeSchema) Check() error {
+	names := make(map[string]bool, len(s))
+	for _, definition := range s {
+		if !attributeName.MatchString(definition.Name) {
+			return fmt.Errorf("attribute name %q must be lower case letters, digits and underscores", definition.Name)
+		}
+		if ProductFilters[definition.Name] {
+			return fmt.Errorf("attribute name %s is reserved", definition.Name)
+		}
+		if names[definition.Name] {
+			return fmt.Errorf("attribute %s is defined twice", definition.Name)
+		}
+		names[definition.Name] = true
+
+		switch definition.Type {
+		case AttributeTypeString, AttributeTypeNumber, AttributeTypeBoolean:
+			if len(definition.Values) > 0 {
+				return fmt.Errorf("attribute %s has values but is not an enum", definition.Name)
+			}
+		case AttributeTypeEnum:
+			if len(definition.Values) == 0 {
+				return fmt.Errorf("enum attribute %s needs values", definition.Name)
+			}
+		default:
+			return fmt.Errorf("attribute %s type must be string, number, boolean or enum", definition.Name)
+		}
+	}
+	return nil
+}
+
+// Validate checks attributes against the schema, every attribute must be defined and every required one set
+func (s AttributeSchema) Validate(attributes ProductAttributes) error {
+	definitions := make(map[string]AttributeDefinition, len(s))
+	for _, definition := range s {
+		definitions[definition.Name] = definition
+		if _, ok := attributes[definition.Name]; definition.Required && !ok {
+			return fmt.Errorf("attribute %s is required", definition.Name)
+		}
+	}
+
+	for _, name := range attributes.Names() {
+		definition, ok := definitions[name]
+		if !ok {
+			return fmt.Errorf("attribute %s is not defined for the category", name)
+		}
+		if !definition.valid(attributes[name]) {
+			if definition.Type == AttributeTypeEnum {
+				return fmt.Errorf("attribute %s must be one of %v", name, definition.Values)
+			}
+			return fmt.Errorf("attribute %s must be a %s", name, definition.Type)
+		}
+	}
+	return nil
+}
+
+func (d AttributeDefinition) valid(value interface{}) bool {
+	switch d.Type {
+	case AttributeTypeString:
+		_, ok := value.(string)
+		return ok
+	case AttributeTypeNumber:
+		_, ok := value.(float64)
+		return ok
+	case AttributeTypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case AttributeTypeEnum:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		for _, v := range d.Values {
+			if v == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		s = AttributeSchema{}
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *AttributeSchema) Scan(src interface{}) error {
+	return scanJSON(src, s)
+}
+
+// ProductAttributes are the attribute values of a product by name, strings, numbers and booleans as decoded from JSON
+type ProductAttributes map[string]interface{}
+
+// Names returns the sorted attribute names
+func (a ProductAttributes) Names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a ProductAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		a = ProductAttributes{}
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (a *ProductAttributes) Scan(src interface{}) error {
+	return scanJSON(src, a)
+}
+
+// IsAttributeName reports whether name can be the name of an attribute
+func IsAttributeName(name string) bool {
+	return attributeName.MatchString(name)
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	case []byte:
+		return json.Unmarshal(v, dst)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dst)
+	}
+}
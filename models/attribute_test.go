@@ -0,0 +1,140 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttributeSchemaCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  AttributeSchema
+		wantErr bool
+	}{
+		{name: "no attribute", schema: nil},
+		{
+			name: "every type",
+			schema: AttributeSchema{
+				{Name: "brand", Type: AttributeTypeString, Required: true},
+				{Name: "screen_size", Type: AttributeTypeNumber},
+				{Name: "wireless", Type: AttributeTypeBoolean},
+				{Name: "colour", Type: AttributeTypeEnum, Values: []string{"red", "blue"}},
+			},
+		},
+		{name: "upper case name", schema: AttributeSchema{{Name: "Brand", Type: AttributeTypeString}}, wantErr: true},
+		{name: "name with a space", schema: AttributeSchema{{Name: "screen size", Type: AttributeTypeNumber}}, wantErr: true},
+		{name: "empty name", schema: AttributeSchema{{Name: "", Type: AttributeTypeString}}, wantErr: true},
+		{name: "reserved name", schema: AttributeSchema{{Name: "category_id", Type: AttributeTypeString}}, wantErr: true},
+		{
+			name: "duplicated name",
+			schema: AttributeSchema{
+				{Name: "brand", Type: AttributeTypeString},
+				{Name: "brand", Type: AttributeTypeNumber},
+			},
+			wantErr: true,
+		},
+		{name: "unknown type", schema: AttributeSchema{{Name: "brand", Type: "text"}}, wantErr: true},
+		{name: "enum without values", schema: AttributeSchema{{Name: "colour", Type: AttributeTypeEnum}}, wantErr: true},
+		{name: "values of a string", schema: AttributeSchema{{Name: "brand", Type: AttributeTypeString, Values: []string{"acme"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schema.Check(); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttributeSchemaValidate(t *testing.T) {
+	schema := AttributeSchema{
+		{Name: "brand", Type: AttributeTypeString, Required: true},
+		{Name: "screen_size", Type: AttributeTypeNumber},
+		{Name: "wireless", Type: AttributeTypeBoolean},
+		{Name: "colour", Type: AttributeTypeEnum, Values: []string{"red", "blue"}},
+	}
+
+	tests := []struct {
+		name       string
+		attributes ProductAttributes
+		wantErr    string
+	}{
+		{name: "required only", attributes: ProductAttributes{"brand": "acme"}},
+		{
+			name:       "every attribute",
+			attributes: ProductAttributes{"brand": "acme", "screen_size": 15.6, "wireless": true, "colour": "blue"},
+		},
+		{name: "missing required", attributes: ProductAttributes{"colour": "red"}, wantErr: "attribute brand is required"},
+		{name: "no attribute", attributes: nil, wantErr: "attribute brand is required"},
+		{
+			name:       "unknown key",
+			attributes: ProductAttributes{"brand": "acme", "weight": 1.2},
+			wantErr:    "attribute weight is not defined for the category",
+		},
+		{name: "number as string", attributes: ProductAttributes{"brand": 42.0}, wantErr: "attribute brand must be a string"},
+		{
+			name:       "string as number",
+			attributes: ProductAttributes{"brand": "acme", "screen_size": "15.6"},
+			wantErr:    "attribute screen_size must be a number",
+		},
+		{
+			name:       "string as boolean",
+			attributes: ProductAttributes{"brand": "acme", "wireless": "yes"},
+			wantErr:    "attribute wireless must be a boolean",
+		},
+		{
+			name:       "value outside the enum",
+			attributes: ProductAttributes{"brand": "acme", "colour": "green"},
+			wantErr:    "attribute colour must be one of [red blue]",
+		},
+		{
+			name:       "enum value of another case",
+			attributes: ProductAttributes{"brand": "acme", "colour": "Red"},
+			wantErr:    "attribute colour must be one of [red blue]",
+		},
+		{
+			name:       "number as enum",
+			attributes: ProductAttributes{"brand": "acme", "colour": 1.0},
+			wantErr:    "attribute colour must be one of [red blue]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(tt.attributes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProductAttributesScan(t *testing.T) {
+	attributes := ProductAttributes{"brand": "acme", "screen_size": 15.6, "wireless": true}
+	value, err := attributes.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []interface{}{value, []byte(value.(string))} {
+		var scanned ProductAttributes
+		if err := scanned.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(scanned, attributes) {
+			t.Errorf("Scan(%T) = %v, want %v", src, scanned, attributes)
+		}
+	}
+	if want := []string{"brand", "screen_size", "wireless"}; !reflect.DeepEqual(attributes.Names(), want) {
+		t.Errorf("Names() = %v, want %v", attributes.Names(), want)
+	}
+
+	if value, err := (ProductAttributes)(nil).Value(); err != nil || value != "{}" {
+		t.Errorf("Value() of nil attributes = %v, %v, want {}", value, err)
+	}
+}
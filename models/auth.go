@@ -1,10 +1,123 @@
 package models
 
+import "time"
+
 type AuthLogin struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
 
 type AuthToken struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+type AuthRefresh struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type AuthLogout struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AuthVerifyEmail struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type AuthEmail struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type AuthResetPassword struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshToken struct {
+	ID         string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	CustomerID string     `json:"customer_id" gorm:"not null;type:varchar(36);index"`
+	FamilyID   string     `json:"family_id" gorm:"not null;type:varchar(36);index"`
+	TokenHash  string     `json:"-" gorm:"unique;not null;type:varchar(64);index"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"default:null"`
+	ReplacedBy *string    `json:"replaced_by,omitempty" gorm:"type:varchar(36);default:null"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedToken is a deny-listed access token, kept until the token expires
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primary_key;not null;type:varchar(36);index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:now()"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// LoginAttempt counts the consecutive failed logins of a key, an email or an ip address
+type LoginAttempt struct {
+	Key         string     `json:"key" gorm:"primary_key;not null;type:varchar(150);index"`
+	Failures    int        `json:"failures" gorm:"not null;default:0"`
+	LockedUntil *time.Time `json:"locked_until,omitempty" gorm:"default:null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null;default:now();index"`
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// IsLocked reports whether logins of the key are refused at now
+func (la LoginAttempt) IsLocked(now time.Time) bool {
+	return la.LockedUntil != nil && la.LockedUntil.After(now)
+}
+
+const (
+	LoginEventLocked = "locked"
+)
+
+// LoginAudit records the security events of logins, such as a key being locked out
+type LoginAudit struct {
+	ID          string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Key         string     `json:"key" gorm:"not null;type:varchar(150);index"`
+	Event       string     `json:"event" gorm:"not null;type:varchar(20);index"`
+	IP          string     `json:"ip" gorm:"type:varchar(45)"`
+	Failures    int        `json:"failures"`
+	LockedUntil *time.Time `json:"locked_until,omitempty" gorm:"default:null"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null;default:now()"`
+}
+
+func (LoginAudit) TableName() string {
+	return "login_audits"
+}
+
+type CustomerTokenPurpose string
+
+const (
+	CustomerTokenVerifyEmail   CustomerTokenPurpose = "verify_email"
+	CustomerTokenResetPassword CustomerTokenPurpose = "reset_password"
+)
+
+func (p CustomerTokenPurpose) String() string {
+	return string(p)
+}
+
+// CustomerToken is a single-use token mailed to a customer, only its signature is stored
+type CustomerToken struct {
+	ID         string               `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	CustomerID string               `json:"customer_id" gorm:"not null;type:varchar(36);index"`
+	Purpose    CustomerTokenPurpose `json:"purpose" gorm:"not null;type:varchar(20);index"`
+	TokenHash  string               `json:"-" gorm:"unique;not null;type:varchar(64);index"`
+	ExpiresAt  time.Time            `json:"expires_at" gorm:"not null"`
+	UsedAt     *time.Time           `json:"used_at,omitempty" gorm:"default:null"`
+	CreatedAt  time.Time            `json:"created_at" gorm:"not null;default:now()"`
+}
+
+func (CustomerToken) TableName() string {
+	return "customer_tokens"
 }
@@ -1,19 +1,23 @@
 package models
 
-import "time"
+import (
+	"mvp-shop-backend/pkg/money"
+	"time"
+)
 
 type Cart struct {
-	ID         string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
-	CustomerID string     `json:"customer_id" gorm:"not null;type:varchar(36);index"`
-	ProductID  string     `json:"product_id" gorm:"not null;type:varchar(36);index"`
-	Qty        float64    `json:"qty" gorm:"index"`
-	Price      float64    `json:"price" gorm:"index"`
-	Amount     float64    `json:"amount" gorm:"index"`
-	Status     Status     `json:"status" gorm:"not null;type:varchar(10);index"`
-	CreatedAt  time.Time  `json:"created_at" gorm:"not null;default:now()"`
-	CreatedBy  string     `json:"created_by" gorm:"not null;type:varchar(150)"`
-	UpdatedAt  *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
-	UpdatedBy  *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	ID         string      `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	CustomerID string      `json:"customer_id" gorm:"not null;type:varchar(36);index;uniqueIndex:uni_carts_customer_id_product_id,where:status <> 'deleted' AND status <> 'checkout' AND variant_id IS NULL;uniqueIndex:uni_carts_customer_id_variant_id,where:status <> 'deleted' AND status <> 'checkout'"`
+	ProductID  string      `json:"product_id" gorm:"not null;type:varchar(36);index;uniqueIndex:uni_carts_customer_id_product_id,where:status <> 'deleted' AND status <> 'checkout' AND variant_id IS NULL"`
+	VariantID  *string     `json:"variant_id,omitempty" gorm:"type:varchar(36);index;uniqueIndex:uni_carts_customer_id_variant_id,where:status <> 'deleted' AND status <> 'checkout'"`
+	Qty        float64     `json:"qty" gorm:"index"`
+	Price      money.Money `json:"price" gorm:"type:numeric(19,4);index"`
+	Amount     money.Money `json:"amount" gorm:"type:numeric(19,4);index"`
+	Status     Status      `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt  time.Time   `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy  string      `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt  *time.Time  `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy  *string     `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
 }
 
 func (Cart) TableName() string {
@@ -23,37 +27,51 @@ func (Cart) TableName() string {
 type CartRegister struct {
 	CustomerID string  `json:"customer_id"`
 	ProductID  string  `json:"product_id" binding:"required"`
+	VariantID  string  `json:"variant_id"`
 	Qty        float64 `json:"qty"`
-	Price      float64 `json:"price"`
-	Amount     float64 `json:"amount"`
 	Status     Status  `json:"status"`
 }
 
+// CartUpdate Price and Amount are set from the product, they are not read from the request
 type CartUpdate struct {
-	ID         string  `json:"id"`
-	CustomerID string  `json:"customer_id"`
-	ProductID  string  `json:"product_id"`
-	Qty        float64 `json:"qty"`
-	Price      float64 `json:"price"`
-	Amount     float64 `json:"amount"`
-	Status     Status  `json:"status"`
-	UpdatedBy  string  `json:"updated_by"`
+	ID         string      `json:"id"`
+	CustomerID string      `json:"customer_id"`
+	ProductID  string      `json:"product_id"`
+	Qty        float64     `json:"qty"`
+	Price      money.Money `json:"-"`
+	Amount     money.Money `json:"-"`
+	Status     Status      `json:"status"`
+	UpdatedBy  string      `json:"updated_by"`
 }
 
+// ProductCartView Price is the price of the product, VariantPrice the price of the variant when it replaces it
 type ProductCartView struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	Qty       float64    `json:"qty"`
-	Price     float64    `json:"price"`
-	Amount    float64    `json:"amount"`
-	Status    Status     `json:"status"`
-	CreatedAt time.Time  `json:"created_at"`
-	CreatedBy string     `json:"created_by"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
-	UpdatedBy *string    `json:"updated_by,omitempty"`
+	ID             string         `json:"id"`
+	ProductID      string         `json:"product_id"`
+	VariantID      *string        `json:"variant_id,omitempty"`
+	SKU            *string        `json:"sku,omitempty"`
+	Options        VariantOptions `json:"options,omitempty"`
+	CategoryID     string         `json:"category_id"`
+	Name           string         `json:"name"`
+	Qty            float64        `json:"qty"`
+	Price          money.Money    `json:"price"`
+	VariantPrice   *money.Money   `json:"-"`
+	Amount         money.Money    `json:"amount"`
+	DiscountAmount money.Money    `json:"discount_amount"`
+	Status         Status         `json:"status"`
+	CreatedAt      time.Time      `json:"created_at"`
+	CreatedBy      string         `json:"created_by"`
+	UpdatedAt      *time.Time     `json:"updated_at,omitempty"`
+	UpdatedBy      *string        `json:"updated_by,omitempty"`
+	LineTax
 }
 
+// CartView is the open cart of a customer, TotalAmount is the grand total of Totals. CouponError tells why the
+// applied coupon does not discount the cart.
 type CartView struct {
 	Products    []ProductCartView `json:"products"`
-	TotalAmount float64           `json:"total_amount"`
+	CouponCode  *string           `json:"coupon_code,omitempty"`
+	CouponError string            `json:"coupon_error,omitempty"`
+	TotalAmount money.Money       `json:"total_amount"`
+	Totals
 }
@@ -6,6 +6,7 @@ const (
 	StatusActive   Status = "active"
 	StatusInactive Status = "inactive"
 	StatusDeleted  Status = "deleted"
+	StatusCheckout Status = "checkout"
 )
 
 func (s Status) String() string {
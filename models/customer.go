@@ -6,16 +6,42 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleCustomer Role = "customer"
+)
+
+func (r Role) String() string {
+	return string(r)
+}
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleAdmin, RoleCustomer:
+		return true
+	}
+	return false
+}
+
 type Customer struct {
 	ID        string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
 	Email     string     `json:"email" gorm:"unique;not null;type:varchar(100);index"`
 	Name      string     `json:"name" gorm:"not null;type:varchar(250);index"`
 	Password  string     `json:"password" gorm:"not null;type:varchar(150);index"`
+	Role      Role       `json:"role" gorm:"not null;type:varchar(10);index;default:customer"`
 	Status    Status     `json:"status" gorm:"not null;type:varchar(10);index"`
 	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
 	CreatedBy string     `json:"created_by" gorm:"not null;type:varchar(150)"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
 	UpdatedBy *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty" gorm:"default:null"`
+	FailedLoginAttempts int        `json:"failed_login_attempts" gorm:"not null;default:0"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty" gorm:"default:null"`
+	LastFailedLoginAt   *time.Time `json:"last_failed_login_at,omitempty" gorm:"default:null"`
+	TokenVersion        int        `json:"-" gorm:"not null;default:0"`
 }
 
 func (Customer) TableName() string {
@@ -33,7 +59,15 @@ type CustomerClaims struct {
 	ID     string `json:"id"`
 	Email  string `json:"email"`
 	Name   string `json:"name"`
+	Role   Role   `json:"role"`
 	Status Status `json:"status"`
+	// TokenVersion is the token version of the customer when the token was issued, a password change bumps it
+	TokenVersion int `json:"token_version"`
+}
+
+// IsAdmin reports whether the token belongs to an admin
+func (c *CustomerClaims) IsAdmin() bool {
+	return c.Role == RoleAdmin
 }
 
 type ListCustomer struct {
@@ -47,6 +81,18 @@ type ListCustomer struct {
 type CustomerUpdate struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
+	Role      Role   `json:"role"`
 	Status    Status `json:"status"`
 	UpdatedBy string `json:"updated_by"`
 }
+
+// CustomerProfileUpdate is the part of the profile a customer can change, empty fields are kept
+type CustomerProfileUpdate struct {
+	Name  string `json:"name" binding:"omitempty,min=3"`
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+type CustomerPasswordChange struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
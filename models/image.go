@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ProductImage is an image of a product kept in the blob store under Key, with its thumbnail under ThumbnailKey.
+// Images are listed by Position, the first one is the cover of the product.
+type ProductImage struct {
+	ID           string    `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	ProductID    string    `json:"product_id" gorm:"not null;type:varchar(36);index"`
+	Position     int       `json:"position" gorm:"not null"`
+	Key          string    `json:"key" gorm:"not null;type:varchar(250)"`
+	ThumbnailKey string    `json:"thumbnail_key" gorm:"not null;type:varchar(250)"`
+	ContentType  string    `json:"content_type" gorm:"not null;type:varchar(50)"`
+	Size         int64     `json:"size" gorm:"not null"`
+	Width        int       `json:"width" gorm:"not null"`
+	Height       int       `json:"height" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy    string    `json:"created_by" gorm:"not null;type:varchar(150)"`
+}
+
+func (ProductImage) TableName() string {
+	return "product_images"
+}
+
+type ProductImageView struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Position     int    `json:"position"`
+}
+
+// ProductImageOrder lists every image of a product in its new order
+type ProductImageOrder struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1,dive,required"`
+}
@@ -1,34 +1,94 @@
 package models
 
-import "time"
+import (
+	"mvp-shop-backend/pkg/money"
+	"time"
 
+	"gorm.io/gorm"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusPaid      OrderStatus = "paid"
+	OrderStatusShipped   OrderStatus = "shipped"
+	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCancelled OrderStatus = "cancelled"
+)
+
+func (s OrderStatus) String() string {
+	return string(s)
+}
+
+// orderStatusTransitions lists the statuses an order can be moved to from each status
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending: {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:    {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped: {OrderStatusDelivered},
+}
+
+// CanTransitionTo reports whether an order in status s can be moved to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, v := range orderStatusTransitions[s] {
+		if v == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Order is priced in Currency, Amount is the grand total of Totals. ShippingAddress is empty for orders placed
+// without an address.
 type Order struct {
-	Invoice    string     `json:"invoice" gorm:"primary_key;not null;type:varchar(100);index"`
-	CustomerID string     `json:"customer_id" gorm:"not null;type:varchar(36);index"`
-	Amount     float64    `json:"amount" gorm:"index"`
-	Payment    bool       `json:"payment" gorm:"not null;index;default:false"`
-	Status     Status     `json:"status" gorm:"not null;type:varchar(10);index"`
-	CreatedAt  time.Time  `json:"created_at" gorm:"not null;default:now()"`
-	CreatedBy  string     `json:"created_by" gorm:"not null;type:varchar(150)"`
-	UpdatedAt  *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
-	UpdatedBy  *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	Invoice         string          `json:"invoice" gorm:"primary_key;not null;type:varchar(100);index"`
+	CustomerID      string          `json:"customer_id" gorm:"not null;type:varchar(36);index"`
+	Amount          money.Money     `json:"amount" gorm:"type:numeric(19,4);index"`
+	Currency        money.Currency  `json:"currency" gorm:"type:varchar(3)"`
+	Region          *string         `json:"region,omitempty" gorm:"type:varchar(10)"`
+	CouponCode      *string         `json:"coupon_code,omitempty" gorm:"type:varchar(50)"`
+	ShippingMethod  *string         `json:"shipping_method,omitempty" gorm:"type:varchar(20)"`
+	ExchangeRate    money.Rate      `json:"exchange_rate" gorm:"not null;type:numeric(19,8);default:1"`
+	Payment         bool            `json:"payment" gorm:"not null;index;default:false"`
+	OrderStatus     OrderStatus     `json:"order_status" gorm:"not null;type:varchar(10);index;default:pending"`
+	Status          Status          `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy       string          `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt       *time.Time      `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy       *string         `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	ShippingAddress ShippingAddress `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_"`
+	Totals
 }
 
 func (Order) TableName() string {
 	return "orders"
 }
 
+// AfterFind labels the amounts with the order currency, orders created before currencies were stored are in the default one
+func (o *Order) AfterFind(tx *gorm.DB) error {
+	if o.Currency == "" {
+		o.Currency = money.DefaultCurrency()
+	}
+	o.Amount = o.Amount.WithCurrency(o.Currency)
+	o.Totals = o.Totals.WithCurrency(o.Currency)
+	return nil
+}
+
 type OrderDetail struct {
-	Invoice   string     `json:"invoice" gorm:"not null;type:varchar(100);index"`
-	ProductID string     `json:"product_id" gorm:"not null;type:varchar(36);index"`
-	Qty       float64    `json:"qty" gorm:"index"`
-	Price     float64    `json:"price" gorm:"index"`
-	Amount    float64    `json:"amount" gorm:"index"`
-	Status    Status     `json:"status" gorm:"not null;type:varchar(10);index"`
-	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
-	CreatedBy string     `json:"created_by" gorm:"not null;type:varchar(150)"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
-	UpdatedBy *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	ID             string      `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Invoice        string      `json:"invoice" gorm:"not null;type:varchar(100);index"`
+	ProductID      string      `json:"product_id" gorm:"not null;type:varchar(36);index"`
+	VariantID      *string     `json:"variant_id,omitempty" gorm:"type:varchar(36);index"`
+	Qty            float64     `json:"qty" gorm:"index"`
+	Price          money.Money `json:"price" gorm:"type:numeric(19,4);index"`
+	Amount         money.Money `json:"amount" gorm:"type:numeric(19,4);index"`
+	DiscountAmount money.Money `json:"discount_amount" gorm:"not null;type:numeric(19,4);default:0"`
+	Status         Status      `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt      time.Time   `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy      string      `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt      *time.Time  `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy      *string     `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	LineTax
 }
 
 func (OrderDetail) TableName() string {
@@ -36,11 +96,74 @@ func (OrderDetail) TableName() string {
 }
 
 type OrderRegister struct {
-	Payment  bool           `json:"payment" binding:"required"`
-	Products []OrderProduct `json:"products" binding:"required"`
+	Products       []OrderProduct `json:"products" binding:"required"`
+	CouponCode     string         `json:"coupon_code"`
+	AddressID      string         `json:"address_id"`
+	ShippingMethod string         `json:"shipping_method"`
+}
+
+// OrderCheckout chooses the address and shipping method of a checkout, the default address is used without AddressID
+type OrderCheckout struct {
+	AddressID      string `json:"address_id"`
+	ShippingMethod string `json:"shipping_method"`
 }
 
 type OrderProduct struct {
 	ProductID string  `json:"product_id" binding:"required"`
-	Qty       float64 `json:"qty" binding:"required"`
+	VariantID string  `json:"variant_id"`
+	Qty       float64 `json:"qty" binding:"required,gt=0"`
+}
+
+type OrderStatusHistory struct {
+	ID         string      `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Invoice    string      `json:"invoice" gorm:"not null;type:varchar(100);index"`
+	FromStatus OrderStatus `json:"from_status" gorm:"type:varchar(10)"`
+	ToStatus   OrderStatus `json:"to_status" gorm:"not null;type:varchar(10)"`
+	CreatedAt  time.Time   `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy  string      `json:"created_by" gorm:"not null;type:varchar(150)"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
+
+type OrderDetailView struct {
+	Invoice        string         `json:"invoice"`
+	ProductID      string         `json:"product_id"`
+	VariantID      *string        `json:"variant_id,omitempty"`
+	SKU            *string        `json:"sku,omitempty"`
+	Options        VariantOptions `json:"options,omitempty"`
+	Name           string         `json:"name"`
+	Qty            float64        `json:"qty"`
+	Price          money.Money    `json:"price"`
+	Amount         money.Money    `json:"amount"`
+	DiscountAmount money.Money    `json:"discount_amount"`
+	Status         Status         `json:"status"`
+	CreatedAt      time.Time      `json:"created_at"`
+	CreatedBy      string         `json:"created_by"`
+	UpdatedAt      *time.Time     `json:"updated_at,omitempty"`
+	UpdatedBy      *string        `json:"updated_by,omitempty"`
+	LineTax
+}
+
+type OrderView struct {
+	Order
+	Details []OrderDetailView    `json:"details"`
+	History []OrderStatusHistory `json:"history"`
+}
+
+type ListOrder struct {
+	Page      int     `json:"page"`
+	Limit     int     `json:"limit"`
+	Total     int     `json:"total"`
+	TotalPage int     `json:"total_page"`
+	Orders    []Order `json:"orders"`
+}
+
+type InsufficientStock struct {
+	ProductID string  `json:"product_id"`
+	VariantID string  `json:"variant_id,omitempty"`
+	Name      string  `json:"name"`
+	Qty       float64 `json:"qty"`
+	Stock     float64 `json:"stock"`
 }
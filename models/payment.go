@@ -0,0 +1,57 @@
+package models
+
+import (
+	"mvp-shop-backend/pkg/money"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusPending   PaymentStatus = "pending"
+	PaymentStatusSucceeded PaymentStatus = "succeeded"
+	PaymentStatusFailed    PaymentStatus = "failed"
+	PaymentStatusRefunded  PaymentStatus = "refunded"
+	// PaymentStatusRefundPending is a payment of a cancelled order waiting for the provider to refund it
+	PaymentStatusRefundPending PaymentStatus = "refund_pending"
+)
+
+func (s PaymentStatus) String() string {
+	return string(s)
+}
+
+type Payment struct {
+	ID            string         `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Invoice       string         `json:"invoice" gorm:"not null;type:varchar(100);index"`
+	CustomerID    string         `json:"customer_id" gorm:"not null;type:varchar(36);index"`
+	Provider      string         `json:"provider" gorm:"not null;type:varchar(50)"`
+	IntentID      string         `json:"intent_id" gorm:"unique;not null;type:varchar(100);index"`
+	Amount        money.Money    `json:"amount" gorm:"type:numeric(19,4);index"`
+	Currency      money.Currency `json:"currency" gorm:"type:varchar(3)"`
+	PaymentStatus PaymentStatus  `json:"payment_status" gorm:"not null;type:varchar(20);index;default:pending"`
+	Status        Status         `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy     string         `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt     *time.Time     `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy     *string        `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (Payment) TableName() string {
+	return "payments"
+}
+
+// AfterFind labels the amount with the payment currency, payments created before currencies were stored are in the default one
+func (p *Payment) AfterFind(tx *gorm.DB) error {
+	if p.Currency == "" {
+		p.Currency = money.DefaultCurrency()
+	}
+	p.Amount = p.Amount.WithCurrency(p.Currency)
+	return nil
+}
+
+type PaymentIntentView struct {
+	Payment
+	ClientSecret string `json:"client_secret,omitempty"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"mvp-shop-backend/pkg/money"
+	"time"
+)
+
+// ExchangeRate is the amount of Currency one unit of the default currency buys, a currency can only be sold in
+// when it has a rate
+type ExchangeRate struct {
+	Currency  money.Currency `json:"currency" gorm:"primary_key;not null;type:varchar(3);index"`
+	Rate      money.Rate     `json:"rate" gorm:"not null;type:numeric(19,8)"`
+	CreatedAt time.Time      `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy string         `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt *time.Time     `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy *string        `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+type ExchangeRateUpdate struct {
+	Rate money.Rate `json:"rate" binding:"required"`
+}
+
+type ListExchangeRate struct {
+	Base  money.Currency `json:"base"`
+	Rates []ExchangeRate `json:"rates"`
+}
+
+// ProductPrice is the price of a product in a currency other than the default one, it replaces the converted price
+type ProductPrice struct {
+	ProductID string         `json:"product_id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Currency  money.Currency `json:"currency" gorm:"primary_key;not null;type:varchar(3);index"`
+	Price     money.Money    `json:"price" gorm:"not null;type:numeric(19,4)"`
+	CreatedAt time.Time      `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy string         `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt *time.Time     `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy *string        `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (ProductPrice) TableName() string {
+	return "product_prices"
+}
+
+type ProductPriceUpdate struct {
+	Price money.Money `json:"price" binding:"required"`
+}
+
+// PriceList prices products in Currency, with their price in Currency when they have one and otherwise with their
+// default currency price converted at Rate
+type PriceList struct {
+	Currency money.Currency
+	Rate     money.Rate
+	Prices   map[string]money.Money
+}
+
+// Price returns the price of productID, base is its price in the default currency
+func (pl PriceList) Price(productID string, base money.Money) (money.Money, error) {
+	if price, ok := pl.Prices[productID]; ok {
+		return price, nil
+	}
+	return pl.Rate.Convert(base, pl.Currency)
+}
+
+// VariantPrice returns the price of a variant of productID, override is the price of the variant in the default
+// currency and base the price of the product
+func (pl PriceList) VariantPrice(productID string, base money.Money, override *money.Money) (money.Money, error) {
+	if override == nil {
+		return pl.Price(productID, base)
+	}
+	return pl.Rate.Convert(override.WithCurrency(money.DefaultCurrency()), pl.Currency)
+}
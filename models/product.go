@@ -1,44 +1,81 @@
 package models
 
-import "time"
+import (
+	"mvp-shop-backend/pkg/money"
+	"time"
+)
 
+// Product Weight is in kg, it sets the fee of the weight based shipping method. Length, Width and Height are in cm.
+// Description is markdown, Attributes are checked against the attribute schema of the category. SearchVector is set
+// by a trigger from the name, category and description, gorm neither reads nor writes it.
 type Product struct {
-	ID         string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
-	Name       string     `json:"name" gorm:"not null;type:varchar(250);index"`
-	Price      float64    `json:"price" gorm:"index"`
-	Stock      float64    `json:"stock" gorm:"index"`
-	CategoryID string     `json:"category_id" gorm:"not null;type:varchar(36);index"`
-	Status     Status     `json:"status" gorm:"not null;type:varchar(10);index"`
-	CreatedAt  time.Time  `json:"created_at" gorm:"not null;default:now()"`
-	CreatedBy  string     `json:"created_by" gorm:"not null;type:varchar(150)"`
-	UpdatedAt  *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
-	UpdatedBy  *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	ID           string            `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Name         string            `json:"name" gorm:"not null;type:varchar(250);index;index:idx_products_name_trgm,type:gin,expression:name gin_trgm_ops"`
+	Description  string            `json:"description" gorm:"not null;type:text"`
+	SKU          *string           `json:"sku" gorm:"type:varchar(100);uniqueIndex:uni_products_sku,where:status <> 'deleted'"`
+	Slug         string            `json:"slug" gorm:"not null;type:varchar(250);uniqueIndex:uni_products_slug,where:status <> 'deleted'"`
+	Price        money.Money       `json:"price" gorm:"type:numeric(19,4);index"`
+	Stock        float64           `json:"stock" gorm:"index"`
+	Weight       float64           `json:"weight" gorm:"not null;default:0"`
+	Length       float64           `json:"length" gorm:"not null;default:0"`
+	Width        float64           `json:"width" gorm:"not null;default:0"`
+	Height       float64           `json:"height" gorm:"not null;default:0"`
+	Attributes   ProductAttributes `json:"attributes" gorm:"not null;type:jsonb;default:'{}'"`
+	CategoryID   string            `json:"category_id" gorm:"not null;type:varchar(36);index"`
+	SearchVector string            `json:"-" gorm:"<-:false;->:false;type:tsvector;index:idx_products_search_vector,type:gin"`
+	Status       Status            `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt    time.Time         `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy    string            `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt    *time.Time        `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy    *string           `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
 }
 
 func (Product) TableName() string {
 	return "products"
 }
 
+// ProductRegister Slug is made from the name when it is empty
 type ProductRegister struct {
-	Name       string  `json:"name" binding:"required,min=3"`
-	Price      float64 `json:"price" binding:"required"`
-	Stock      float64 `json:"stock" binding:"required"`
-	CategoryID string  `json:"category_id" binding:"required"`
-	Status     Status  `json:"status"`
+	Name        string            `json:"name" binding:"required,min=3"`
+	Description string            `json:"description"`
+	SKU         string            `json:"sku" binding:"max=100"`
+	Slug        string            `json:"slug" binding:"max=250"`
+	Price       money.Money       `json:"price" binding:"required"`
+	Stock       float64           `json:"stock" binding:"required"`
+	Weight      float64           `json:"weight" binding:"gte=0"`
+	Length      float64           `json:"length" binding:"gte=0"`
+	Width       float64           `json:"width" binding:"gte=0"`
+	Height      float64           `json:"height" binding:"gte=0"`
+	Attributes  ProductAttributes `json:"attributes"`
+	CategoryID  string            `json:"category_id" binding:"required"`
+	Status      Status            `json:"status"`
 }
 
+// ProductView Rank and Snippet are set by a search with q, the snippet is HTML with the matched words in <mark> tags
 type ProductView struct {
-	ID           string     `json:"id"`
-	Name         string     `json:"name"`
-	Price        float64    `json:"price"`
-	Stock        float64    `json:"stock"`
-	CategoryID   string     `json:"category_id"`
-	CategoryName string     `json:"category_name"`
-	Status       Status     `json:"status"`
-	CreatedAt    time.Time  `json:"created_at"`
-	CreatedBy    string     `json:"created_by"`
-	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
-	UpdatedBy    *string    `json:"updated_by,omitempty"`
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	SKU          *string            `json:"sku"`
+	Slug         string             `json:"slug"`
+	Price        money.Money        `json:"price"`
+	Stock        float64            `json:"stock"`
+	Weight       float64            `json:"weight"`
+	Length       float64            `json:"length"`
+	Width        float64            `json:"width"`
+	Height       float64            `json:"height"`
+	Attributes   ProductAttributes  `json:"attributes"`
+	CategoryID   string             `json:"category_id"`
+	CategoryName string             `json:"category_name"`
+	Breadcrumbs  []CategoryCrumb    `json:"breadcrumbs" gorm:"-"`
+	Images       []ProductImageView `json:"images" gorm:"-"`
+	Rank         float64            `json:"rank,omitempty"`
+	Snippet      string             `json:"snippet,omitempty"`
+	Status       Status             `json:"status"`
+	CreatedAt    time.Time          `json:"created_at"`
+	CreatedBy    string             `json:"created_by"`
+	UpdatedAt    *time.Time         `json:"updated_at,omitempty"`
+	UpdatedBy    *string            `json:"updated_by,omitempty"`
 }
 
 type ListProduct struct {
@@ -49,12 +86,21 @@ type ListProduct struct {
 	Products  []ProductView `json:"products"`
 }
 
+// ProductUpdate Slug is made from the name when it is empty
 type ProductUpdate struct {
-	ID         string  `json:"id"`
-	Name       string  `json:"name"`
-	Price      float64 `json:"price"`
-	Stock      float64 `json:"stock"`
-	CategoryID string  `json:"category_id"`
-	Status     Status  `json:"status"`
-	UpdatedBy  string  `json:"updated_by"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	SKU         string            `json:"sku" binding:"max=100"`
+	Slug        string            `json:"slug" binding:"max=250"`
+	Price       money.Money       `json:"price"`
+	Stock       float64           `json:"stock"`
+	Weight      float64           `json:"weight" binding:"gte=0"`
+	Length      float64           `json:"length" binding:"gte=0"`
+	Width       float64           `json:"width" binding:"gte=0"`
+	Height      float64           `json:"height" binding:"gte=0"`
+	Attributes  ProductAttributes `json:"attributes"`
+	CategoryID  string            `json:"category_id"`
+	Status      Status            `json:"status"`
+	UpdatedBy   string            `json:"updated_by"`
 }
@@ -2,14 +2,18 @@ package models
 
 import "time"
 
+// ProductCategory ParentID is the category it is a child of, root categories have none. AttributeSchema lists the
+// attributes the products of the category may have.
 type ProductCategory struct {
-	ID        string     `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
-	Name      string     `json:"name" gorm:"not null;type:varchar(250);index"`
-	Status    Status     `json:"status" gorm:"not null;type:varchar(10);index"`
-	CreatedAt time.Time  `json:"created_at" gorm:"not null;default:now()"`
-	CreatedBy string     `json:"created_by" gorm:"not null;type:varchar(150)"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty" gorm:"default:null"`
-	UpdatedBy *string    `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+	ID              string          `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	ParentID        *string         `json:"parent_id" gorm:"type:varchar(36);index"`
+	Name            string          `json:"name" gorm:"not null;type:varchar(250);index"`
+	AttributeSchema AttributeSchema `json:"attribute_schema" gorm:"not null;type:jsonb;default:'[]'"`
+	Status          Status          `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy       string          `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt       *time.Time      `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy       *string         `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
 }
 
 func (ProductCategory) TableName() string {
@@ -17,8 +21,10 @@ func (ProductCategory) TableName() string {
 }
 
 type ProductCategoryRegister struct {
-	Name   string `json:"name" binding:"required,min=3"`
-	Status Status `json:"status"`
+	ParentID        string          `json:"parent_id"`
+	Name            string          `json:"name" binding:"required,min=3"`
+	AttributeSchema AttributeSchema `json:"attribute_schema"`
+	Status          Status          `json:"status"`
 }
 
 type ListProductCategory struct {
@@ -30,8 +36,55 @@ type ListProductCategory struct {
 }
 
 type ProductCategoryUpdate struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Status    Status `json:"status"`
-	UpdatedBy string `json:"updated_by"`
+	ID              string          `json:"id"`
+	ParentID        *string         `json:"parent_id"`
+	Name            string          `json:"name"`
+	AttributeSchema AttributeSchema `json:"attribute_schema"`
+	Status          Status          `json:"status"`
+	UpdatedBy       string          `json:"updated_by"`
+}
+
+// ProductCategoryNode is a category of the category tree with its children
+type ProductCategoryNode struct {
+	ID       string                `json:"id"`
+	Name     string                `json:"name"`
+	Children []ProductCategoryNode `json:"children"`
+}
+
+// CategoryCrumb is a category of the path from the root category to the category of a product
+type CategoryCrumb struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryTree nests categories under their parent, categories whose parent is not listed are roots. Siblings keep
+// the order of categories.
+func CategoryTree(categories []ProductCategory) []ProductCategoryNode {
+	listed := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		listed[category.ID] = true
+	}
+	children := make(map[string][]ProductCategory)
+	var roots []ProductCategory
+	for _, category := range categories {
+		if category.ParentID != nil && listed[*category.ParentID] {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	var nodes func(categories []ProductCategory) []ProductCategoryNode
+	nodes = func(categories []ProductCategory) []ProductCategoryNode {
+		result := make([]ProductCategoryNode, len(categories))
+		for i, category := range categories {
+			result[i] = ProductCategoryNode{
+				ID:       category.ID,
+				Name:     category.Name,
+				Children: nodes(children[category.ID]),
+			}
+		}
+		return result
+	}
+	return nodes(roots)
 }
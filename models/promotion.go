@@ -0,0 +1,265 @@
+package models
+
+import (
+	"math"
+	"mvp-shop-backend/pkg/money"
+	"time"
+)
+
+type PromotionType string
+
+const (
+	// PromotionTypePercentage takes Percent off the lines in scope
+	PromotionTypePercentage PromotionType = "percentage"
+	// PromotionTypeFixed takes Amount off the lines in scope, split between them by amount
+	PromotionTypeFixed PromotionType = "fixed"
+	// PromotionTypeBuyXGetY gives GetQty units of a product in scope for every BuyQty units bought
+	PromotionTypeBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+func (t PromotionType) String() string {
+	return string(t)
+}
+
+func (t PromotionType) IsValid() bool {
+	switch t {
+	case PromotionTypePercentage, PromotionTypeFixed, PromotionTypeBuyXGetY:
+		return true
+	}
+	return false
+}
+
+// Promotion is a discount customers get with its Code. Amount and MinSpend are in the default currency, they are
+// converted at the exchange rate of the cart or order. A promotion of a category or product only discounts its lines.
+type Promotion struct {
+	ID                    string        `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Code                  string        `json:"code" gorm:"not null;type:varchar(50);uniqueIndex:uni_promotions_code,where:status <> 'deleted'"`
+	Name                  string        `json:"name" gorm:"not null;type:varchar(100)"`
+	Type                  PromotionType `json:"type" gorm:"not null;type:varchar(20)"`
+	Percent               money.Percent `json:"percent" gorm:"not null;type:numeric(7,4);default:0"`
+	Amount                money.Money   `json:"amount" gorm:"not null;type:numeric(19,4);default:0"`
+	BuyQty                int           `json:"buy_qty" gorm:"not null;default:0"`
+	GetQty                int           `json:"get_qty" gorm:"not null;default:0"`
+	MinSpend              money.Money   `json:"min_spend" gorm:"not null;type:numeric(19,4);default:0"`
+	CategoryID            *string       `json:"category_id" gorm:"type:varchar(36);index"`
+	ProductID             *string       `json:"product_id" gorm:"type:varchar(36);index"`
+	StartsAt              *time.Time    `json:"starts_at"`
+	EndsAt                *time.Time    `json:"ends_at"`
+	UsageLimit            *int          `json:"usage_limit"`
+	UsageLimitPerCustomer *int          `json:"usage_limit_per_customer"`
+	UsedCount             int           `json:"used_count" gorm:"not null;default:0"`
+	Status                Status        `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt             time.Time     `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy             string        `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt             *time.Time    `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy             *string       `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (Promotion) TableName() string {
+	return "promotions"
+}
+
+type PromotionRegister struct {
+	Code                  string        `json:"code" binding:"required,min=3,max=50"`
+	Name                  string        `json:"name" binding:"required,min=3,max=100"`
+	Type                  PromotionType `json:"type" binding:"required"`
+	Percent               money.Percent `json:"percent"`
+	Amount                money.Money   `json:"amount"`
+	BuyQty                int           `json:"buy_qty"`
+	GetQty                int           `json:"get_qty"`
+	MinSpend              money.Money   `json:"min_spend"`
+	CategoryID            *string       `json:"category_id"`
+	ProductID             *string       `json:"product_id"`
+	StartsAt              *time.Time    `json:"starts_at"`
+	EndsAt                *time.Time    `json:"ends_at"`
+	UsageLimit            *int          `json:"usage_limit"`
+	UsageLimitPerCustomer *int          `json:"usage_limit_per_customer"`
+}
+
+type ListPromotion struct {
+	Page       int         `json:"page"`
+	Limit      int         `json:"limit"`
+	Total      int         `json:"total"`
+	TotalPage  int         `json:"total_page"`
+	Promotions []Promotion `json:"promotions"`
+}
+
+// PromotionRedemption records the use of a promotion by an order, it is removed when the order is cancelled
+type PromotionRedemption struct {
+	ID             string         `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	PromotionID    string         `json:"promotion_id" gorm:"not null;type:varchar(36);index"`
+	Invoice        string         `json:"invoice" gorm:"not null;type:varchar(100);uniqueIndex:uni_promotion_redemptions_invoice"`
+	CustomerID     string         `json:"customer_id" gorm:"not null;type:varchar(36);index"`
+	DiscountAmount money.Money    `json:"discount_amount" gorm:"not null;type:numeric(19,4)"`
+	Currency       money.Currency `json:"currency" gorm:"not null;type:varchar(3)"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy      string         `json:"created_by" gorm:"not null;type:varchar(150)"`
+}
+
+func (PromotionRedemption) TableName() string {
+	return "promotion_redemptions"
+}
+
+// CartCoupon is the promotion a customer applied to the cart, it is redeemed at checkout
+type CartCoupon struct {
+	CustomerID  string    `json:"customer_id" gorm:"primary_key;not null;type:varchar(36);index"`
+	PromotionID string    `json:"promotion_id" gorm:"not null;type:varchar(36);index"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy   string    `json:"created_by" gorm:"not null;type:varchar(150)"`
+}
+
+func (CartCoupon) TableName() string {
+	return "cart_coupons"
+}
+
+type CouponRegister struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// PromotionError tells a customer why a promotion cannot be used
+type PromotionError struct {
+	Reason string
+}
+
+func (e *PromotionError) Error() string {
+	return e.Reason
+}
+
+// PromotionLine is a cart or order line a promotion may discount
+type PromotionLine struct {
+	ProductID  string
+	CategoryID string
+	Qty        float64
+	Price      money.Money
+	Amount     money.Money
+}
+
+// Check returns a *PromotionError when p cannot be used at now by a customer who already used it customerUses times
+func (p Promotion) Check(now time.Time, customerUses int64) error {
+	switch {
+	case p.Status != StatusActive:
+		return &PromotionError{Reason: "Coupon is not active"}
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return &PromotionError{Reason: "Coupon is not valid yet"}
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return &PromotionError{Reason: "Coupon has expired"}
+	case p.UsageLimit != nil && p.UsedCount >= *p.UsageLimit:
+		return &PromotionError{Reason: "Coupon usage limit reached"}
+	case p.UsageLimitPerCustomer != nil && customerUses >= int64(*p.UsageLimitPerCustomer):
+		return &PromotionError{Reason: "Coupon usage limit per customer reached"}
+	}
+	return nil
+}
+
+func (p Promotion) applies(line PromotionLine) bool {
+	if p.ProductID != nil && *p.ProductID != line.ProductID {
+		return false
+	}
+	if p.CategoryID != nil && *p.CategoryID != line.CategoryID {
+		return false
+	}
+	return true
+}
+
+// Discounts returns the discount of every line, rounded to the currency minor unit. rate converts Amount and
+// MinSpend to the currency of the lines. A *PromotionError is returned when p discounts nothing.
+func (p Promotion) Discounts(lines []PromotionLine, rate money.Rate) ([]money.Money, error) {
+	notApplicable := &PromotionError{Reason: "Coupon does not apply to the cart"}
+	if len(lines) == 0 {
+		return nil, notApplicable
+	}
+	currency := lines[0].Amount.Currency()
+
+	discounts := make([]money.Money, len(lines))
+	total := money.Zero(currency)
+	var inScope []int
+	for i, line := range lines {
+		discounts[i] = money.Zero(currency)
+		var err error
+		if total, err = total.Add(line.Amount); err != nil {
+			return nil, err
+		}
+		if p.applies(line) {
+			inScope = append(inScope, i)
+		}
+	}
+
+	if p.MinSpend.IsPositive() {
+		minSpend, err := rate.Convert(p.MinSpend.WithCurrency(money.DefaultCurrency()), currency)
+		if err != nil {
+			return nil, err
+		}
+		cmp, err := total.Cmp(minSpend)
+		if err != nil {
+			return nil, err
+		}
+		if cmp < 0 {
+			return nil, &PromotionError{Reason: "Coupon needs a minimum spend of " + minSpend.String()}
+		}
+	}
+	if len(inScope) == 0 {
+		return nil, notApplicable
+	}
+
+	var err error
+	switch p.Type {
+	case PromotionTypePercentage:
+		for _, i := range inScope {
+			if discounts[i], err = p.Percent.Of(lines[i].Amount); err != nil {
+				return nil, err
+			}
+		}
+	case PromotionTypeFixed:
+		err = p.splitAmount(lines, inScope, discounts, rate)
+	case PromotionTypeBuyXGetY:
+		for _, i := range inScope {
+			free := math.Floor(lines[i].Qty/float64(p.BuyQty+p.GetQty)) * float64(p.GetQty)
+			if discounts[i], err = lines[i].Price.MulQty(free); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, discount := range discounts {
+		if discount.IsPositive() {
+			return discounts, nil
+		}
+	}
+	return nil, notApplicable
+}
+
+// splitAmount splits Amount between the lines in scope by amount, it takes at most the amount of these lines
+func (p Promotion) splitAmount(lines []PromotionLine, inScope []int, discounts []money.Money, rate money.Rate) error {
+	currency := lines[0].Amount.Currency()
+	amount, err := rate.Convert(p.Amount.WithCurrency(money.DefaultCurrency()), currency)
+	if err != nil {
+		return err
+	}
+
+	weights := make([]money.Money, len(inScope))
+	scopeTotal := money.Zero(currency)
+	for j, i := range inScope {
+		weights[j] = lines[i].Amount
+		if scopeTotal, err = scopeTotal.Add(lines[i].Amount); err != nil {
+			return err
+		}
+	}
+	if !scopeTotal.IsPositive() {
+		return nil
+	}
+	if cmp, _ := amount.Cmp(scopeTotal); cmp > 0 {
+		amount = scopeTotal
+	}
+
+	parts, err := amount.Split(weights)
+	if err != nil {
+		return err
+	}
+	for j, i := range inScope {
+		discounts[i] = parts[j]
+	}
+	return nil
+}
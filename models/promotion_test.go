@@ -0,0 +1,167 @@
+package models
+
+import (
+	"errors"
+	"mvp-shop-backend/pkg/money"
+	"testing"
+	"time"
+)
+
+func promotionLine(t *testing.T, productID string, categoryID string, price string, qty float64) PromotionLine {
+	t.Helper()
+
+	p := money.MustParse(price, money.DefaultCurrency())
+	amount, err := p.MulQty(qty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return PromotionLine{ProductID: productID, CategoryID: categoryID, Qty: qty, Price: p, Amount: amount}
+}
+
+func TestPromotionDiscounts(t *testing.T) {
+	currency := money.DefaultCurrency()
+	category := "category-1"
+	product := "product-2"
+
+	tests := []struct {
+		name      string
+		promotion Promotion
+		lines     []PromotionLine
+		want      []string
+		wantErr   bool
+	}{
+		{
+			name:      "percentage",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: money.MustParsePercent("10")},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1), promotionLine(t, "product-2", "category-2", "25.00", 2)},
+			want:      []string{"10.00", "5.00"},
+		},
+		{
+			name:      "percentage of a category",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: money.MustParsePercent("10"), CategoryID: &category},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1), promotionLine(t, "product-2", "category-2", "50.00", 1)},
+			want:      []string{"10.00", "0.00"},
+		},
+		{
+			name:      "fixed split by amount",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: money.MustParse("30.00", currency)},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1), promotionLine(t, "product-2", category, "50.00", 1)},
+			want:      []string{"20.00", "10.00"},
+		},
+		{
+			name:      "fixed split rest on the last line",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: money.MustParse("10.00", currency)},
+			lines: []PromotionLine{
+				promotionLine(t, "product-1", category, "10.00", 1),
+				promotionLine(t, "product-2", category, "10.00", 1),
+				promotionLine(t, "product-3", category, "10.00", 1),
+			},
+			want: []string{"3.33", "3.33", "3.34"},
+		},
+		{
+			name:      "fixed capped at the lines in scope",
+			promotion: Promotion{Type: PromotionTypeFixed, Amount: money.MustParse("500.00", currency), ProductID: &product},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1), promotionLine(t, "product-2", category, "40.00", 1)},
+			want:      []string{"0.00", "40.00"},
+		},
+		{
+			name:      "buy 2 get 1",
+			promotion: Promotion{Type: PromotionTypeBuyXGetY, BuyQty: 2, GetQty: 1},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "10.00", 7)},
+			want:      []string{"20.00"},
+		},
+		{
+			name:      "buy 2 get 1 without enough qty",
+			promotion: Promotion{Type: PromotionTypeBuyXGetY, BuyQty: 2, GetQty: 1},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "10.00", 2)},
+			wantErr:   true,
+		},
+		{
+			name:      "minimum spend reached",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: money.MustParsePercent("10"), MinSpend: money.MustParse("150.00", currency)},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1), promotionLine(t, "product-2", category, "50.00", 1)},
+			want:      []string{"10.00", "5.00"},
+		},
+		{
+			name:      "minimum spend not reached",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: money.MustParsePercent("10"), MinSpend: money.MustParse("150.01", currency)},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1), promotionLine(t, "product-2", category, "50.00", 1)},
+			wantErr:   true,
+		},
+		{
+			name:      "no line in scope",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: money.MustParsePercent("10"), ProductID: &product},
+			lines:     []PromotionLine{promotionLine(t, "product-1", category, "100.00", 1)},
+			wantErr:   true,
+		},
+		{
+			name:      "no line",
+			promotion: Promotion{Type: PromotionTypePercentage, Percent: money.MustParsePercent("10")},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discounts, err := tt.promotion.Discounts(tt.lines, money.OneRate())
+			if tt.wantErr {
+				var promotionErr *PromotionError
+				if !errors.As(err, &promotionErr) {
+					t.Fatalf("Discounts() error = %v, want a PromotionError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Discounts(): %v", err)
+			}
+			if len(discounts) != len(tt.want) {
+				t.Fatalf("Discounts() returned %d discounts, want %d", len(discounts), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := discounts[i].Decimal(); got != want {
+					t.Errorf("discount %d = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPromotionCheck(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+	limit := 2
+
+	tests := []struct {
+		name         string
+		promotion    Promotion
+		customerUses int64
+		wantErr      bool
+	}{
+		{name: "active", promotion: Promotion{Status: StatusActive}},
+		{name: "inactive", promotion: Promotion{Status: StatusInactive}, wantErr: true},
+		{name: "inside the window", promotion: Promotion{Status: StatusActive, StartsAt: &before, EndsAt: &after}},
+		{name: "not started", promotion: Promotion{Status: StatusActive, StartsAt: &after}, wantErr: true},
+		{name: "starts now", promotion: Promotion{Status: StatusActive, StartsAt: &now}},
+		{name: "ended", promotion: Promotion{Status: StatusActive, EndsAt: &before}, wantErr: true},
+		{name: "ends now", promotion: Promotion{Status: StatusActive, EndsAt: &now}, wantErr: true},
+		{name: "under the usage limit", promotion: Promotion{Status: StatusActive, UsageLimit: &limit, UsedCount: 1}},
+		{name: "usage limit reached", promotion: Promotion{Status: StatusActive, UsageLimit: &limit, UsedCount: 2}, wantErr: true},
+		{name: "under the customer limit", promotion: Promotion{Status: StatusActive, UsageLimitPerCustomer: &limit}, customerUses: 1},
+		{name: "customer limit reached", promotion: Promotion{Status: StatusActive, UsageLimitPerCustomer: &limit}, customerUses: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.promotion.Check(now, tt.customerUses)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			var promotionErr *PromotionError
+			if !errors.As(err, &promotionErr) {
+				t.Errorf("Check() = %v, want a PromotionError", err)
+			}
+		})
+	}
+}
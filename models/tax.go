@@ -0,0 +1,161 @@
+package models
+
+import (
+	"fmt"
+	"mvp-shop-backend/pkg/money"
+	"strings"
+	"time"
+)
+
+// ParseRegion reads a tax region like "ID" or "ID-JK", an ISO 3166 country or subdivision code in any case
+func ParseRegion(s string) (string, error) {
+	region := strings.ToUpper(strings.TrimSpace(s))
+	if len(region) < 2 || len(region) > 10 {
+		return "", fmt.Errorf("invalid region %q", s)
+	}
+	for _, r := range region {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' {
+			return "", fmt.Errorf("invalid region %q", s)
+		}
+	}
+	return region, nil
+}
+
+// TaxRule taxes the products of CategoryID sold in Region at Rate, a rule without region applies to every region and
+// a rule without category to every category
+type TaxRule struct {
+	ID         string        `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	Name       string        `json:"name" gorm:"not null;type:varchar(100)"`
+	Region     *string       `json:"region" gorm:"type:varchar(10);index"`
+	CategoryID *string       `json:"category_id" gorm:"type:varchar(36);index"`
+	Rate       money.Percent `json:"rate" gorm:"not null;type:numeric(7,4)"`
+	Inclusive  bool          `json:"inclusive" gorm:"not null;default:false"`
+	CreatedAt  time.Time     `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy  string        `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt  *time.Time    `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy  *string       `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (TaxRule) TableName() string {
+	return "tax_rules"
+}
+
+type TaxRuleRegister struct {
+	Name       string        `json:"name" binding:"required,min=2,max=100"`
+	Region     *string       `json:"region"`
+	CategoryID *string       `json:"category_id"`
+	Rate       money.Percent `json:"rate"`
+	Inclusive  bool          `json:"inclusive"`
+}
+
+// matches returns how closely r matches a product of categoryID, 0 when it does not apply to it. A rule of the
+// category wins over a rule of the region, which wins over a rule of every region and category.
+func (r TaxRule) matches(region string, categoryID string) int {
+	score := 1
+	if r.Region != nil {
+		if *r.Region != region {
+			return 0
+		}
+		score++
+	}
+	if r.CategoryID != nil {
+		if *r.CategoryID != categoryID {
+			return 0
+		}
+		score += 2
+	}
+	return score
+}
+
+// LineTax is the tax of an order or cart line, an inclusive line amount already contains its tax
+type LineTax struct {
+	TaxRate      money.Percent `json:"tax_rate" gorm:"not null;type:numeric(7,4);default:0"`
+	TaxInclusive bool          `json:"tax_inclusive" gorm:"not null;default:false"`
+	TaxAmount    money.Money   `json:"tax_amount" gorm:"not null;type:numeric(19,4);default:0"`
+}
+
+// TaxTable holds the tax rules applying to Region
+type TaxTable struct {
+	Region string
+	Rules  []TaxRule
+}
+
+// LineTax returns the tax of amount, a line of a product of categoryID, with the closest matching rule. Lines no
+// rule applies to are not taxed.
+func (t TaxTable) LineTax(categoryID string, amount money.Money) (LineTax, error) {
+	var rule *TaxRule
+	best := 0
+	for i, r := range t.Rules {
+		if score := r.matches(t.Region, categoryID); score > best {
+			rule, best = &t.Rules[i], score
+		}
+	}
+	if rule == nil {
+		return LineTax{TaxAmount: money.Zero(amount.Currency())}, nil
+	}
+
+	tax, err := rule.Rate.Tax(amount, rule.Inclusive)
+	if err != nil {
+		return LineTax{}, err
+	}
+	return LineTax{TaxRate: rule.Rate, TaxInclusive: rule.Inclusive, TaxAmount: tax}, nil
+}
+
+// Totals is the amount breakdown of an order or a cart. Subtotal is the sum of the lines without their tax, the
+// grand total is Subtotal + TaxAmount - DiscountAmount + ShippingAmount. The tax of a line is computed on its amount
+// minus its discount.
+type Totals struct {
+	Subtotal       money.Money `json:"subtotal" gorm:"not null;type:numeric(19,4);default:0"`
+	TaxAmount      money.Money `json:"tax_amount" gorm:"not null;type:numeric(19,4);default:0"`
+	DiscountAmount money.Money `json:"discount_amount" gorm:"not null;type:numeric(19,4);default:0"`
+	ShippingAmount money.Money `json:"shipping_amount" gorm:"not null;type:numeric(19,4);default:0"`
+}
+
+func NewTotals(currency money.Currency) Totals {
+	return Totals{
+		Subtotal:       money.Zero(currency),
+		TaxAmount:      money.Zero(currency),
+		DiscountAmount: money.Zero(currency),
+		ShippingAmount: money.Zero(currency),
+	}
+}
+
+// AddLine adds a line amount, its discount and its tax
+func (t *Totals) AddLine(amount money.Money, discount money.Money, tax LineTax) (err error) {
+	net := amount
+	if tax.TaxInclusive {
+		if net, err = amount.Sub(tax.TaxAmount); err != nil {
+			return err
+		}
+	}
+	if t.Subtotal, err = t.Subtotal.Add(net); err != nil {
+		return err
+	}
+	if t.DiscountAmount, err = t.DiscountAmount.Add(discount); err != nil {
+		return err
+	}
+	t.TaxAmount, err = t.TaxAmount.Add(tax.TaxAmount)
+	return err
+}
+
+// GrandTotal returns the amount to pay
+func (t Totals) GrandTotal() (money.Money, error) {
+	total, err := t.Subtotal.Add(t.TaxAmount)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if total, err = total.Sub(t.DiscountAmount); err != nil {
+		return money.Money{}, err
+	}
+	return total.Add(t.ShippingAmount)
+}
+
+// WithCurrency returns the totals labeled with currency
+func (t Totals) WithCurrency(currency money.Currency) Totals {
+	return Totals{
+		Subtotal:       t.Subtotal.WithCurrency(currency),
+		TaxAmount:      t.TaxAmount.WithCurrency(currency),
+		DiscountAmount: t.DiscountAmount.WithCurrency(currency),
+		ShippingAmount: t.ShippingAmount.WithCurrency(currency),
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"mvp-shop-backend/pkg/money"
+	"sort"
+	"time"
+)
+
+// VariantOptions are the option values of a variant by option name, like {"Size": "M", "Colour": "Red"}
+type VariantOptions map[string]string
+
+// Names returns the sorted option names
+func (o VariantOptions) Names() []string {
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (o VariantOptions) Value() (driver.Value, error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (o *VariantOptions) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case []byte:
+		return json.Unmarshal(v, o)
+	default:
+		return fmt.Errorf("cannot scan %T into VariantOptions", src)
+	}
+}
+
+// ProductVariant is a variant of a product like a size and colour, with its own SKU and stock. Price replaces the price
+// of the product when it is set, it is in the default currency. Products with variants are sold by variant only.
+type ProductVariant struct {
+	ID        string         `json:"id" gorm:"primary_key;not null;type:varchar(36);index"`
+	ProductID string         `json:"product_id" gorm:"not null;type:varchar(36);index;uniqueIndex:uni_product_variants_product_id_options,where:status <> 'deleted'"`
+	SKU       string         `json:"sku" gorm:"not null;type:varchar(100);uniqueIndex:uni_product_variants_sku,where:status <> 'deleted'"`
+	Options   VariantOptions `json:"options" gorm:"not null;type:jsonb;uniqueIndex:uni_product_variants_product_id_options,where:status <> 'deleted'"`
+	Price     *money.Money   `json:"price" gorm:"type:numeric(19,4)"`
+	Stock     float64        `json:"stock" gorm:"not null;default:0"`
+	Status    Status         `json:"status" gorm:"not null;type:varchar(10);index"`
+	CreatedAt time.Time      `json:"created_at" gorm:"not null;default:now()"`
+	CreatedBy string         `json:"created_by" gorm:"not null;type:varchar(150)"`
+	UpdatedAt *time.Time     `json:"updated_at,omitempty" gorm:"default:null"`
+	UpdatedBy *string        `json:"updated_by,omitempty" gorm:"type:varchar(150);default:null"`
+}
+
+func (ProductVariant) TableName() string {
+	return "product_variants"
+}
+
+type ProductVariantRegister struct {
+	SKU     string         `json:"sku" binding:"required,max=100"`
+	Options VariantOptions `json:"options" binding:"required,min=1"`
+	Price   *money.Money   `json:"price"`
+	Stock   float64        `json:"stock" binding:"gte=0"`
+}
+
+// ProductVariantView is a variant priced in the currency of the request, Price is the price of the product when the
+// variant has none
+type ProductVariantView struct {
+	ID      string         `json:"id"`
+	SKU     string         `json:"sku"`
+	Options VariantOptions `json:"options"`
+	Price   money.Money    `json:"price"`
+	Stock   float64        `json:"stock"`
+}
+
+// ProductOption is an option of a product like Size, with the values of its variants in the order they were added
+type ProductOption struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// ProductDetailView is a product with its variant matrix
+type ProductDetailView struct {
+	ProductView
+	Options  []ProductOption      `json:"options"`
+	Variants []ProductVariantView `json:"variants"`
+}
+
+// VariantMatrix returns the options of variants by name
+func VariantMatrix(variants []ProductVariant) []ProductOption {
+	values := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, variant := range variants {
+		for name, value := range variant.Options {
+			if key := name + "\x00" + value; !seen[key] {
+				seen[key] = true
+				values[name] = append(values[name], value)
+			}
+		}
+	}
+
+	options := make([]ProductOption, 0, len(values))
+	for name, v := range values {
+		options = append(options, ProductOption{Name: name, Values: v})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Name < options[j].Name })
+	return options
+}
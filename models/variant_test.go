@@ -0,0 +1,119 @@
+package models
+
+import (
+	"mvp-shop-backend/pkg/money"
+	"reflect"
+	"testing"
+)
+
+func TestPriceListVariantPrice(t *testing.T) {
+	base := money.MustParse("10.00", money.DefaultCurrency())
+	override := money.MustParse("12.00", money.DefaultCurrency())
+	rate, err := money.ParseRate("0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		priceList PriceList
+		override  *money.Money
+		want      money.Money
+	}{
+		{
+			name:      "product price",
+			priceList: PriceList{Currency: money.DefaultCurrency(), Rate: money.OneRate()},
+			want:      base,
+		},
+		{
+			name:      "variant price",
+			priceList: PriceList{Currency: money.DefaultCurrency(), Rate: money.OneRate()},
+			override:  &override,
+			want:      override,
+		},
+		{
+			name:      "converted product price",
+			priceList: PriceList{Currency: "USD", Rate: rate},
+			want:      money.MustParse("5.00", "USD"),
+		},
+		{
+			name:      "converted variant price",
+			priceList: PriceList{Currency: "USD", Rate: rate},
+			override:  &override,
+			want:      money.MustParse("6.00", "USD"),
+		},
+		{
+			name:      "product price in the currency",
+			priceList: PriceList{Currency: "USD", Rate: rate, Prices: map[string]money.Money{"product-1": money.MustParse("4.00", "USD")}},
+			want:      money.MustParse("4.00", "USD"),
+		},
+		{
+			// the price of a variant is only kept in the default currency, it replaces the price of the product
+			name:      "variant price over the product price in the currency",
+			priceList: PriceList{Currency: "USD", Rate: rate, Prices: map[string]money.Money{"product-1": money.MustParse("4.00", "USD")}},
+			override:  &override,
+			want:      money.MustParse("6.00", "USD"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.priceList.VariantPrice("product-1", base, tt.override)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("VariantPrice() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (PriceList{Currency: "USD"}).VariantPrice("product-1", base, &override); err == nil {
+		t.Error("VariantPrice() converted without exchange rate")
+	}
+}
+
+func TestVariantMatrix(t *testing.T) {
+	variants := []ProductVariant{
+		{Options: VariantOptions{"Size": "M", "Colour": "Red"}},
+		{Options: VariantOptions{"Size": "L", "Colour": "Red"}},
+		{Options: VariantOptions{"Size": "M", "Colour": "Blue"}},
+		{Options: VariantOptions{"Size": "S"}},
+	}
+
+	want := []ProductOption{
+		{Name: "Colour", Values: []string{"Red", "Blue"}},
+		{Name: "Size", Values: []string{"M", "L", "S"}},
+	}
+	if got := VariantMatrix(variants); !reflect.DeepEqual(got, want) {
+		t.Errorf("VariantMatrix() = %v, want %v", got, want)
+	}
+	if got := VariantMatrix(nil); len(got) != 0 {
+		t.Errorf("VariantMatrix(nil) = %v, want no option", got)
+	}
+}
+
+func TestVariantOptionsScan(t *testing.T) {
+	options := VariantOptions{"Size": "M", "Colour": "Red"}
+	value, err := options.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range []interface{}{value, []byte(value.(string))} {
+		var scanned VariantOptions
+		if err := scanned.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(scanned, options) {
+			t.Errorf("Scan(%T) = %v, want %v", src, scanned, options)
+		}
+	}
+
+	var scanned VariantOptions
+	if err := scanned.Scan(nil); err != nil || scanned != nil {
+		t.Errorf("Scan(nil) = %v, %v, want nil options", scanned, err)
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Error("Scan() accepted an int")
+	}
+}
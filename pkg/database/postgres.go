@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"mvp-shop-backend/models"
 	"os"
 
 	"gorm.io/driver/postgres"
@@ -23,21 +22,13 @@ func InitGorm(ctx context.Context) (db *gorm.DB, err error) {
 		),
 		&gorm.Config{
 			Logger: logger.Default.LogMode(logger.Info),
+			// constraint violations are returned as gorm.ErrDuplicatedKey, gorm.ErrForeignKeyViolated...
+			TranslateError: true,
 		},
 	)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	//TODO add other db/model migrate
-	db.AutoMigrate(
-		&models.Customer{},
-		&models.ProductCategory{},
-		&models.Product{},
-		&models.Cart{},
-		&models.Order{},
-		&models.OrderDetail{},
-	)
-
 	return db, nil
 }
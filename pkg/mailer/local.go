@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StdoutMailerName = "stdout"
+	FileMailerName   = "file"
+)
+
+// StdoutMailer writes the emails to w instead of sending them, for local development
+type StdoutMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewStdoutMailer(w io.Writer) *StdoutMailer {
+	return &StdoutMailer{
+		w: w,
+	}
+}
+
+func (sm *StdoutMailer) Send(ctx context.Context, msg Message) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	_, err := io.WriteString(sm.w, format(msg)+"\n")
+	return err
+}
+
+// FileMailer writes every email to its own .eml file in dir, for offline testing
+type FileMailer struct {
+	dir string
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if dir == "" {
+		dir = "mails"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating mail directory, %v", err)
+	}
+	return &FileMailer{
+		dir: dir,
+	}, nil
+}
+
+func (fm *FileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	return os.WriteFile(filepath.Join(fm.dir, name), []byte(format(msg)), 0o600)
+}
+
+func format(msg Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	b.WriteString("\r\n")
+	return b.String()
+}
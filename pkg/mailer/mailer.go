@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is implemented by every transport the shop can send emails through
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer returns the mailer configured by MAILER, the stdout mailer is used when it is empty
+func NewMailer() (Mailer, error) {
+	switch name := os.Getenv("MAILER"); name {
+	case "", StdoutMailerName:
+		return NewStdoutMailer(os.Stdout), nil
+	case FileMailerName:
+		return NewFileMailer(os.Getenv("MAILER_DIR"))
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", name)
+	}
+}
@@ -0,0 +1,186 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+var (
+	// ErrTooLarge is returned by Read when the image is larger than Options.MaxSize bytes or MaxPixels pixels
+	ErrTooLarge = errors.New("image too large")
+
+	// ErrUnsupportedType is returned by Read when the content is not a JPEG, PNG or GIF image
+	ErrUnsupportedType = errors.New("unsupported image type")
+
+	// ErrInvalidImage is returned when the content has an image signature but cannot be decoded
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// MaxPixels bounds the decoded size of an image, a small file may declare a huge canvas
+const MaxPixels = 40_000_000
+
+// Extensions are the file extensions of the accepted content types
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Options MaxSize is in bytes, ThumbnailSize is the longest side of the thumbnails in pixels
+type Options struct {
+	MaxSize       int64
+	ThumbnailSize int
+}
+
+// Image is an uploaded image, its ContentType is sniffed from Data, the name and header of the upload are ignored
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+func (img *Image) Extension() string {
+	return Extensions[img.ContentType]
+}
+
+// LoadOptions reads MEDIA_MAX_IMAGE_SIZE, 5 MiB when empty, and MEDIA_THUMBNAIL_SIZE, 256 pixels when empty
+func LoadOptions() (Options, error) {
+	options := Options{
+		MaxSize:       5 << 20,
+		ThumbnailSize: 256,
+	}
+	if s := os.Getenv("MEDIA_MAX_IMAGE_SIZE"); s != "" {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || size <= 0 {
+			return options, fmt.Errorf("invalid MEDIA_MAX_IMAGE_SIZE %q", s)
+		}
+		options.MaxSize = size
+	}
+	if s := os.Getenv("MEDIA_THUMBNAIL_SIZE"); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size <= 0 {
+			return options, fmt.Errorf("invalid MEDIA_THUMBNAIL_SIZE %q", s)
+		}
+		options.ThumbnailSize = size
+	}
+	return options, nil
+}
+
+// Read reads an image of at most maxSize bytes from r and checks its type and dimensions
+func Read(r io.Reader, maxSize int64) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading image, %v", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := Extensions[contentType]; !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	return &Image{
+		Data:        data,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, nil
+}
+
+// Thumbnail scales img down to fit in a size x size square, smaller images keep their dimensions. JPEG images give a
+// JPEG thumbnail, PNG and GIF images a PNG one to keep their transparency.
+func Thumbnail(img *Image, size int) (*Image, error) {
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	width, height := fit(img.Width, img.Height, size)
+	dst := scale(src, width, height)
+
+	var b bytes.Buffer
+	thumbnail := &Image{Width: width, Height: height}
+	if img.ContentType == "image/jpeg" {
+		thumbnail.ContentType = "image/jpeg"
+		err = jpeg.Encode(&b, dst, &jpeg.Options{Quality: 85})
+	} else {
+		thumbnail.ContentType = "image/png"
+		err = png.Encode(&b, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding thumbnail, %v", err)
+	}
+	thumbnail.Data = b.Bytes()
+	return thumbnail, nil
+}
+
+// fit returns the dimensions of a width x height image scaled down to fit in a size x size square
+func fit(width int, height int, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// scale resizes src to width x height averaging the source pixels covered by every destination pixel, colors are
+// averaged premultiplied so transparent pixels do not bleed into their neighbours
+func scale(src image.Image, width int, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcWidth/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
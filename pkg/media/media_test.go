@@ -0,0 +1,97 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	data := encodePNG(t, 40, 20)
+
+	img, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/png" || img.Extension() != ".png" || img.Width != 40 || img.Height != 20 {
+		t.Errorf("Read = %s %s %dx%d", img.ContentType, img.Extension(), img.Width, img.Height)
+	}
+
+	if _, err := Read(bytes.NewReader(data), int64(len(data))-1); err != ErrTooLarge {
+		t.Errorf("Read of a too large file error = %v, want ErrTooLarge", err)
+	}
+	if _, err := Read(strings.NewReader("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), 1<<10); err != ErrUnsupportedType {
+		t.Errorf("Read of an svg error = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := Read(bytes.NewReader(data[:20]), 1<<10); err != ErrInvalidImage {
+		t.Errorf("Read of a truncated png error = %v, want ErrInvalidImage", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	for _, tt := range []struct {
+		width, height, size int
+		wantW, wantH        int
+	}{
+		{400, 200, 100, 100, 50},
+		{200, 400, 100, 50, 100},
+		{1000, 1, 100, 100, 1},
+		{50, 20, 100, 50, 20},
+	} {
+		data := encodePNG(t, tt.width, tt.height)
+		img, err := Read(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		thumbnail, err := Thumbnail(img, tt.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := png.Decode(bytes.NewReader(thumbnail.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := decoded.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH || thumbnail.Width != tt.wantW || thumbnail.Height != tt.wantH {
+			t.Errorf("thumbnail of %dx%d in %d = %dx%d, want %dx%d", tt.width, tt.height, tt.size, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+		if r, g, _, a := decoded.At(0, 0).RGBA(); r>>8 != 255 || g != 0 || a>>8 != 255 {
+			t.Errorf("thumbnail pixel = %d %d %d, want red", r>>8, g>>8, a>>8)
+		}
+	}
+}
+
+func TestThumbnailJPEG(t *testing.T) {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewGray(image.Rect(0, 0, 300, 300)), nil); err != nil {
+		t.Fatal(err)
+	}
+	img, err := Read(&b, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, err := Thumbnail(img, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbnail.ContentType != "image/jpeg" || thumbnail.Width != 64 || thumbnail.Height != 64 {
+		t.Errorf("thumbnail = %s %dx%d", thumbnail.ContentType, thumbnail.Width, thumbnail.Height)
+	}
+}
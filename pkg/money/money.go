@@ -0,0 +1,482 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of decimals Money keeps, amounts are stored in numeric(19,4) columns
+const Scale = 4
+
+const unit = 10_000
+
+var (
+	// ErrCurrencyMismatch is returned when amounts of different currencies are added or compared
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+
+	// ErrOverflow is returned when an amount does not fit in Money
+	ErrOverflow = errors.New("amount out of range")
+)
+
+// Currency is an ISO 4217 currency code
+type Currency string
+
+// exponents lists the currencies which do not have 2 decimals
+var exponents = map[Currency]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KMF": 0, "KRW": 0, "KWD": 3,
+	"LYD": 3, "OMR": 3, "PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0,
+}
+
+// Exponent is the number of decimals of the currency minor unit, 2 for USD and 0 for JPY
+func (c Currency) Exponent() int {
+	if e, ok := exponents[c]; ok {
+		return e
+	}
+	return 2
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+// ParseCurrency accepts a three letters currency code in any case
+func ParseCurrency(s string) (Currency, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) != 3 {
+		return "", fmt.Errorf("invalid currency %q", s)
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency %q", s)
+		}
+	}
+	return Currency(s), nil
+}
+
+var defaultCurrency Currency = "IDR"
+
+// LoadCurrency reads CURRENCY, the currency of the prices and amounts stored by the shop.
+// PAYMENT_CURRENCY is read when CURRENCY is empty.
+func LoadCurrency() error {
+	v := os.Getenv("CURRENCY")
+	if v == "" {
+		v = os.Getenv("PAYMENT_CURRENCY")
+	}
+	if v == "" {
+		return nil
+	}
+
+	c, err := ParseCurrency(v)
+	if err != nil {
+		return err
+	}
+	defaultCurrency = c
+	return nil
+}
+
+// DefaultCurrency returns the currency loaded by LoadCurrency
+func DefaultCurrency() Currency {
+	return defaultCurrency
+}
+
+// Money is an exact amount of a currency, kept as an integer number of 1/10000 of the currency unit.
+// Amounts computed by Money are rounded to the currency minor unit, half away from zero.
+type Money struct {
+	units    int64
+	currency Currency
+}
+
+// Zero returns no money of currency c
+func Zero(c Currency) Money {
+	return Money{currency: c}
+}
+
+// FromMinor returns minor units of currency c, FromMinor(1234, "USD") is 12.34 USD
+func FromMinor(minor int64, c Currency) (Money, error) {
+	units, ok := mul64(minor, pow10(Scale-c.Exponent()))
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units, currency: c}, nil
+}
+
+// Parse reads a decimal amount like "-12.34", it cannot have more decimals than the currency minor unit
+func Parse(amount string, c Currency) (Money, error) {
+	units, decimals, err := parseUnits(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	if decimals > c.Exponent() {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimals for %s", amount, c.Exponent(), c)
+	}
+	return Money{units: units, currency: c}, nil
+}
+
+// MustParse is Parse panicking on error, for constants and tests
+func MustParse(amount string, c Currency) Money {
+	m, err := Parse(amount, c)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m Money) Currency() Currency {
+	return m.currency
+}
+
+// Minor returns the amount in minor units of its currency, rounded half away from zero
+func (m Money) Minor() int64 {
+	return m.Round().units / pow10(Scale-m.currency.Exponent())
+}
+
+func (m Money) IsZero() bool {
+	return m.units == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.units < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.units > 0
+}
+
+// WithCurrency returns the same amount in currency c, rounded to its minor unit
+func (m Money) WithCurrency(c Currency) Money {
+	m.currency = c
+	return m.Round()
+}
+
+// Add returns m + o. Money without currency takes the currency of the other amount.
+func (m Money) Add(o Money) (Money, error) {
+	c, err := m.sameCurrency(o)
+	if err != nil {
+		return Money{}, err
+	}
+	units, ok := add64(m.units, o.units)
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units, currency: c}, nil
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) (Money, error) {
+	if o.units == math.MinInt64 {
+		return Money{}, ErrOverflow
+	}
+	o.units = -o.units
+	return m.Add(o)
+}
+
+// Cmp returns -1, 0 or 1 when m is lower than, equal to or greater than o
+func (m Money) Cmp(o Money) (int, error) {
+	if _, err := m.sameCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.units < o.units:
+		return -1, nil
+	case m.units > o.units:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// MulQty returns m * qty rounded to the currency minor unit, qty is read as the shortest decimal printing it,
+// so 0.1 is exactly one tenth
+func (m Money) MulQty(qty float64) (Money, error) {
+	if math.IsNaN(qty) || math.IsInf(qty, 0) {
+		return Money{}, fmt.Errorf("invalid quantity %v", qty)
+	}
+	q, _ := new(big.Rat).SetString(strconv.FormatFloat(qty, 'f', -1, 64))
+	product := q.Mul(q, new(big.Rat).SetInt64(m.units))
+
+	units, ok := roundRat(product, pow10(Scale-m.currency.Exponent()))
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units, currency: m.currency}, nil
+}
+
+// Split divides m in parts proportional to weights, each part is rounded to the currency minor unit and the last
+// part takes the rounding rest so the parts add up to m
+func (m Money) Split(weights []Money) ([]Money, error) {
+	var total int64
+	for _, w := range weights {
+		var ok bool
+		if total, ok = add64(total, w.units); !ok {
+			return nil, ErrOverflow
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("cannot split %s between zero weights", m)
+	}
+
+	parts := make([]Money, len(weights))
+	rest := m
+	step := pow10(Scale - m.currency.Exponent())
+	for i, w := range weights[:len(weights)-1] {
+		r := new(big.Rat).SetFrac(big.NewInt(w.units), big.NewInt(total))
+		r.Mul(r, new(big.Rat).SetInt64(m.units))
+		units, ok := roundRat(r, step)
+		if !ok {
+			return nil, ErrOverflow
+		}
+		parts[i] = Money{units: units, currency: m.currency}
+		rest.units -= units
+	}
+	parts[len(parts)-1] = rest
+	return parts, nil
+}
+
+// Round rounds m to its currency minor unit, half away from zero
+func (m Money) Round() Money {
+	step := pow10(Scale - m.currency.Exponent())
+	rest := m.units % step
+	m.units -= rest
+	if 2*abs(rest) >= step {
+		if rest > 0 {
+			m.units += step
+		} else {
+			m.units -= step
+		}
+	}
+	return m
+}
+
+// Decimal formats the amount with the decimals of its currency, more when it is not rounded
+func (m Money) Decimal() string {
+	var sb strings.Builder
+	u := m.units
+	if u < 0 {
+		sb.WriteByte('-')
+	}
+	integer, fraction := u/unit, u%unit
+	sb.WriteString(strconv.FormatUint(uint64(abs(integer)), 10))
+
+	digits := fmt.Sprintf("%0*d", Scale, abs(fraction))
+	keep := m.currency.Exponent()
+	for i := Scale; i > keep; i-- {
+		if digits[i-1] != '0' {
+			keep = i
+			break
+		}
+	}
+	if keep > 0 {
+		sb.WriteByte('.')
+		sb.WriteString(digits[:keep])
+	}
+	return sb.String()
+}
+
+func (m Money) String() string {
+	if m.currency == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + string(m.currency)
+}
+
+type moneyJSON struct {
+	Amount   json.Number `json:"amount"`
+	Currency Currency    `json:"currency"`
+}
+
+// MarshalJSON writes {"amount":"12.34","currency":"USD"}, the amount is a string so clients do not read it as a float
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{m.Decimal(), m.currency})
+}
+
+// UnmarshalJSON reads {"amount":"12.34","currency":"USD"}, the amount may be a number, and a bare "12.34" or 12.34
+// in the default currency
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	var v moneyJSON
+	if len(b) > 0 && b[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+	} else {
+		if len(b) > 1 && b[0] == '"' {
+			b = b[1 : len(b)-1]
+		}
+		v.Amount = json.Number(b)
+	}
+
+	c := defaultCurrency
+	if v.Currency != "" {
+		var err error
+		if c, err = ParseCurrency(string(v.Currency)); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := Parse(string(v.Amount), c)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount unlabelled, rows keeping their own currency relabel it from that column in AfterFind
+func (m Money) Value() (driver.Value, error) {
+	u := m.units
+	sign := ""
+	if u < 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, abs(u/unit), Scale, abs(u%unit)), nil
+}
+
+// Scan reads a numeric column as an amount of the default currency until AfterFind relabels it
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*m = Zero(defaultCurrency)
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', Scale, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+
+	units, _, err := parseUnits(s)
+	if err != nil {
+		return err
+	}
+	*m = Money{units: units, currency: defaultCurrency}
+	return nil
+}
+
+func (m Money) sameCurrency(o Money) (Currency, error) {
+	switch {
+	case m.currency == o.currency, o.currency == "":
+		return m.currency, nil
+	case m.currency == "":
+		return o.currency, nil
+	}
+	return "", fmt.Errorf("%w, %s and %s", ErrCurrencyMismatch, m.currency, o.currency)
+}
+
+// parseUnits reads a decimal with at most Scale decimals and returns it in 1/10000 with its number of decimals
+func parseUnits(s string) (units int64, decimals int, err error) {
+	invalid := fmt.Errorf("invalid amount %q", s)
+
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(s, ".")
+	if integer == "" && fraction == "" {
+		return 0, 0, invalid
+	}
+	// trailing zeros are not decimals
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > Scale {
+		return 0, 0, fmt.Errorf("amount %q has more than %d decimals", s, Scale)
+	}
+	decimals = len(fraction)
+
+	digits := integer + fraction + strings.Repeat("0", Scale-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return 0, 0, invalid
+		}
+	}
+	n, err := strconv.ParseUint(digits, 10, 63)
+	if err != nil {
+		return 0, 0, ErrOverflow
+	}
+
+	units = int64(n)
+	if neg {
+		units = -units
+	}
+	return units, decimals, nil
+}
+
+// roundRat rounds r to a multiple of step, half away from zero
+func roundRat(r *big.Rat, step int64) (int64, bool) {
+	num := new(big.Int).Set(r.Num())
+	den := new(big.Int).Mul(r.Denom(), big.NewInt(step))
+
+	q, rest := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rest), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	q.Mul(q, big.NewInt(step))
+	if !q.IsInt64() {
+		return 0, false
+	}
+	return q.Int64(), true
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func add64(a, b int64) (int64, bool) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, false
+	}
+	return c, true
+}
+
+func mul64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a {
+		return 0, false
+	}
+	return c, true
+}
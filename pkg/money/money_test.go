@@ -0,0 +1,223 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseAndDecimal(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency Currency
+		want     string
+	}{
+		{"12.34", "USD", "12.34"},
+		{"12.3", "USD", "12.30"},
+		{"12", "USD", "12.00"},
+		{"-0.05", "USD", "-0.05"},
+		{".5", "EUR", "0.50"},
+		{"1500", "JPY", "1500"},
+		{"1.234", "KWD", "1.234"},
+		{"1.00", "JPY", "1"},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.amount, tt.currency)
+		if err != nil {
+			t.Fatalf("Parse(%q, %s): %v", tt.amount, tt.currency, err)
+		}
+		if got := m.Decimal(); got != tt.want {
+			t.Errorf("Parse(%q, %s).Decimal() = %s, want %s", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+
+	for _, amount := range []string{"", "-", "1.2.3", "1e3", "abc", "1.23456", "99999999999999999999"} {
+		if _, err := Parse(amount, "KWD"); err == nil {
+			t.Errorf("Parse(%q) accepted an invalid amount", amount)
+		}
+	}
+	if _, err := Parse("1.5", "JPY"); err == nil {
+		t.Error("Parse accepted decimals JPY does not have")
+	}
+}
+
+func TestMulQtyRounding(t *testing.T) {
+	tests := []struct {
+		price string
+		qty   float64
+		want  string
+	}{
+		{"0.10", 3, "0.30"},
+		{"19.99", 0.5, "10.00"},   // 9.995 rounds up
+		{"-19.99", 0.5, "-10.00"}, // half away from zero
+		{"10.01", 0.5, "5.01"},    // 5.005
+		{"1.00", 0.333, "0.33"},
+		{"2.50", 0.1, "0.25"},
+		{"0.00", 12, "0.00"},
+	}
+	for _, tt := range tests {
+		m, err := MustParse(tt.price, "USD").MulQty(tt.qty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Decimal(); got != tt.want {
+			t.Errorf("%s * %v = %s, want %s", tt.price, tt.qty, got, tt.want)
+		}
+	}
+
+	m, err := MustParse("1001", "JPY").MulQty(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Decimal() != "501" {
+		t.Errorf("1001 JPY * 0.5 = %s, want 501", m.Decimal())
+	}
+
+	if _, err := FromMinor(1<<62, "JPY"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("FromMinor overflow: got %v", err)
+	}
+}
+
+func TestTotalOfManyLines(t *testing.T) {
+	// summing 10000 float64 lines of 0.1 * 0.7 does not give 700
+	price := MustParse("0.70", "USD")
+	total := Zero("USD")
+	for i := 0; i < 10000; i++ {
+		line, err := price.MulQty(0.1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total, err = total.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if total.Decimal() != "700.00" {
+		t.Errorf("total = %s, want 700.00", total.Decimal())
+	}
+
+	// every line is rounded before summing, 1/3 of 1.00 is 0.33 and three of them are 0.99
+	third := MustParse("1.00", "USD")
+	total = Money{}
+	for i := 0; i < 3; i++ {
+		line, _ := third.MulQty(1.0 / 3)
+		total, _ = total.Add(line)
+	}
+	if total.Decimal() != "0.99" || total.Currency() != "USD" {
+		t.Errorf("total = %s, want 0.99 USD", total)
+	}
+
+	lines := []string{"19.99", "0.01", "1234.56", "0.99", "100.00", "42.42"}
+	total = Zero("USD")
+	for i := 0; i < 1000; i++ {
+		for _, l := range lines {
+			total, _ = total.Add(MustParse(l, "USD"))
+		}
+	}
+	if total.Decimal() != "1397970.00" || total.Minor() != 139797000 {
+		t.Errorf("total = %s (%d minor), want 1397970.00", total.Decimal(), total.Minor())
+	}
+}
+
+func TestCurrencyMismatch(t *testing.T) {
+	if _, err := MustParse("1", "USD").Add(MustParse("1", "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("adding USD and EUR: got %v", err)
+	}
+	if _, err := MustParse("1", "USD").Sub(MustParse("1", "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("subtracting EUR from USD: got %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(MustParse("12.5", "USD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"amount":"12.50","currency":"USD"}` {
+		t.Errorf("json = %s", b)
+	}
+
+	for input, want := range map[string]string{
+		`{"amount":"12.50","currency":"usd"}`: "12.50 USD",
+		`{"amount":0.1,"currency":"EUR"}`:     "0.10 EUR",
+		`"7.25"`:                              "7.25 " + string(defaultCurrency),
+		`3`:                                   "3.00 " + string(defaultCurrency),
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(input), &m); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", input, err)
+		}
+		if m.String() != want {
+			t.Errorf("Unmarshal(%s) = %s, want %s", input, m, want)
+		}
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount":"1.001","currency":"USD"}`), &m); err == nil {
+		t.Error("Unmarshal accepted more decimals than USD has")
+	}
+}
+
+func TestValueScan(t *testing.T) {
+	v, err := MustParse("-1234.5", "USD").Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "-1234.5000" {
+		t.Errorf("Value() = %v, want -1234.5000", v)
+	}
+
+	tests := []struct {
+		src  interface{}
+		want string
+	}{
+		{"-1234.5000", "-1234.50"},
+		{[]byte("1"), "1.00"},
+		{int64(3), "3.00"},
+		{float64(0.1), "0.10"},
+		{nil, "0.00"},
+	}
+	for _, tt := range tests {
+		var m Money
+		if err := m.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%v): %v", tt.src, err)
+		}
+		if got := m.WithCurrency("USD").Decimal(); got != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	// scanned amounts are kept exact until they are rounded to a currency
+	var m Money
+	if err := m.Scan("0.1250"); err != nil {
+		t.Fatal(err)
+	}
+	if m.WithCurrency("KWD").Decimal() != "0.125" || m.WithCurrency("USD").Decimal() != "0.13" {
+		t.Errorf("Scan(0.1250) = %s in KWD and %s in USD", m.WithCurrency("KWD").Decimal(), m.WithCurrency("USD").Decimal())
+	}
+}
+
+func TestSplit(t *testing.T) {
+	weights := []Money{MustParse("10.00", "USD"), MustParse("10.00", "USD"), MustParse("10.00", "USD")}
+	parts, err := MustParse("10.00", "USD").Split(weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the last part takes the rounding rest so the parts add up to the split amount
+	for i, want := range []string{"3.33", "3.33", "3.34"} {
+		if parts[i].Decimal() != want || parts[i].Currency() != "USD" {
+			t.Errorf("part %d = %s, want %s USD", i, parts[i], want)
+		}
+	}
+
+	parts, err = MustParse("100", "JPY").Split([]Money{MustParse("1", "JPY"), MustParse("2", "JPY")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parts[0].Decimal() != "33" || parts[1].Decimal() != "67" {
+		t.Errorf("parts = %s %s, want 33 67", parts[0], parts[1])
+	}
+
+	if _, err := MustParse("1.00", "USD").Split([]Money{Zero("USD")}); err == nil {
+		t.Error("Split accepted zero weights")
+	}
+}
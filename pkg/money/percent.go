@@ -0,0 +1,120 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Percent is a non-negative percentage with Scale decimals, tax rates are stored in numeric(7,4) columns
+type Percent struct {
+	units int64
+}
+
+// ParsePercent reads a percentage like "11" or "12.5", it cannot be negative nor over 999.9999
+func ParsePercent(s string) (Percent, error) {
+	s = strings.TrimSpace(s)
+	units, _, err := parseUnits(s)
+	if err != nil || units < 0 || units >= 1000*unit {
+		return Percent{}, fmt.Errorf("invalid percentage %q", s)
+	}
+	return Percent{units: units}, nil
+}
+
+// MustParsePercent is ParsePercent panicking on error, for constants and tests
+func MustParsePercent(s string) Percent {
+	p, err := ParsePercent(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p Percent) IsZero() bool {
+	return p.units == 0
+}
+
+// Cmp returns -1, 0 or 1 when p is lower than, equal to or greater than o
+func (p Percent) Cmp(o Percent) int {
+	switch {
+	case p.units < o.units:
+		return -1
+	case p.units > o.units:
+		return 1
+	}
+	return 0
+}
+
+func (p Percent) String() string {
+	s := Money{units: p.units}.Decimal()
+	if strings.Contains(s, ".") {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Of returns p percent of m rounded to the currency minor unit, half away from zero
+func (p Percent) Of(m Money) (Money, error) {
+	return p.ratio(m, 100*unit)
+}
+
+// Tax returns the tax of m at p rounded to the currency minor unit, half away from zero. An inclusive m already
+// contains its tax, the tax is then m * p / (100 + p) instead of m * p / 100.
+func (p Percent) Tax(m Money, inclusive bool) (Money, error) {
+	den := int64(100 * unit)
+	if inclusive {
+		den += p.units
+	}
+	return p.ratio(m, den)
+}
+
+// ratio returns m * p.units / den rounded to the currency minor unit
+func (p Percent) ratio(m Money, den int64) (Money, error) {
+	r := new(big.Rat).SetFrac(big.NewInt(p.units), big.NewInt(den))
+	r.Mul(r, new(big.Rat).SetInt64(m.units))
+
+	units, ok := roundRat(r, pow10(Scale-m.currency.Exponent()))
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units, currency: m.currency}, nil
+}
+
+// MarshalJSON writes the percentage as a string so clients do not read it as a float
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(p.String())), nil
+}
+
+// UnmarshalJSON reads "12.5" or 12.5
+func (p *Percent) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 1 && b[0] == '"' {
+		b = b[1 : len(b)-1]
+	}
+
+	parsed, err := ParsePercent(string(b))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+func (p Percent) Value() (driver.Value, error) {
+	return Money{units: p.units}.Value()
+}
+
+func (p *Percent) Scan(src interface{}) error {
+	var m Money
+	if err := m.Scan(src); err != nil {
+		return err
+	}
+	*p = Percent{units: m.units}
+	return nil
+}
@@ -0,0 +1,74 @@
+package money
+
+import "testing"
+
+func TestPercentTax(t *testing.T) {
+	tests := []struct {
+		amount    string
+		currency  Currency
+		rate      string
+		inclusive bool
+		want      string
+	}{
+		{"100.00", "USD", "11", false, "11.00"},
+		{"111.00", "USD", "11", true, "11.00"},
+		{"19.99", "USD", "7.5", false, "1.50"},  // 1.49925
+		{"10.05", "USD", "10", false, "1.01"},   // 1.005 rounds up
+		{"-10.05", "USD", "10", false, "-1.01"}, // half away from zero
+		{"1000", "JPY", "8", true, "74"},        // 74.07
+		{"12.345", "KWD", "5", false, "0.617"},  // 0.61725
+		{"50.00", "USD", "0", false, "0.00"},
+	}
+	for _, tt := range tests {
+		p, err := ParsePercent(tt.rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tax, err := p.Tax(MustParse(tt.amount, tt.currency), tt.inclusive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tax.Decimal() != tt.want || tax.Currency() != tt.currency {
+			t.Errorf("tax of %s %s at %s%% (inclusive %v) = %s, want %s", tt.amount, tt.currency, tt.rate, tt.inclusive, tax, tt.want)
+		}
+	}
+
+	for _, rate := range []string{"", "-1", "1000", "abc", "1.23456"} {
+		if _, err := ParsePercent(rate); err == nil {
+			t.Errorf("ParsePercent(%q) accepted an invalid percentage", rate)
+		}
+	}
+}
+
+func TestPercentString(t *testing.T) {
+	for input, want := range map[string]string{"11": "11", "12.50": "12.5", "0": "0", "0.0725": "0.0725"} {
+		p, err := ParsePercent(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.String() != want {
+			t.Errorf("ParsePercent(%q) = %s, want %s", input, p, want)
+		}
+		if v, _ := p.Value(); v == nil {
+			t.Errorf("Value() of %s is nil", p)
+		}
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	p, err := ParsePercent("15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	off, err := p.Of(MustParse("19.99", "USD")) // 2.9985
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off.Decimal() != "3.00" || off.Currency() != "USD" {
+		t.Errorf("15%% of 19.99 USD = %s, want 3.00 USD", off)
+	}
+
+	if p.Cmp(MustParsePercent("100")) >= 0 || MustParsePercent("100").Cmp(p) <= 0 || p.Cmp(MustParsePercent("15.0")) != 0 {
+		t.Errorf("Cmp does not order 15 and 100")
+	}
+}
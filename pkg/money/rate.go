@@ -0,0 +1,123 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RateScale is the number of decimals of a Rate, rates are stored in numeric(19,8) columns
+const RateScale = 8
+
+// Rate is an exchange rate from the default currency, the amount of a currency one unit of the default currency buys
+type Rate struct {
+	r *big.Rat
+}
+
+// OneRate is the rate of the default currency to itself
+func OneRate() Rate {
+	return Rate{r: big.NewRat(1, 1)}
+}
+
+// ParseRate reads a positive decimal rate with at most RateScale decimals
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	_, fraction, _ := strings.Cut(s, ".")
+	if len(strings.TrimRight(fraction, "0")) > RateScale {
+		return Rate{}, fmt.Errorf("rate %q has more than %d decimals", s, RateScale)
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' {
+			return Rate{}, fmt.Errorf("invalid rate %q", s)
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", s)
+	}
+	return Rate{r: r}, nil
+}
+
+func (r Rate) IsZero() bool {
+	return r.r == nil || r.r.Sign() == 0
+}
+
+func (r Rate) String() string {
+	if r.r == nil {
+		return "0"
+	}
+	s := r.r.FloatString(RateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert returns m, an amount of the default currency, in currency to, rounded to its minor unit half away from zero
+func (r Rate) Convert(m Money, to Currency) (Money, error) {
+	if r.IsZero() {
+		return Money{}, fmt.Errorf("no exchange rate to %s", to)
+	}
+	product := new(big.Rat).Mul(r.r, new(big.Rat).SetInt64(m.units))
+
+	units, ok := roundRat(product, pow10(Scale-to.Exponent()))
+	if !ok {
+		return Money{}, ErrOverflow
+	}
+	return Money{units: units, currency: to}, nil
+}
+
+// MarshalJSON writes the rate as a string so clients do not read it as a float
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(r.String())), nil
+}
+
+// UnmarshalJSON reads "15500.5" or 15500.5
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 1 && b[0] == '"' {
+		b = b[1 : len(b)-1]
+	}
+
+	parsed, err := ParseRate(string(b))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*r = Rate{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', RateScale, 64)
+	default:
+		return fmt.Errorf("cannot scan %T into Rate", src)
+	}
+
+	parsed, err := ParseRate(s)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
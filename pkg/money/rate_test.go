@@ -0,0 +1,92 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	for input, want := range map[string]string{
+		"15500":        "15500",
+		"0.00006452":   "0.00006452",
+		"1.50000000":   "1.5",
+		"0.9200000000": "0.92",
+	} {
+		r, err := ParseRate(input)
+		if err != nil {
+			t.Fatalf("ParseRate(%q): %v", input, err)
+		}
+		if r.String() != want {
+			t.Errorf("ParseRate(%q) = %s, want %s", input, r, want)
+		}
+	}
+
+	for _, input := range []string{"", "0", "-1", "1e3", "abc", "0.000000001"} {
+		if _, err := ParseRate(input); err == nil {
+			t.Errorf("ParseRate(%q) accepted an invalid rate", input)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount string
+		from   Currency
+		rate   string
+		to     Currency
+		want   string
+	}{
+		{"10.00", "USD", "0.92", "EUR", "9.20"},
+		{"150000", "IDR", "0.00006452", "USD", "9.68"}, // 9.678
+		{"1.00", "USD", "149.5", "JPY", "150"},         // 149.5 rounds up
+		{"-1.00", "USD", "149.5", "JPY", "-150"},       // half away from zero
+		{"2.50", "USD", "0.3075", "KWD", "0.769"},      // 0.76875
+	}
+	for _, tt := range tests {
+		m, err := mustParseRate(t, tt.rate).Convert(MustParse(tt.amount, tt.from), tt.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Decimal() != tt.want || m.Currency() != tt.to {
+			t.Errorf("%s %s at %s = %s, want %s %s", tt.amount, tt.from, tt.rate, m, tt.want, tt.to)
+		}
+	}
+
+	if _, err := (Rate{}).Convert(MustParse("1", "USD"), "EUR"); err == nil {
+		t.Error("Convert without a rate succeeded")
+	}
+}
+
+func TestRateJSONAndScan(t *testing.T) {
+	b, err := json.Marshal(mustParseRate(t, "15500.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"15500.5"` {
+		t.Errorf("json = %s", b)
+	}
+
+	for _, input := range []string{`"0.92"`, `0.92`} {
+		var r Rate
+		if err := json.Unmarshal([]byte(input), &r); err != nil || r.String() != "0.92" {
+			t.Errorf("Unmarshal(%s) = %s, %v", input, r, err)
+		}
+	}
+
+	var r Rate
+	if err := r.Scan([]byte("0.00006452")); err != nil || r.String() != "0.00006452" {
+		t.Errorf("Scan = %s, %v", r, err)
+	}
+	if v, _ := r.Value(); v != "0.00006452" {
+		t.Errorf("Value() = %v", v)
+	}
+}
+
+func mustParseRate(t *testing.T, s string) Rate {
+	t.Helper()
+	r, err := ParseRate(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
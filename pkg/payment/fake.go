@@ -0,0 +1,117 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mvp-shop-backend/pkg/money"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const FakeProviderName = "fake"
+
+// WebhookFunc receives the signed webhook payloads sent by FakeProvider
+type WebhookFunc func(payload []byte, signature string)
+
+// FakeProvider is an in-process payment gateway for local development and offline testing.
+// Confirming or refunding an intent delivers a signed webhook to the function set with SetWebhook.
+type FakeProvider struct {
+	secret  []byte
+	mu      sync.Mutex
+	intents map[string]*Intent
+	webhook WebhookFunc
+}
+
+func NewFakeProvider(secret string) *FakeProvider {
+	return &FakeProvider{
+		secret:  []byte(secret),
+		intents: make(map[string]*Intent),
+	}
+}
+
+// SetWebhook sets the function receiving the webhooks, usually the payment service handler
+func (fp *FakeProvider) SetWebhook(webhook WebhookFunc) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.webhook = webhook
+}
+
+func (fp *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (fp *FakeProvider) CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error) {
+	intent := &Intent{
+		ID:           "fake_pi_" + uuid.New().String(),
+		Reference:    req.Reference,
+		Amount:       req.Amount,
+		Status:       IntentStatusPending,
+		ClientSecret: uuid.New().String(),
+	}
+
+	fp.mu.Lock()
+	fp.intents[intent.ID] = intent
+	fp.mu.Unlock()
+
+	res := *intent
+	return &res, nil
+}
+
+func (fp *FakeProvider) ConfirmIntent(ctx context.Context, intentID string) (*Intent, error) {
+	return fp.update(intentID, IntentStatusSucceeded, EventPaymentSucceeded)
+}
+
+func (fp *FakeProvider) Refund(ctx context.Context, intentID string, amount money.Money) (*Intent, error) {
+	return fp.update(intentID, IntentStatusRefunded, EventPaymentRefunded)
+}
+
+func (fp *FakeProvider) ParseWebhook(payload []byte, signature string) (*Event, error) {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, fp.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (fp *FakeProvider) update(intentID string, status IntentStatus, eventType EventType) (*Intent, error) {
+	fp.mu.Lock()
+	intent, ok := fp.intents[intentID]
+	if !ok {
+		fp.mu.Unlock()
+		return nil, ErrIntentNotFound
+	}
+	intent.Status = status
+	res := *intent
+	webhook := fp.webhook
+	fp.mu.Unlock()
+
+	if webhook != nil {
+		payload, err := json.Marshal(Event{
+			ID:       uuid.New().String(),
+			Type:     eventType,
+			IntentID: intentID,
+			Amount:   res.Amount,
+		})
+		if err != nil {
+			return nil, err
+		}
+		webhook(payload, hex.EncodeToString(fp.sign(payload)))
+	}
+
+	return &res, nil
+}
+
+func (fp *FakeProvider) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, fp.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mvp-shop-backend/pkg/money"
+	"os"
+)
+
+var (
+	// ErrIntentNotFound is returned when the provider does not know the intent id
+	ErrIntentNotFound = errors.New("payment intent not found")
+
+	// ErrInvalidSignature is returned by ParseWebhook when the payload signature cannot be verified
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+type IntentStatus string
+
+const (
+	IntentStatusPending   IntentStatus = "pending"
+	IntentStatusSucceeded IntentStatus = "succeeded"
+	IntentStatusFailed    IntentStatus = "failed"
+	IntentStatusRefunded  IntentStatus = "refunded"
+)
+
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+	EventPaymentRefunded  EventType = "payment.refunded"
+)
+
+type IntentRequest struct {
+	Reference string
+	Amount    money.Money
+}
+
+type Intent struct {
+	ID           string       `json:"id"`
+	Reference    string       `json:"reference"`
+	Amount       money.Money  `json:"amount"`
+	Status       IntentStatus `json:"status"`
+	ClientSecret string       `json:"client_secret,omitempty"`
+}
+
+// Event is a verified notification sent by the provider about an intent
+type Event struct {
+	ID       string      `json:"id"`
+	Type     EventType   `json:"type"`
+	IntentID string      `json:"intent_id"`
+	Amount   money.Money `json:"amount"`
+}
+
+// PaymentProvider is implemented by every payment gateway the shop can charge through
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, req IntentRequest) (*Intent, error)
+	ConfirmIntent(ctx context.Context, intentID string) (*Intent, error)
+	Refund(ctx context.Context, intentID string, amount money.Money) (*Intent, error)
+	ParseWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// NewProvider returns the provider configured by PAYMENT_PROVIDER. The provider has to be set explicitly and
+// PAYMENT_WEBHOOK_SECRET cannot be empty, the webhook is not authenticated otherwise.
+func NewProvider() (PaymentProvider, error) {
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	switch name := os.Getenv("PAYMENT_PROVIDER"); name {
+	case "":
+		return nil, errors.New("PAYMENT_PROVIDER is not set")
+	case FakeProviderName:
+		if secret == "" {
+			return nil, errors.New("PAYMENT_WEBHOOK_SECRET is not set")
+		}
+		return NewFakeProvider(secret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
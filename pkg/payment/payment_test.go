@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	for _, tt := range []struct {
+		provider, secret string
+		ok               bool
+	}{
+		{"", "secret", false},
+		{FakeProviderName, "", false},
+		{"unknown", "secret", false},
+		{FakeProviderName, "secret", true},
+	} {
+		t.Setenv("PAYMENT_PROVIDER", tt.provider)
+		t.Setenv("PAYMENT_WEBHOOK_SECRET", tt.secret)
+		_, err := NewProvider()
+		if (err == nil) != tt.ok {
+			t.Errorf("NewProvider with provider %q and secret %q error = %v", tt.provider, tt.secret, err)
+		}
+	}
+}
+
+func TestFakeProviderParseWebhook(t *testing.T) {
+	provider := NewFakeProvider("secret")
+	payload := []byte(`{"id":"1","type":"payment.succeeded","intent_id":"fake_pi_1","amount":{"amount":"10.00","currency":"USD"}}`)
+
+	event, err := provider.ParseWebhook(payload, hex.EncodeToString(provider.sign(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.IntentID != "fake_pi_1" || event.Amount.Currency() != "USD" || event.Amount.Minor() != 1000 {
+		t.Errorf("event = %+v", event)
+	}
+
+	forged := NewFakeProvider("")
+	if _, err := provider.ParseWebhook(payload, hex.EncodeToString(forged.sign(payload))); err != ErrInvalidSignature {
+		t.Errorf("ParseWebhook of a payload signed with another key error = %v, want ErrInvalidSignature", err)
+	}
+}
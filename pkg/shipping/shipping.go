@@ -0,0 +1,147 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/pkg/money"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrNotDeliverable is returned by Rate when a method does not deliver to the region of the request
+var ErrNotDeliverable = errors.New("shipping method does not deliver to the region")
+
+const (
+	FlatMethodName   = "flat"
+	WeightMethodName = "weight"
+	ZoneMethodName   = "zone"
+)
+
+// Request is a parcel to ship, Weight is in kg and Region is the ISO 3166 code of the address like "ID-JK"
+type Request struct {
+	Region string
+	Weight float64
+}
+
+// ShippingRateCalculator is implemented by every way the shop computes a shipping fee, fees are in the default currency
+type ShippingRateCalculator interface {
+	Rate(req Request) (money.Money, error)
+}
+
+// Methods are the shipping methods customers can choose, by name
+type Methods map[string]ShippingRateCalculator
+
+// Names returns the sorted method names
+func (m Methods) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadMethods returns the methods configured by SHIPPING_FLAT_FEE, SHIPPING_WEIGHT_FEE_PER_KG (with
+// SHIPPING_WEIGHT_BASE_FEE) and SHIPPING_ZONE_FEES, a method is offered when its variable is set. It must be called after
+// money.LoadCurrency.
+func LoadMethods() (Methods, error) {
+	methods := make(Methods)
+	currency := money.DefaultCurrency()
+
+	if v := os.Getenv("SHIPPING_FLAT_FEE"); v != "" {
+		fee, err := money.Parse(v, currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHIPPING_FLAT_FEE, %v", err)
+		}
+		methods[FlatMethodName] = FlatRate{Fee: fee}
+	}
+
+	if v := os.Getenv("SHIPPING_WEIGHT_FEE_PER_KG"); v != "" {
+		perKg, err := money.Parse(v, currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHIPPING_WEIGHT_FEE_PER_KG, %v", err)
+		}
+		base := money.Zero(currency)
+		if v := os.Getenv("SHIPPING_WEIGHT_BASE_FEE"); v != "" {
+			if base, err = money.Parse(v, currency); err != nil {
+				return nil, fmt.Errorf("invalid SHIPPING_WEIGHT_BASE_FEE, %v", err)
+			}
+		}
+		methods[WeightMethodName] = WeightRate{Base: base, PerKg: perKg}
+	}
+
+	if v := os.Getenv("SHIPPING_ZONE_FEES"); v != "" {
+		zone, err := ParseZoneRate(v, currency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHIPPING_ZONE_FEES, %v", err)
+		}
+		methods[ZoneMethodName] = zone
+	}
+
+	return methods, nil
+}
+
+// FlatRate charges Fee for every parcel
+type FlatRate struct {
+	Fee money.Money
+}
+
+func (r FlatRate) Rate(req Request) (money.Money, error) {
+	return r.Fee, nil
+}
+
+// WeightRate charges Base plus PerKg for every started kg
+type WeightRate struct {
+	Base  money.Money
+	PerKg money.Money
+}
+
+func (r WeightRate) Rate(req Request) (money.Money, error) {
+	kg := 0.0
+	if req.Weight > 0 {
+		kg = float64(int64(req.Weight))
+		if kg < req.Weight {
+			kg++
+		}
+	}
+	fee, err := r.PerKg.MulQty(kg)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return r.Base.Add(fee)
+}
+
+// ZoneRate charges the fee of the zone of the region, the zone of "ID-JK" is "ID-JK", then its country "ID", then
+// the "*" zone of every region
+type ZoneRate struct {
+	Fees map[string]money.Money
+}
+
+// ParseZoneRate reads zone fees like "ID-JK=10000,ID=20000,*=75000"
+func ParseZoneRate(s string, currency money.Currency) (ZoneRate, error) {
+	fees := make(map[string]money.Money)
+	for _, pair := range strings.Split(s, ",") {
+		zone, amount, ok := strings.Cut(pair, "=")
+		if !ok {
+			return ZoneRate{}, fmt.Errorf("zone fee %q is not zone=fee", pair)
+		}
+		zone = strings.ToUpper(strings.TrimSpace(zone))
+		fee, err := money.Parse(strings.TrimSpace(amount), currency)
+		if err != nil {
+			return ZoneRate{}, err
+		}
+		fees[zone] = fee
+	}
+	return ZoneRate{Fees: fees}, nil
+}
+
+func (r ZoneRate) Rate(req Request) (money.Money, error) {
+	country, _, _ := strings.Cut(req.Region, "-")
+	for _, zone := range []string{req.Region, country, "*"} {
+		if fee, ok := r.Fees[zone]; ok && zone != "" {
+			return fee, nil
+		}
+	}
+	return money.Money{}, ErrNotDeliverable
+}
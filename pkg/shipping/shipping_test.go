@@ -0,0 +1,52 @@
+package shipping
+
+import (
+	"mvp-shop-backend/pkg/money"
+	"testing"
+)
+
+func TestWeightRate(t *testing.T) {
+	rate := WeightRate{Base: money.MustParse("5.00", "USD"), PerKg: money.MustParse("2.50", "USD")}
+	// every started kg is charged
+	for weight, want := range map[float64]string{0: "5.00", 0.2: "7.50", 1: "7.50", 1.01: "10.00", 3: "12.50"} {
+		fee, err := rate.Rate(Request{Weight: weight})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee.Decimal() != want || fee.Currency() != "USD" {
+			t.Errorf("fee of %v kg = %s, want %s USD", weight, fee, want)
+		}
+	}
+}
+
+func TestZoneRate(t *testing.T) {
+	rate, err := ParseZoneRate("id-jk=10000, ID=20000", "IDR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for region, want := range map[string]string{"ID-JK": "10000.00", "ID-BA": "20000.00", "ID": "20000.00"} {
+		fee, err := rate.Rate(Request{Region: region})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee.Decimal() != want {
+			t.Errorf("fee to %s = %s, want %s", region, fee, want)
+		}
+	}
+	for _, region := range []string{"SG", ""} {
+		if _, err := rate.Rate(Request{Region: region}); err != ErrNotDeliverable {
+			t.Errorf("fee to %q error = %v, want ErrNotDeliverable", region, err)
+		}
+	}
+
+	rate.Fees["*"] = money.MustParse("75000", "IDR")
+	if fee, err := rate.Rate(Request{Region: "SG"}); err != nil || fee.Decimal() != "75000.00" {
+		t.Errorf("fee to SG = %s, %v, want 75000", fee, err)
+	}
+
+	for _, s := range []string{"ID", "ID=abc", "ID=1.555"} {
+		if _, err := ParseZoneRate(s, "IDR"); err == nil {
+			t.Errorf("ParseZoneRate(%q) accepted invalid fees", s)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrBlobNotFound is returned by Get when the store has no blob under the key
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore is implemented by every storage the shop can keep uploaded files in. Keys are slash separated paths like
+// products/<id>/<image>.jpg, URL returns the address clients download the blob from.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewBlobStore returns the store configured by BLOB_STORE, the local filesystem store is used when it is empty
+func NewBlobStore() (BlobStore, error) {
+	switch name := os.Getenv("BLOB_STORE"); name {
+	case "", LocalStoreName:
+		return NewLocalStore(os.Getenv("BLOB_DIR"), os.Getenv("BLOB_BASE_URL"))
+	case S3StoreName:
+		return NewS3Store(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", name)
+	}
+}
+
+// validKey refuses keys escaping the store, like ../secret or /etc/passwd
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid blob key %q", key)
+		}
+	}
+	return nil
+}
@@ -41,8 +41,11 @@ func GeneratePaginationFromRequest(filter map[string][]string) (pagination Pagin
 	if paramSearch, ok := filter["search"]; ok && (len(paramSearch) > 0) {
 		entries := strings.Split(strings.TrimSpace(filter["search"][0]), ",")
 		for _, e := range entries {
-			parts := strings.Split(e, "=")
-			search[parts[0]] = parts[1]
+			parts := strings.SplitN(e, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			search[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 		}
 	}
 
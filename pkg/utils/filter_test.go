@@ -0,0 +1,31 @@
+package utils
+
+import "testing"
+
+func TestGeneratePaginationSortDirection(t *testing.T) {
+	tests := []struct {
+		direction string
+		want      string
+	}{
+		{"asc", "ASC"},
+		{"DESC", "DESC"},
+		{"", "ASC"},
+		{"desc; DROP TABLE orders", "ASC"},
+		{"asc nulls first", "ASC"},
+	}
+	for _, tt := range tests {
+		pagination, _ := GeneratePaginationFromRequest(map[string][]string{"sort_direction": {tt.direction}})
+		if pagination.SortDirection != tt.want {
+			t.Errorf("sort_direction %q gave %q, want %q", tt.direction, pagination.SortDirection, tt.want)
+		}
+	}
+}
+
+func TestGeneratePaginationSortField(t *testing.T) {
+	for _, field := range []string{"created_at; DROP TABLE orders", "name desc", "1"} {
+		pagination, _ := GeneratePaginationFromRequest(map[string][]string{"sort_field": {field}})
+		if pagination.SortField != "created_at" {
+			t.Errorf("sort_field %q gave %q, want created_at", field, pagination.SortField)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -13,3 +14,27 @@ func GenerateInvoice() string {
 
 	return id
 }
+
+// GenerateSlug makes a URL slug of name, its letters and digits in lower case separated by single dashes
+func GenerateSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if 'a' <= r && r <= 'z' || '0' <= r && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+		if b.Len() >= 200 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "product"
+	}
+	return b.String()
+}
@@ -1,21 +1,179 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password
+const (
+	PasswordHashBcrypt   = "bcrypt"
+	PasswordHashArgon2id = "argon2id"
+)
+
+// bcryptMaxLength is the length in bytes of the longest password bcrypt hashes
+const bcryptMaxLength = 72
+
+// ErrPasswordMismatch is returned by CheckPassword when the password does not match the hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHashing holds the algorithm and the parameters new password hashes are created with
+type PasswordHashing struct {
+	Algorithm     string
+	BcryptCost    int
+	Argon2Memory  uint32
+	Argon2Time    uint32
+	Argon2Threads uint8
+}
+
+var passwordHashing = PasswordHashing{
+	Algorithm:     PasswordHashBcrypt,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Memory:  64 * 1024,
+	Argon2Time:    1,
+	Argon2Threads: 4,
+}
+
+// LoadPasswordHashing reads PASSWORD_HASH (bcrypt or argon2id), PASSWORD_BCRYPT_COST and
+// PASSWORD_ARGON2_MEMORY (KiB), PASSWORD_ARGON2_TIME and PASSWORD_ARGON2_THREADS, empty values keep the defaults
+func LoadPasswordHashing() error {
+	ph := passwordHashing
+
+	if alg := os.Getenv("PASSWORD_HASH"); alg != "" {
+		ph.Algorithm = alg
+	}
+	if ph.Algorithm != PasswordHashBcrypt && ph.Algorithm != PasswordHashArgon2id {
+		return fmt.Errorf("unsupported password hash %q", ph.Algorithm)
+	}
+
+	if v := os.Getenv("PASSWORD_BCRYPT_COST"); v != "" {
+		cost, err := strconv.Atoi(v)
+		if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return fmt.Errorf("invalid PASSWORD_BCRYPT_COST %q", v)
+		}
+		ph.BcryptCost = cost
+	}
+
+	for name, dst := range map[string]*uint32{
+		"PASSWORD_ARGON2_MEMORY": &ph.Argon2Memory,
+		"PASSWORD_ARGON2_TIME":   &ph.Argon2Time,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || n == 0 {
+				return fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = uint32(n)
+		}
+	}
+
+	if v := os.Getenv("PASSWORD_ARGON2_THREADS"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid PASSWORD_ARGON2_THREADS %q", v)
+		}
+		ph.Argon2Threads = uint8(n)
+	}
+
+	if ph.Algorithm == PasswordHashBcrypt && passwordPolicy.MaxLength > bcryptMaxLength {
+		return fmt.Errorf("PASSWORD_MAX_LENGTH %d is greater than %d, the longest password bcrypt hashes", passwordPolicy.MaxLength, bcryptMaxLength)
+	}
+
+	passwordHashing = ph
+	return nil
+}
+
+// HashPassword returns the hash of the password with the configured algorithm
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if passwordHashing.Algorithm == PasswordHashArgon2id {
+		return hashArgon2id(password, passwordHashing)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashing.BcryptCost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 	return string(hashedPassword), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
+// CheckPassword checks if the provided password is correct or not, whatever algorithm the hash was created with
 func CheckPassword(password string, hashedPassword string) error {
+	if strings.HasPrefix(hashedPassword, "$"+PasswordHashArgon2id+"$") {
+		params, salt, key, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			return err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// PasswordNeedsRehash reports whether the hash was created with another algorithm or weaker parameters than the configured ones
+func PasswordNeedsRehash(hashedPassword string) bool {
+	if strings.HasPrefix(hashedPassword, "$"+PasswordHashArgon2id+"$") {
+		if passwordHashing.Algorithm != PasswordHashArgon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2id(hashedPassword)
+		return err != nil ||
+			params.Argon2Memory != passwordHashing.Argon2Memory ||
+			params.Argon2Time != passwordHashing.Argon2Time ||
+			params.Argon2Threads != passwordHashing.Argon2Threads
+	}
+
+	if passwordHashing.Algorithm != PasswordHashBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != passwordHashing.BcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format, $argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>
+func hashArgon2id(password string, ph PasswordHashing) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, ph.Argon2Time, ph.Argon2Memory, ph.Argon2Threads, 32)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordHashArgon2id, argon2.Version, ph.Argon2Memory, ph.Argon2Time, ph.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func decodeArgon2id(hashedPassword string) (params PasswordHashing, salt, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2id version")
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads); err != nil {
+		return params, nil, nil, errors.New("invalid argon2id parameters")
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, errors.New("invalid argon2id salt")
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2id key")
+	}
+
+	params.Algorithm = PasswordHashArgon2id
+	return params, salt, key, nil
+}
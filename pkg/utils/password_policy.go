@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicyError lists every rule a password breaks
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, ", ")
+}
+
+// PasswordPolicy is checked by ValidatePassword on every new password
+type PasswordPolicy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+	// breached holds the upper case sha1 hex of the breached passwords
+	breached map[string]struct{}
+}
+
+var passwordPolicy = PasswordPolicy{
+	MinLength:  8,
+	MaxLength:  72,
+	MinClasses: 3,
+}
+
+// LoadPasswordPolicy reads PASSWORD_MIN_LENGTH, PASSWORD_MAX_LENGTH, PASSWORD_MIN_CLASSES and PASSWORD_BREACHED_FILE,
+// empty values keep the defaults. The breached file holds one password or sha1 hex per line, a sha1 may be
+// followed by :<count> as in the Have I Been Pwned downloads. It is called after LoadPasswordHashing, a maximum
+// length above 72 bytes needs argon2id.
+func LoadPasswordPolicy() error {
+	pp := passwordPolicy
+
+	for name, dst := range map[string]*int{
+		"PASSWORD_MIN_LENGTH":  &pp.MinLength,
+		"PASSWORD_MAX_LENGTH":  &pp.MaxLength,
+		"PASSWORD_MIN_CLASSES": &pp.MinClasses,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = n
+		}
+	}
+	if pp.MaxLength < pp.MinLength {
+		return fmt.Errorf("PASSWORD_MAX_LENGTH %d is lower than PASSWORD_MIN_LENGTH %d", pp.MaxLength, pp.MinLength)
+	}
+	if passwordHashing.Algorithm == PasswordHashBcrypt && pp.MaxLength > bcryptMaxLength {
+		return fmt.Errorf("PASSWORD_MAX_LENGTH %d is greater than %d, the longest password bcrypt hashes", pp.MaxLength, bcryptMaxLength)
+	}
+	if pp.MinClasses > 4 {
+		return fmt.Errorf("PASSWORD_MIN_CLASSES %d is greater than 4", pp.MinClasses)
+	}
+
+	if path := os.Getenv("PASSWORD_BREACHED_FILE"); path != "" {
+		breached, err := loadBreachedPasswords(path)
+		if err != nil {
+			return err
+		}
+		pp.breached = breached
+	}
+
+	passwordPolicy = pp
+	return nil
+}
+
+// ValidatePassword returns a *PasswordPolicyError when password breaks the configured policy
+func ValidatePassword(password string) error {
+	var violations []string
+
+	if n := len([]rune(password)); n < passwordPolicy.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", passwordPolicy.MinLength))
+	} else if len(password) > passwordPolicy.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes", passwordPolicy.MaxLength))
+	}
+
+	if classes := passwordClasses(password); classes < passwordPolicy.MinClasses {
+		violations = append(violations, fmt.Sprintf("must contain %d of lower case, upper case, digit and symbol characters", passwordPolicy.MinClasses))
+	}
+
+	if _, ok := passwordPolicy.breached[sha1Hex(password)]; ok {
+		violations = append(violations, "appears in a list of breached passwords")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+func loadBreachedPasswords(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breached passwords file, %v", err)
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSha1Hex(hash) {
+			breached[strings.ToUpper(hash)] = struct{}{}
+			continue
+		}
+		breached[sha1Hex(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading breached passwords file, %v", err)
+	}
+	return breached, nil
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSha1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
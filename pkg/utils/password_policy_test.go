@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setPasswordPolicy replaces the configured policy for the duration of the test
+func setPasswordPolicy(t *testing.T, pp PasswordPolicy) {
+	t.Helper()
+
+	saved := passwordPolicy
+	passwordPolicy = pp
+	t.Cleanup(func() {
+		passwordPolicy = saved
+	})
+}
+
+func TestValidatePassword(t *testing.T) {
+	setPasswordPolicy(t, PasswordPolicy{
+		MinLength:  8,
+		MaxLength:  16,
+		MinClasses: 3,
+		breached:   map[string]struct{}{sha1Hex("Password-1"): {}},
+	})
+
+	tests := []struct {
+		password   string
+		violations int
+	}{
+		{"Secret-123", 0},
+		{"secret-123", 0},
+		{"Sécret123", 0},
+		{"Sec-1", 1},
+		{"Secret-123456789012", 1},
+		{"secret123", 1},
+		{"secretsecret", 1},
+		{"Password-1", 1},
+		{"sec", 2},
+		{"", 2},
+	}
+	for _, tt := range tests {
+		err := ValidatePassword(tt.password)
+		if tt.violations == 0 {
+			if err != nil {
+				t.Errorf("ValidatePassword(%q) = %v, want nil", tt.password, err)
+			}
+			continue
+		}
+
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("ValidatePassword(%q) = %v, want a PasswordPolicyError", tt.password, err)
+			continue
+		}
+		if len(policyErr.Violations) != tt.violations {
+			t.Errorf("ValidatePassword(%q) violations = %v, want %d", tt.password, policyErr.Violations, tt.violations)
+		}
+	}
+}
+
+func TestPasswordClasses(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"", 0},
+		{"abc", 1},
+		{"aBc", 2},
+		{"aB1", 3},
+		{"aB1!", 4},
+		{"éÉ٣ ", 4},
+	}
+	for _, tt := range tests {
+		if got := passwordClasses(tt.password); got != tt.want {
+			t.Errorf("passwordClasses(%q) = %d, want %d", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	sha1 := sha1Hex("Hashed-123")
+	content := strings.Join([]string{
+		"Plain-123",
+		"",
+		strings.ToLower(sha1) + ":42",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	breached, err := loadBreachedPasswords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breached) != 2 {
+		t.Errorf("loaded %d breached passwords, want 2", len(breached))
+	}
+	for _, password := range []string{"Plain-123", "Hashed-123"} {
+		if _, ok := breached[sha1Hex(password)]; !ok {
+			t.Errorf("%q is not in the breached passwords", password)
+		}
+	}
+
+	if _, err := loadBreachedPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("loadBreachedPasswords() accepted a missing file")
+	}
+}
+
+func TestLoadPasswordPolicy(t *testing.T) {
+	breachedFile := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(breachedFile, []byte("Plain-123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		hashing PasswordHashing
+		env     map[string]string
+		want    PasswordPolicy
+		wantErr bool
+	}{
+		{name: "defaults", hashing: testBcrypt, want: PasswordPolicy{MinLength: 8, MaxLength: 72, MinClasses: 3}},
+		{
+			name:    "lengths and classes",
+			hashing: testBcrypt,
+			env:     map[string]string{"PASSWORD_MIN_LENGTH": "12", "PASSWORD_MAX_LENGTH": "64", "PASSWORD_MIN_CLASSES": "4"},
+			want:    PasswordPolicy{MinLength: 12, MaxLength: 64, MinClasses: 4},
+		},
+		{
+			name:    "long passwords with argon2id",
+			hashing: testArgon2id,
+			env:     map[string]string{"PASSWORD_MAX_LENGTH": "128"},
+			want:    PasswordPolicy{MinLength: 8, MaxLength: 128, MinClasses: 3},
+		},
+		{name: "long passwords with bcrypt", hashing: testBcrypt, env: map[string]string{"PASSWORD_MAX_LENGTH": "73"}, wantErr: true},
+		{name: "maximum below the minimum", hashing: testBcrypt, env: map[string]string{"PASSWORD_MIN_LENGTH": "20", "PASSWORD_MAX_LENGTH": "16"}, wantErr: true},
+		{name: "too many classes", hashing: testBcrypt, env: map[string]string{"PASSWORD_MIN_CLASSES": "5"}, wantErr: true},
+		{name: "negative length", hashing: testBcrypt, env: map[string]string{"PASSWORD_MIN_LENGTH": "-1"}, wantErr: true},
+		{name: "invalid length", hashing: testBcrypt, env: map[string]string{"PASSWORD_MAX_LENGTH": "long"}, wantErr: true},
+		{name: "missing breached file", hashing: testBcrypt, env: map[string]string{"PASSWORD_BREACHED_FILE": filepath.Join(t.TempDir(), "missing.txt")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPasswordHashing(t, tt.hashing)
+			setPasswordPolicy(t, PasswordPolicy{MinLength: 8, MaxLength: 72, MinClasses: 3})
+			for _, name := range []string{"PASSWORD_MIN_LENGTH", "PASSWORD_MAX_LENGTH", "PASSWORD_MIN_CLASSES", "PASSWORD_BREACHED_FILE"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			err := LoadPasswordPolicy()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("LoadPasswordPolicy() accepted an invalid configuration")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPasswordPolicy(): %v", err)
+			}
+			if passwordPolicy.MinLength != tt.want.MinLength || passwordPolicy.MaxLength != tt.want.MaxLength || passwordPolicy.MinClasses != tt.want.MinClasses {
+				t.Errorf("loaded %+v, want %+v", passwordPolicy, tt.want)
+			}
+		})
+	}
+
+	t.Run("breached file", func(t *testing.T) {
+		setPasswordHashing(t, testBcrypt)
+		setPasswordPolicy(t, PasswordPolicy{MinLength: 8, MaxLength: 72, MinClasses: 3})
+		t.Setenv("PASSWORD_BREACHED_FILE", breachedFile)
+
+		if err := LoadPasswordPolicy(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidatePassword("Plain-123"); err == nil {
+			t.Error("ValidatePassword() accepted a breached password")
+		}
+	})
+}
+
+func TestLoadPasswordHashingKeepsBcryptMaxLength(t *testing.T) {
+	setPasswordHashing(t, testArgon2id)
+	setPasswordPolicy(t, PasswordPolicy{MinLength: 8, MaxLength: 128, MinClasses: 3})
+	t.Setenv("PASSWORD_HASH", PasswordHashBcrypt)
+
+	if err := LoadPasswordHashing(); err == nil {
+		t.Error("LoadPasswordHashing() accepted bcrypt with a maximum length above 72")
+	}
+}
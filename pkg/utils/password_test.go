@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setPasswordHashing replaces the configured hashing for the duration of the test
+func setPasswordHashing(t *testing.T, ph PasswordHashing) {
+	t.Helper()
+
+	saved := passwordHashing
+	passwordHashing = ph
+	t.Cleanup(func() {
+		passwordHashing = saved
+	})
+}
+
+var testArgon2id = PasswordHashing{
+	Algorithm:     PasswordHashArgon2id,
+	BcryptCost:    bcrypt.MinCost,
+	Argon2Memory:  1024,
+	Argon2Time:    1,
+	Argon2Threads: 1,
+}
+
+var testBcrypt = PasswordHashing{
+	Algorithm:     PasswordHashBcrypt,
+	BcryptCost:    bcrypt.MinCost,
+	Argon2Memory:  1024,
+	Argon2Time:    1,
+	Argon2Threads: 1,
+}
+
+func TestArgon2idHash(t *testing.T) {
+	setPasswordHashing(t, testArgon2id)
+
+	hash, err := HashPassword("Secret-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("HashPassword() = %s, want a PHC argon2id string", hash)
+	}
+
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Argon2Memory != 1024 || params.Argon2Time != 1 || params.Argon2Threads != 1 {
+		t.Errorf("decoded parameters m=%d,t=%d,p=%d, want m=1024,t=1,p=1", params.Argon2Memory, params.Argon2Time, params.Argon2Threads)
+	}
+	if len(salt) != 16 || len(key) != 32 {
+		t.Errorf("decoded a %d bytes salt and a %d bytes key, want 16 and 32", len(salt), len(key))
+	}
+
+	if err := CheckPassword("Secret-123", hash); err != nil {
+		t.Errorf("CheckPassword() of the password = %v", err)
+	}
+	if err := CheckPassword("Secret-124", hash); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("CheckPassword() of another password = %v, want ErrPasswordMismatch", err)
+	}
+
+	other, err := HashPassword("Secret-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("two hashes of the same password share their salt")
+	}
+}
+
+func TestDecodeArgon2idInvalid(t *testing.T) {
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+	} {
+		if _, _, _, err := decodeArgon2id(hash); err == nil {
+			t.Errorf("decodeArgon2id(%q) accepted an invalid hash", hash)
+		}
+		if err := CheckPassword("Secret-123", hash); err == nil {
+			t.Errorf("CheckPassword() accepted the invalid hash %q", hash)
+		}
+	}
+}
+
+func TestCheckPasswordBcrypt(t *testing.T) {
+	setPasswordHashing(t, testBcrypt)
+
+	hash, err := HashPassword("Secret-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPassword("Secret-123", hash); err != nil {
+		t.Errorf("CheckPassword() of the password = %v", err)
+	}
+	if err := CheckPassword("Secret-124", hash); err == nil {
+		t.Error("CheckPassword() accepted another password")
+	}
+
+	// argon2id hashes are still checked once bcrypt is configured again
+	setPasswordHashing(t, testArgon2id)
+	argon2Hash, err := HashPassword("Secret-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPasswordHashing(t, testBcrypt)
+	if err := CheckPassword("Secret-123", argon2Hash); err != nil {
+		t.Errorf("CheckPassword() of an argon2id hash = %v", err)
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	setPasswordHashing(t, testBcrypt)
+	bcryptHash, err := HashPassword("Secret-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPasswordHashing(t, testArgon2id)
+	argon2Hash, err := HashPassword("Secret-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moreMemory := testArgon2id
+	moreMemory.Argon2Memory = 2048
+	moreTime := testArgon2id
+	moreTime.Argon2Time = 2
+	moreThreads := testArgon2id
+	moreThreads.Argon2Threads = 2
+	higherCost := testBcrypt
+	higherCost.BcryptCost = bcrypt.MinCost + 1
+
+	tests := []struct {
+		name    string
+		hashing PasswordHashing
+		hash    string
+		want    bool
+	}{
+		{name: "bcrypt with the same cost", hashing: testBcrypt, hash: bcryptHash, want: false},
+		{name: "bcrypt with another cost", hashing: higherCost, hash: bcryptHash, want: true},
+		{name: "bcrypt when argon2id is configured", hashing: testArgon2id, hash: bcryptHash, want: true},
+		{name: "argon2id with the same parameters", hashing: testArgon2id, hash: argon2Hash, want: false},
+		{name: "argon2id with another memory", hashing: moreMemory, hash: argon2Hash, want: true},
+		{name: "argon2id with another time", hashing: moreTime, hash: argon2Hash, want: true},
+		{name: "argon2id with other threads", hashing: moreThreads, hash: argon2Hash, want: true},
+		{name: "argon2id when bcrypt is configured", hashing: testBcrypt, hash: argon2Hash, want: true},
+		{name: "invalid argon2id", hashing: testArgon2id, hash: "$argon2id$v=19$m=1024", want: true},
+		{name: "invalid bcrypt", hashing: testBcrypt, hash: "not a hash", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPasswordHashing(t, tt.hashing)
+			if got := PasswordNeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("PasswordNeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPasswordHashing(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    PasswordHashing
+		wantErr bool
+	}{
+		{name: "defaults", want: passwordHashing},
+		{
+			name: "argon2id",
+			env:  map[string]string{"PASSWORD_HASH": "argon2id", "PASSWORD_ARGON2_MEMORY": "2048", "PASSWORD_ARGON2_TIME": "3", "PASSWORD_ARGON2_THREADS": "2"},
+			want: PasswordHashing{Algorithm: PasswordHashArgon2id, BcryptCost: bcrypt.DefaultCost, Argon2Memory: 2048, Argon2Time: 3, Argon2Threads: 2},
+		},
+		{
+			name: "bcrypt cost",
+			env:  map[string]string{"PASSWORD_BCRYPT_COST": "12"},
+			want: PasswordHashing{Algorithm: PasswordHashBcrypt, BcryptCost: 12, Argon2Memory: 64 * 1024, Argon2Time: 1, Argon2Threads: 4},
+		},
+		{name: "unknown algorithm", env: map[string]string{"PASSWORD_HASH": "md5"}, wantErr: true},
+		{name: "bcrypt cost too low", env: map[string]string{"PASSWORD_BCRYPT_COST": "3"}, wantErr: true},
+		{name: "bcrypt cost too high", env: map[string]string{"PASSWORD_BCRYPT_COST": "32"}, wantErr: true},
+		{name: "zero argon2id memory", env: map[string]string{"PASSWORD_ARGON2_MEMORY": "0"}, wantErr: true},
+		{name: "too many argon2id threads", env: map[string]string{"PASSWORD_ARGON2_THREADS": "256"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setPasswordHashing(t, passwordHashing)
+			for _, name := range []string{"PASSWORD_HASH", "PASSWORD_BCRYPT_COST", "PASSWORD_ARGON2_MEMORY", "PASSWORD_ARGON2_TIME", "PASSWORD_ARGON2_THREADS"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			err := LoadPasswordHashing()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("LoadPasswordHashing() accepted an invalid configuration")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPasswordHashing(): %v", err)
+			}
+			if passwordHashing != tt.want {
+				t.Errorf("loaded %+v, want %+v", passwordHashing, tt.want)
+			}
+		})
+	}
+}
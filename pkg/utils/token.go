@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenerateRandomToken returns a url safe random token of 32 bytes
+func GenerateRandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the sha256 hex digest of token, used to store tokens without keeping them in clear
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignToken returns the hex HMAC-SHA256 of token with secret, so stored tokens can't be forged without the secret
+func SignToken(token string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseDuration parses durations like 15m, 1h or 7d, fallback is returned when value is empty or invalid
+func ParseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+
+	uom := value[len(value)-1:]
+	n, err := strconv.Atoi(strings.TrimSuffix(value, uom))
+	if err != nil {
+		return fallback
+	}
+
+	switch uom {
+	case "m":
+		return time.Minute * time.Duration(n)
+	case "h":
+		return time.Hour * time.Duration(n)
+	case "d":
+		return time.Hour * 24 * time.Duration(n)
+	default:
+		return fallback
+	}
+}
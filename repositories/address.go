@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"fmt"
+	"mvp-shop-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type addressRepository struct {
+	db *gorm.DB
+}
+
+type AddressRepositoryInterface interface {
+	GetAddresses(customerID string) ([]models.Address, error)
+	GetAddressById(id string, customerID string) (models.Address, error)
+	GetDefaultAddress(customerID string) (models.Address, error)
+	CreateAddress(address *models.Address) error
+	UpdateAddress(address *models.Address) error
+	DeleteAddress(id string, customerID string) error
+}
+
+func NewAddressRepository(db *gorm.DB) AddressRepositoryInterface {
+	return &addressRepository{
+		db: db,
+	}
+}
+
+func (ar *addressRepository) GetAddresses(customerID string) (addresses []models.Address, err error) {
+	return addresses, ar.db.Where("customer_id = ?", customerID).Order("is_default desc, created_at").Find(&addresses).Error
+}
+
+func (ar *addressRepository) GetAddressById(id string, customerID string) (address models.Address, err error) {
+	return address, ar.db.Where("id = ? and customer_id = ?", id, customerID).Take(&address).Error
+}
+
+func (ar *addressRepository) GetDefaultAddress(customerID string) (address models.Address, err error) {
+	return address, ar.db.Where("customer_id = ? and is_default", customerID).Take(&address).Error
+}
+
+// lockAddressBook locks the customer row so the default address of customerID is changed by one transaction at a time
+func lockAddressBook(tx *gorm.DB, customerID string) error {
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", customerID).Take(&customer).Error; err != nil {
+		return fmt.Errorf("error getting customer, %w", err)
+	}
+	return nil
+}
+
+// unsetDefaultAddress makes no address of customerID the default one
+func unsetDefaultAddress(tx *gorm.DB, customerID string) error {
+	if err := tx.Model(&models.Address{}).Where("customer_id = ? and is_default", customerID).Update("is_default", false).Error; err != nil {
+		return fmt.Errorf("error updating default address, %v", err)
+	}
+	return nil
+}
+
+// CreateAddress adds address to the address book of address.CustomerID, the first address is the default one
+func (ar *addressRepository) CreateAddress(address *models.Address) error {
+	tx := ar.db.Begin()
+	defer tx.Rollback()
+
+	if err := lockAddressBook(tx, address.CustomerID); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.Address{}).Where("customer_id = ?", address.CustomerID).Count(&count).Error; err != nil {
+		return fmt.Errorf("error getting address, %v", err)
+	}
+	if count == 0 {
+		address.IsDefault = true
+	} else if address.IsDefault {
+		if err := unsetDefaultAddress(tx, address.CustomerID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Create(address).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction, %v", err)
+	}
+	return nil
+}
+
+// UpdateAddress replaces the address address.ID of address.CustomerID, gorm.ErrRecordNotFound is returned when there is
+// none. It becomes the default address when address.IsDefault is set, the default address stays the default otherwise.
+func (ar *addressRepository) UpdateAddress(address *models.Address) error {
+	tx := ar.db.Begin()
+	defer tx.Rollback()
+
+	if err := lockAddressBook(tx, address.CustomerID); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"label":          address.Label,
+		"recipient_name": address.RecipientName,
+		"phone":          address.Phone,
+		"street":         address.Street,
+		"city":           address.City,
+		"province":       address.Province,
+		"postal_code":    address.PostalCode,
+		"region":         address.Region,
+		"updated_at":     gorm.Expr("now()"),
+		"updated_by":     address.UpdatedBy,
+	}
+	if address.IsDefault {
+		if err := unsetDefaultAddress(tx, address.CustomerID); err != nil {
+			return err
+		}
+		updates["is_default"] = true
+	}
+
+	result := tx.Model(&models.Address{}).Where("id = ? and customer_id = ?", address.ID, address.CustomerID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction, %v", err)
+	}
+	return nil
+}
+
+// DeleteAddress removes the address id of customerID, gorm.ErrRecordNotFound is returned when there is none. When it was
+// the default address the latest remaining address becomes the default one. Orders keep their shipping address.
+func (ar *addressRepository) DeleteAddress(id string, customerID string) error {
+	tx := ar.db.Begin()
+	defer tx.Rollback()
+
+	if err := lockAddressBook(tx, customerID); err != nil {
+		return err
+	}
+
+	var deleted []models.Address
+	result := tx.Clauses(clause.Returning{}).Where("id = ? and customer_id = ?", id, customerID).Delete(&deleted)
+	if result.Error != nil {
+		return result.Error
+	}
+	if len(deleted) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	if deleted[0].IsDefault {
+		latest := tx.Model(&models.Address{}).Select("id").Where("customer_id = ?", customerID).Order("created_at desc").Limit(1)
+		if err := tx.Model(&models.Address{}).Where("id = (?)", latest).Update("is_default", true).Error; err != nil {
+			return fmt.Errorf("error updating default address, %v", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction, %v", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"mvp-shop-backend/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type cartRepository struct {
@@ -14,8 +15,12 @@ type CartRepositoryInterface interface {
 	CreateCart(cart *models.Cart) error
 	UpdateCart(cart *models.CartUpdate) (err error)
 	GetCartByCustomerID(id string) (carts []models.ProductCartView, err error)
-	GetCartByCustomerIDAndProductID(id string, productID string) (cart models.ProductCartView, err error)
+	GetCartById(id string, customerID string) (cart models.ProductCartView, err error)
+	GetCartByCustomerIDAndProductID(id string, productID string, variantID *string) (cart models.ProductCartView, err error)
 	DeleteCart(cart *models.CartUpdate) (err error)
+	GetCartCoupon(customerID string) (models.Promotion, error)
+	SaveCartCoupon(coupon *models.CartCoupon) error
+	DeleteCartCoupon(customerID string) error
 }
 
 func NewCartRepository(db *gorm.DB) CartRepositoryInterface {
@@ -28,9 +33,11 @@ func (cr *cartRepository) CreateCart(cart *models.Cart) error {
 	return cr.db.Create(cart).Error
 }
 
+// UpdateCart updates a cart line of cart.CustomerID, gorm.ErrRecordNotFound is returned when the customer has no such line
 func (cr *cartRepository) UpdateCart(cart *models.CartUpdate) (err error) {
-	return cr.db.
-		Model(&models.Cart{ID: cart.ID}).
+	result := cr.db.
+		Model(&models.Cart{}).
+		Where("id = ? and customer_id = ? and status not in ?", cart.ID, cart.CustomerID, []models.Status{models.StatusDeleted, models.StatusCheckout}).
 		Updates(
 			map[string]interface{}{
 				"qty":        cart.Qty,
@@ -40,31 +47,105 @@ func (cr *cartRepository) UpdateCart(cart *models.CartUpdate) (err error) {
 				"updated_at": gorm.Expr("now()"),
 				"updated_by": cart.UpdatedBy,
 			},
-		).Error
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
+// DeleteCart deletes a cart line of cart.CustomerID, gorm.ErrRecordNotFound is returned when the customer has no such line
 func (cr *cartRepository) DeleteCart(cart *models.CartUpdate) (err error) {
-	return cr.db.
-		Model(&models.Cart{ID: cart.ID}).
+	result := cr.db.
+		Model(&models.Cart{}).
+		Where("id = ? and customer_id = ? and status not in ?", cart.ID, cart.CustomerID, []models.Status{models.StatusDeleted, models.StatusCheckout}).
 		Updates(
 			map[string]interface{}{
 				"status":     models.StatusDeleted.String(),
 				"updated_at": gorm.Expr("now()"),
 				"updated_by": cart.UpdatedBy,
 			},
-		).Error
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
+// GetCartByCustomerID returns the open cart lines of customer id with the current price of their product and variant,
+// the price stored with a line is not used to price the cart
 func (cr *cartRepository) GetCartByCustomerID(id string) (carts []models.ProductCartView, err error) {
 	return carts, cr.db.
-		Table("carts").Select("carts.*, products.name as name").
+		Table("carts").
+		Select(`carts.id, carts.product_id, carts.variant_id, carts.qty, carts.amount, carts.status, carts.created_at,
+			carts.created_by, carts.updated_at, carts.updated_by, products.price as price, products.name as name,
+			products.category_id as category_id, product_variants.price as variant_price, product_variants.sku as sku,
+			product_variants.options as options`).
 		Joins("left join products on carts.product_id = products.id").
-		Where("carts.customer_id = ? and carts.status <> ?", id, models.StatusDeleted).Find(&carts).Error
+		Joins("left join product_variants on carts.variant_id = product_variants.id").
+		Where("carts.customer_id = ? and carts.status not in ?", id, []models.Status{models.StatusDeleted, models.StatusCheckout}).Find(&carts).Error
 }
 
-func (cr *cartRepository) GetCartByCustomerIDAndProductID(id string, productID string) (cart models.ProductCartView, err error) {
+// GetCartById returns the open cart line id of customerID with the price of its variant, gorm.ErrRecordNotFound is
+// returned when there is none
+func (cr *cartRepository) GetCartById(id string, customerID string) (cart models.ProductCartView, err error) {
 	return cart, cr.db.
+		Table("carts").Select("carts.*, product_variants.price as variant_price").
+		Joins("left join product_variants on carts.variant_id = product_variants.id").
+		Where("carts.id = ? and carts.customer_id = ? and carts.status not in ?", id, customerID, []models.Status{models.StatusDeleted, models.StatusCheckout}).
+		Take(&cart).Error
+}
+
+// GetCartByCustomerIDAndProductID returns the open cart line of productID, of its variant variantID when not nil
+func (cr *cartRepository) GetCartByCustomerIDAndProductID(id string, productID string, variantID *string) (cart models.ProductCartView, err error) {
+	query := cr.db.
 		Table("carts").Select("carts.*, products.name as name").
 		Joins("left join products on carts.product_id = products.id").
-		Where("carts.customer_id = ? and carts.product_id = ? and carts.status <> ?", id, productID, models.StatusDeleted).Find(&cart).Error
+		Where("carts.customer_id = ? and carts.product_id = ? and carts.status not in ?", id, productID, []models.Status{models.StatusDeleted, models.StatusCheckout})
+	if variantID == nil {
+		query = query.Where("carts.variant_id is null")
+	} else {
+		query = query.Where("carts.variant_id = ?", *variantID)
+	}
+	return cart, query.Find(&cart).Error
+}
+
+// GetCartCoupon returns the promotion applied to the cart of customerID, gorm.ErrRecordNotFound is returned when there is none
+func (cr *cartRepository) GetCartCoupon(customerID string) (promotion models.Promotion, err error) {
+	return promotion, cr.db.
+		Joins("join cart_coupons on cart_coupons.promotion_id = promotions.id").
+		Where("cart_coupons.customer_id = ?", customerID).
+		First(&promotion).Error
+}
+
+// SaveCartCoupon applies a promotion to the cart of coupon.CustomerID, it replaces the one applied before
+func (cr *cartRepository) SaveCartCoupon(coupon *models.CartCoupon) error {
+	return cr.db.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "customer_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"promotion_id": coupon.PromotionID,
+				"created_at":   gorm.Expr("now()"),
+				"created_by":   coupon.CreatedBy,
+			}),
+		}).
+		Create(coupon).Error
+}
+
+// DeleteCartCoupon removes the promotion applied to the cart of customerID, gorm.ErrRecordNotFound is returned when there is none
+func (cr *cartRepository) DeleteCartCoupon(customerID string) error {
+	result := cr.db.Where("customer_id = ?", customerID).Delete(&models.CartCoupon{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
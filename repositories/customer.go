@@ -17,6 +17,10 @@ type CustomerRepositoryInterface interface {
 	GetCustomerByEmail(email string) (models.Customer, error)
 	GetCustomerById(id string) (models.Customer, error)
 	UpdateCustomer(customer *models.CustomerUpdate) error
+	UpdateCustomerProfile(id string, profile *models.CustomerProfileUpdate, updatedBy string) error
+	GetCustomerPassword(id string) (string, error)
+	UpdateCustomerPassword(id string, password string, updatedBy string) error
+	RehashCustomerPassword(id string, oldPassword string, password string) error
 	GetCustomers(pagination utils.Pagination, where map[string]string) (customers []models.Customer, count int64, err error)
 	DeleteCustomer(customer *models.CustomerUpdate) (err error)
 }
@@ -43,7 +47,7 @@ func (cr *customerRepository) GetCustomerById(id string) (models.Customer, error
 	var customer models.Customer
 	if err := cr.db.
 		Where(&models.Customer{ID: id}).
-		Select("id", "email", "name", "status", "created_at", "created_by", "updated_at", "updated_by").
+		Select("id", "email", "name", "role", "status", "created_at", "created_by", "updated_at", "updated_by", "email_verified_at", "failed_login_attempts", "locked_until", "token_version").
 		First(&customer).Error; err != nil {
 		return customer, err
 	}
@@ -51,14 +55,66 @@ func (cr *customerRepository) GetCustomerById(id string) (models.Customer, error
 }
 
 func (cr *customerRepository) UpdateCustomer(customer *models.CustomerUpdate) error {
+	updates := map[string]interface{}{
+		"updated_at": gorm.Expr("now()"),
+		"updated_by": customer.UpdatedBy,
+	}
+	if customer.Name != "" {
+		updates["name"] = customer.Name
+	}
+	if customer.Status != "" {
+		updates["status"] = customer.Status
+	}
+	if customer.Role != "" {
+		updates["role"] = customer.Role
+	}
+
 	return cr.db.
 		Model(&models.Customer{ID: customer.ID}).
+		Updates(updates).Error
+}
+
+// UpdateCustomerProfile updates the non empty fields of profile, a new email has to be verified again
+func (cr *customerRepository) UpdateCustomerProfile(id string, profile *models.CustomerProfileUpdate, updatedBy string) error {
+	updates := map[string]interface{}{
+		"updated_at": gorm.Expr("now()"),
+		"updated_by": updatedBy,
+	}
+	if profile.Name != "" {
+		updates["name"] = profile.Name
+	}
+	if profile.Email != "" {
+		updates["email"] = profile.Email
+		updates["email_verified_at"] = nil
+	}
+
+	return cr.db.
+		Model(&models.Customer{ID: id}).
+		Updates(updates).Error
+}
+
+func (cr *customerRepository) GetCustomerPassword(id string) (string, error) {
+	var customer models.Customer
+	if err := cr.db.
+		Where(&models.Customer{ID: id}).
+		Where("status <> ?", models.StatusDeleted).
+		Select("id", "password").
+		First(&customer).Error; err != nil {
+		return "", err
+	}
+	return customer.Password, nil
+}
+
+// UpdateCustomerPassword sets a new password, the access tokens issued before are refused from now on
+func (cr *customerRepository) UpdateCustomerPassword(id string, password string, updatedBy string) error {
+	return cr.db.
+		Model(&models.Customer{ID: id}).
 		Updates(
 			map[string]interface{}{
-				"name":       customer.Name,
-				"status":     customer.Status,
-				"updated_at": gorm.Expr("now()"),
-				"updated_by": customer.UpdatedBy,
+				"password":      password,
+				"token_version": gorm.Expr("token_version + 1"),
+				"updated_at":    gorm.Expr("now()"),
+				"updated_by":    updatedBy,
 			},
 		).Error
 }
@@ -72,7 +128,7 @@ func (cr *customerRepository) GetCustomers(pagination utils.Pagination, where ma
 	queryBuilder := cr.db.
 		Model(&models.Customer{}).
 		Where("status <> ?", models.StatusDeleted).
-		Select("id", "email", "name", "status", "created_at", "created_by", "updated_at", "updated_by")
+		Select("id", "email", "name", "role", "status", "created_at", "created_by", "updated_at", "updated_by", "email_verified_at", "failed_login_attempts", "locked_until")
 
 	if id, ok := where["id"]; ok && id != "" {
 		queryBuilder = queryBuilder.Where("id = ?", id)
@@ -129,3 +185,12 @@ func (cr *customerRepository) DeleteCustomer(customer *models.CustomerUpdate) (e
 			},
 		).Error
 }
+
+// RehashCustomerPassword replaces the hash oldPassword with password, it is a no-op when the password changed meanwhile.
+// The audit columns are kept, the password itself is unchanged.
+func (cr *customerRepository) RehashCustomerPassword(id string, oldPassword string, password string) error {
+	return cr.db.
+		Model(&models.Customer{}).
+		Where("id = ? and password = ?", id, oldPassword).
+		Update("password", password).Error
+}
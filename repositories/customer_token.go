@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCustomerTokenInvalid is returned when a customer token is unknown, already used or expired
+var ErrCustomerTokenInvalid = errors.New("customer token invalid")
+
+type customerTokenRepository struct {
+	db *gorm.DB
+}
+
+type CustomerTokenRepositoryInterface interface {
+	CreateCustomerToken(token *models.CustomerToken) error
+	TransactionVerifyEmail(tokenHash string) (models.CustomerToken, error)
+	TransactionResetPassword(tokenHash string, password string) (models.CustomerToken, error)
+}
+
+func NewCustomerTokenRepository(db *gorm.DB) CustomerTokenRepositoryInterface {
+	return &customerTokenRepository{
+		db: db,
+	}
+}
+
+// CreateCustomerToken creates token and invalidates the unused tokens of the same purpose of the customer
+func (ctr *customerTokenRepository) CreateCustomerToken(token *models.CustomerToken) error {
+	tx := ctr.db.Begin()
+	defer tx.Rollback()
+
+	if err := tx.
+		Model(&models.CustomerToken{}).
+		Where("customer_id = ? and purpose = ? and used_at is null", token.CustomerID, token.Purpose).
+		Update("used_at", gorm.Expr("now()")).Error; err != nil {
+		return fmt.Errorf("error invalidating customer tokens, %v", err)
+	}
+
+	if err := tx.Create(token).Error; err != nil {
+		return fmt.Errorf("error creating customer token, %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return nil
+}
+
+// TransactionVerifyEmail uses the verify_email token of tokenHash and marks the email of its customer as verified
+func (ctr *customerTokenRepository) TransactionVerifyEmail(tokenHash string) (models.CustomerToken, error) {
+	tx := ctr.db.Begin()
+	defer tx.Rollback()
+
+	token, err := useCustomerToken(tx, tokenHash, models.CustomerTokenVerifyEmail)
+	if err != nil {
+		return token, err
+	}
+
+	if err := tx.Model(&models.Customer{}).
+		Where("id = ? and email_verified_at is null", token.CustomerID).
+		Update("email_verified_at", gorm.Expr("now()")).Error; err != nil {
+		return token, fmt.Errorf("error verifying customer email, %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return token, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return token, nil
+}
+
+// TransactionResetPassword uses the reset_password token of tokenHash and sets the password of its customer.
+// The email is verified on the way, receiving the token proves it, any login lockout is lifted and the access
+// tokens issued before are refused.
+func (ctr *customerTokenRepository) TransactionResetPassword(tokenHash string, password string) (models.CustomerToken, error) {
+	tx := ctr.db.Begin()
+	defer tx.Rollback()
+
+	token, err := useCustomerToken(tx, tokenHash, models.CustomerTokenResetPassword)
+	if err != nil {
+		return token, err
+	}
+
+	if err := tx.Model(&models.Customer{ID: token.CustomerID}).
+		Updates(
+			map[string]interface{}{
+				"password":              password,
+				"token_version":         gorm.Expr("token_version + 1"),
+				"email_verified_at":     gorm.Expr("coalesce(email_verified_at, now())"),
+				"failed_login_attempts": 0,
+				"locked_until":          nil,
+				"updated_at":            gorm.Expr("now()"),
+			},
+		).Error; err != nil {
+		return token, fmt.Errorf("error resetting customer password, %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return token, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return token, nil
+}
+
+func useCustomerToken(tx *gorm.DB, tokenHash string, purpose models.CustomerTokenPurpose) (models.CustomerToken, error) {
+	var token models.CustomerToken
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(&models.CustomerToken{TokenHash: tokenHash, Purpose: purpose}).
+		First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return token, ErrCustomerTokenInvalid
+		}
+		return token, err
+	}
+
+	if token.UsedAt != nil || token.ExpiresAt.Before(time.Now()) {
+		return token, ErrCustomerTokenInvalid
+	}
+
+	if err := tx.Model(&models.CustomerToken{ID: token.ID}).
+		Update("used_at", gorm.Expr("now()")).Error; err != nil {
+		return token, fmt.Errorf("error using customer token, %v", err)
+	}
+
+	return token, nil
+}
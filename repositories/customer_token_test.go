@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"mvp-shop-backend/models"
+	"testing"
+	"time"
+)
+
+// TestTransactionResetPassword uses reset tokens once, refuses expired, replaced and verify_email tokens, lifts the
+// login lockout of the customer and records the password change
+func TestTransactionResetPassword(t *testing.T) {
+	db := openTestDB(t, "repositories_test_customer_token")
+	seedCustomers(t, db, 1)
+	if err := db.Exec(`UPDATE customers SET failed_login_attempts = 5, locked_until = now() + interval '1 hour' WHERE id = 'customer-0'`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	customerTokenRepository := NewCustomerTokenRepository(db)
+	createToken := func(id string, purpose models.CustomerTokenPurpose, expiresAt time.Time) {
+		t.Helper()
+		if err := customerTokenRepository.CreateCustomerToken(&models.CustomerToken{
+			ID:         id,
+			CustomerID: "customer-0",
+			Purpose:    purpose,
+			TokenHash:  "hash-" + id,
+			ExpiresAt:  expiresAt,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createToken("verify", models.CustomerTokenVerifyEmail, time.Now().Add(time.Hour))
+	createToken("expired", models.CustomerTokenResetPassword, time.Now().Add(-time.Minute))
+	createToken("replaced", models.CustomerTokenResetPassword, time.Now().Add(time.Hour))
+	createToken("current", models.CustomerTokenResetPassword, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		wantErr   bool
+	}{
+		{name: "verify_email token", tokenHash: "hash-verify", wantErr: true},
+		{name: "expired token", tokenHash: "hash-expired", wantErr: true},
+		{name: "replaced token", tokenHash: "hash-replaced", wantErr: true},
+		{name: "unknown token", tokenHash: "hash-unknown", wantErr: true},
+		{name: "current token", tokenHash: "hash-current"},
+		{name: "used token", tokenHash: "hash-current", wantErr: true},
+	}
+	for _, tt := range tests {
+		token, err := customerTokenRepository.TransactionResetPassword(tt.tokenHash, "new-password-hash")
+		if tt.wantErr {
+			if err != ErrCustomerTokenInvalid {
+				t.Errorf("TransactionResetPassword() of the %s = %v, want ErrCustomerTokenInvalid", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("TransactionResetPassword() of the %s: %v", tt.name, err)
+		}
+		if token.CustomerID != "customer-0" {
+			t.Errorf("the %s belongs to %s, want customer-0", tt.name, token.CustomerID)
+		}
+	}
+
+	var customer models.Customer
+	if err := db.Where("id = ?", "customer-0").First(&customer).Error; err != nil {
+		t.Fatal(err)
+	}
+	if customer.Password != "new-password-hash" {
+		t.Errorf("password is %q, want the new hash", customer.Password)
+	}
+	if customer.EmailVerifiedAt == nil {
+		t.Error("the email is not verified by the reset")
+	}
+	if customer.TokenVersion != 1 {
+		t.Errorf("token version is %d, want 1 after the reset", customer.TokenVersion)
+	}
+	if customer.FailedLoginAttempts != 0 || customer.LockedUntil != nil {
+		t.Errorf("lockout is %d failures until %v, want lifted", customer.FailedLoginAttempts, customer.LockedUntil)
+	}
+}
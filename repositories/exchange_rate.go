@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"mvp-shop-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type exchangeRateRepository struct {
+	db *gorm.DB
+}
+
+type ExchangeRateRepositoryInterface interface {
+	GetExchangeRates() ([]models.ExchangeRate, error)
+	SaveExchangeRate(rate *models.ExchangeRate) error
+	DeleteExchangeRate(currency string) error
+}
+
+func NewExchangeRateRepository(db *gorm.DB) ExchangeRateRepositoryInterface {
+	return &exchangeRateRepository{
+		db: db,
+	}
+}
+
+func (er *exchangeRateRepository) GetExchangeRates() (rates []models.ExchangeRate, err error) {
+	return rates, er.db.Order("currency").Find(&rates).Error
+}
+
+// SaveExchangeRate creates the rate of rate.Currency or replaces it, the creator is kept on replace
+func (er *exchangeRateRepository) SaveExchangeRate(rate *models.ExchangeRate) error {
+	return er.db.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "currency"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"rate":       rate.Rate,
+				"updated_at": gorm.Expr("now()"),
+				"updated_by": rate.CreatedBy,
+			}),
+		}).
+		Create(rate).Error
+}
+
+// DeleteExchangeRate removes the rate of currency, gorm.ErrRecordNotFound is returned when there is none
+func (er *exchangeRateRepository) DeleteExchangeRate(currency string) error {
+	result := er.db.Where("currency = ?", currency).Delete(&models.ExchangeRate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
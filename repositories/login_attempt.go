@@ -0,0 +1,295 @@
+package repositories
+
+import (
+	"fmt"
+	"mvp-shop-backend/models"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// LoginKeyEmail prefixes the login attempt key of an account
+	LoginKeyEmail = "email:"
+
+	// LoginKeyIP prefixes the login attempt key of a client ip address
+	LoginKeyIP = "ip:"
+)
+
+// LoginLockout locks a key once it exceeds MaxFailures consecutive failures, for Base doubled on
+// every further failure up to Max. Counting restarts after Max without any failure.
+type LoginLockout struct {
+	MaxFailures int
+	Base        time.Duration
+	Max         time.Duration
+}
+
+// LockFor returns how long a key is locked after its nth consecutive failure, zero when it is not locked
+func (ll LoginLockout) LockFor(failures int) time.Duration {
+	if failures <= ll.MaxFailures {
+		return 0
+	}
+
+	d := ll.Base
+	for i := ll.MaxFailures + 1; i < failures && d < ll.Max; i++ {
+		d *= 2
+	}
+	if d > ll.Max {
+		d = ll.Max
+	}
+	return d
+}
+
+type LoginAttemptRepositoryInterface interface {
+	GetLoginAttempt(key string) (models.LoginAttempt, error)
+	FailLoginAttempt(key string, lockout LoginLockout) (models.LoginAttempt, error)
+	ResetLoginAttempt(key string) error
+}
+
+// NewLoginAttemptRepository returns the store selected by LOGIN_ATTEMPT_STORE, memory by default
+func NewLoginAttemptRepository(db *gorm.DB, store string) (LoginAttemptRepositoryInterface, error) {
+	switch store {
+	case "", "memory":
+		return NewMemoryLoginAttemptRepository(), nil
+	case "postgres":
+		return NewPostgresLoginAttemptRepository(db), nil
+	default:
+		return nil, fmt.Errorf("unknown login attempt store %q", store)
+	}
+}
+
+// memoryLoginAttemptSweep is how often the memory store drops its expired counters
+const memoryLoginAttemptSweep = time.Minute
+
+type memoryLoginAttempt struct {
+	attempt   models.LoginAttempt
+	expiresAt time.Time
+}
+
+type memoryLoginAttemptRepository struct {
+	mu        sync.Mutex
+	attempts  map[string]memoryLoginAttempt
+	nextSweep time.Time
+}
+
+// NewMemoryLoginAttemptRepository keeps the counters in process, they are lost on restart and not shared between instances.
+// A counter expires once it is neither locked nor counting anymore.
+func NewMemoryLoginAttemptRepository() LoginAttemptRepositoryInterface {
+	return &memoryLoginAttemptRepository{
+		attempts: make(map[string]memoryLoginAttempt),
+	}
+}
+
+func (mr *memoryLoginAttemptRepository) GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	entry, ok := mr.attempts[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return models.LoginAttempt{Key: key}, nil
+	}
+	return entry.attempt, nil
+}
+
+func (mr *memoryLoginAttemptRepository) FailLoginAttempt(key string, lockout LoginLockout) (models.LoginAttempt, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	now := time.Now()
+	mr.sweep(now)
+
+	attempt := mr.attempts[key].attempt
+	attempt.Key = key
+	fail(&attempt, lockout)
+	mr.attempts[key] = memoryLoginAttempt{
+		attempt:   attempt,
+		expiresAt: attemptExpiry(attempt, lockout),
+	}
+	return attempt, nil
+}
+
+func (mr *memoryLoginAttemptRepository) ResetLoginAttempt(key string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	delete(mr.attempts, key)
+	return nil
+}
+
+// sweep drops the expired counters, at most once every memoryLoginAttemptSweep
+func (mr *memoryLoginAttemptRepository) sweep(now time.Time) {
+	if now.Before(mr.nextSweep) {
+		return
+	}
+	for key, entry := range mr.attempts {
+		if now.After(entry.expiresAt) {
+			delete(mr.attempts, key)
+		}
+	}
+	mr.nextSweep = now.Add(memoryLoginAttemptSweep)
+}
+
+type postgresLoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresLoginAttemptRepository keeps the counters of existing accounts on the customers row
+// and every other key (ip addresses, unknown emails) in login_attempts, the expired rows are purged on the way
+func NewPostgresLoginAttemptRepository(db *gorm.DB) LoginAttemptRepositoryInterface {
+	return &postgresLoginAttemptRepository{
+		db: db,
+	}
+}
+
+func (pr *postgresLoginAttemptRepository) GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	if customer, ok, err := pr.getCustomer(pr.db, key, false); err != nil || ok {
+		return customerLoginAttempt(key, customer), err
+	}
+
+	attempt := models.LoginAttempt{Key: key}
+	err := pr.db.Where(&models.LoginAttempt{Key: key}).Find(&attempt).Error
+	return attempt, err
+}
+
+func (pr *postgresLoginAttemptRepository) FailLoginAttempt(key string, lockout LoginLockout) (models.LoginAttempt, error) {
+	tx := pr.db.Begin()
+	defer tx.Rollback()
+
+	customer, ok, err := pr.getCustomer(tx, key, true)
+	if err != nil {
+		return models.LoginAttempt{Key: key}, err
+	}
+
+	var attempt models.LoginAttempt
+	if ok {
+		attempt = customerLoginAttempt(key, customer)
+		fail(&attempt, lockout)
+		err = tx.Model(&models.Customer{ID: customer.ID}).
+			Updates(
+				map[string]interface{}{
+					"failed_login_attempts": attempt.Failures,
+					"locked_until":          attempt.LockedUntil,
+					"last_failed_login_at":  attempt.UpdatedAt,
+				},
+			).Error
+	} else {
+		// make sure the row exists so it can be locked
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginAttempt{Key: key}).Error
+		if err == nil {
+			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&models.LoginAttempt{Key: key}).First(&attempt).Error
+		}
+		if err == nil {
+			fail(&attempt, lockout)
+			err = tx.Model(&models.LoginAttempt{Key: key}).
+				Updates(
+					map[string]interface{}{
+						"failures":     attempt.Failures,
+						"locked_until": attempt.LockedUntil,
+						"updated_at":   gorm.Expr("now()"),
+					},
+				).Error
+		}
+	}
+	if err != nil {
+		return attempt, fmt.Errorf("error updating login attempt, %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return attempt, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	if err := pr.purge(key, lockout); err != nil {
+		return attempt, fmt.Errorf("error purging login attempts, %v", err)
+	}
+
+	return attempt, nil
+}
+
+// purge deletes the rows of the keys of the kind of key which are neither locked nor counting anymore, rows locked by
+// a concurrent failure are left to the next purge
+func (pr *postgresLoginAttemptRepository) purge(key string, lockout LoginLockout) error {
+	expired := pr.db.Model(&models.LoginAttempt{}).
+		Select(`"key"`).
+		Where(`"key" like ?`, loginKeyKind(key)+"%").
+		Where("updated_at < ? and (locked_until is null or locked_until < now())", time.Now().Add(-lockout.Max)).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	return pr.db.Where(`"key" in (?)`, expired).Delete(&models.LoginAttempt{}).Error
+}
+
+func (pr *postgresLoginAttemptRepository) ResetLoginAttempt(key string) error {
+	if strings.HasPrefix(key, LoginKeyEmail) {
+		if err := pr.db.Model(&models.Customer{}).
+			Where("email = ?", strings.TrimPrefix(key, LoginKeyEmail)).
+			Updates(
+				map[string]interface{}{
+					"failed_login_attempts": 0,
+					"locked_until":          nil,
+					"last_failed_login_at":  nil,
+				},
+			).Error; err != nil {
+			return err
+		}
+	}
+
+	return pr.db.Where(&models.LoginAttempt{Key: key}).Delete(&models.LoginAttempt{}).Error
+}
+
+// getCustomer returns the customer of an email key, ok is false for ip keys and unknown emails
+func (pr *postgresLoginAttemptRepository) getCustomer(db *gorm.DB, key string, lock bool) (customer models.Customer, ok bool, err error) {
+	if !strings.HasPrefix(key, LoginKeyEmail) {
+		return customer, false, nil
+	}
+
+	queryBuilder := db.Where(&models.Customer{Email: strings.TrimPrefix(key, LoginKeyEmail)})
+	if lock {
+		queryBuilder = queryBuilder.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	result := queryBuilder.Select("id", "failed_login_attempts", "locked_until", "last_failed_login_at").Limit(1).Find(&customer)
+	return customer, result.RowsAffected > 0, result.Error
+}
+
+func customerLoginAttempt(key string, customer models.Customer) models.LoginAttempt {
+	attempt := models.LoginAttempt{
+		Key:         key,
+		Failures:    customer.FailedLoginAttempts,
+		LockedUntil: customer.LockedUntil,
+	}
+	if customer.LastFailedLoginAt != nil {
+		attempt.UpdatedAt = *customer.LastFailedLoginAt
+	}
+	return attempt
+}
+
+// loginKeyKind returns the prefix of key, keys of a kind share their lockout
+func loginKeyKind(key string) string {
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[:i+1]
+	}
+	return key
+}
+
+// attemptExpiry returns when attempt stops mattering, once it is unlocked and its failures are no longer counted
+func attemptExpiry(attempt models.LoginAttempt, lockout LoginLockout) time.Time {
+	expiresAt := attempt.UpdatedAt.Add(lockout.Max)
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(expiresAt) {
+		expiresAt = *attempt.LockedUntil
+	}
+	return expiresAt
+}
+
+func fail(attempt *models.LoginAttempt, lockout LoginLockout) {
+	now := time.Now()
+	if !attempt.IsLocked(now) && !attempt.UpdatedAt.IsZero() && now.Sub(attempt.UpdatedAt) > lockout.Max {
+		attempt.Failures = 0
+	}
+
+	attempt.Failures++
+	attempt.UpdatedAt = now
+	if d := lockout.LockFor(attempt.Failures); d > 0 {
+		lockedUntil := now.Add(d)
+		attempt.LockedUntil = &lockedUntil
+	}
+}
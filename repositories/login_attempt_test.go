@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"mvp-shop-backend/models"
+	"testing"
+	"time"
+)
+
+func TestMemoryLoginAttemptExpiry(t *testing.T) {
+	lockout := LoginLockout{MaxFailures: 5, Base: time.Millisecond, Max: 10 * time.Millisecond}
+	mr := NewMemoryLoginAttemptRepository().(*memoryLoginAttemptRepository)
+
+	if _, err := mr.FailLoginAttempt("ip:1", lockout); err != nil {
+		t.Fatal(err)
+	}
+	attempt, _ := mr.GetLoginAttempt("ip:1")
+	if attempt.Failures != 1 {
+		t.Fatalf("failures = %d, want 1", attempt.Failures)
+	}
+
+	time.Sleep(2 * lockout.Max)
+	attempt, _ = mr.GetLoginAttempt("ip:1")
+	if attempt.Failures != 0 {
+		t.Errorf("failures of an expired counter = %d, want 0", attempt.Failures)
+	}
+
+	mr.nextSweep = time.Time{}
+	if _, err := mr.FailLoginAttempt("ip:2", lockout); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mr.attempts["ip:1"]; ok {
+		t.Error("the expired counter was not swept")
+	}
+	if len(mr.attempts) != 1 {
+		t.Errorf("%d counters kept, want 1", len(mr.attempts))
+	}
+}
+
+func TestCustomerLoginAttemptWindow(t *testing.T) {
+	lockout := LoginLockout{MaxFailures: 5, Base: time.Minute, Max: time.Hour}
+	lastFailure := time.Now().Add(-2 * lockout.Max)
+
+	attempt := customerLoginAttempt("email:customer@example.com", models.Customer{
+		FailedLoginAttempts: 4,
+		LastFailedLoginAt:   &lastFailure,
+	})
+	fail(&attempt, lockout)
+	if attempt.Failures != 1 {
+		t.Errorf("failures after the window = %d, want 1", attempt.Failures)
+	}
+
+	recent := time.Now().Add(-time.Minute)
+	attempt = customerLoginAttempt("email:customer@example.com", models.Customer{
+		FailedLoginAttempts: 4,
+		LastFailedLoginAt:   &recent,
+	})
+	fail(&attempt, lockout)
+	if attempt.Failures != 5 {
+		t.Errorf("failures inside the window = %d, want 5", attempt.Failures)
+	}
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"mvp-shop-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type loginAuditRepository struct {
+	db *gorm.DB
+}
+
+type LoginAuditRepositoryInterface interface {
+	CreateLoginAudit(audit *models.LoginAudit) error
+}
+
+func NewLoginAuditRepository(db *gorm.DB) LoginAuditRepositoryInterface {
+	return &loginAuditRepository{
+		db: db,
+	}
+}
+
+func (lr *loginAuditRepository) CreateLoginAudit(audit *models.LoginAudit) error {
+	return lr.db.Create(audit).Error
+}
@@ -1,20 +1,70 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+	"mvp-shop-backend/pkg/shipping"
 	"mvp-shop-backend/pkg/utils"
-	"sync"
+	"sort"
+	"strings"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	// ErrCartEmpty is returned by TransactionCheckout when the customer has no cart line to checkout
+	ErrCartEmpty = errors.New("cart is empty")
+
+	// ErrVariantRequired is returned when a product with variants is ordered without variant
+	ErrVariantRequired = errors.New("product variant required")
+
+	// ErrVariantNotFound is returned when an ordered variant does not exist or is a variant of another product
+	ErrVariantNotFound = errors.New("product variant not found")
+
+	// ErrPaymentNotRefundable is returned by TransactionCancelOrder when a paid order has no succeeded payment to refund
+	ErrPaymentNotRefundable = errors.New("order payment cannot be refunded")
+)
+
+// InsufficientStockError is returned when the stock of one or more products is lower than the ordered qty
+type InsufficientStockError struct {
+	Products []models.InsufficientStock
+}
+
+func (e *InsufficientStockError) Error() string {
+	ids := make([]string, len(e.Products))
+	for i, v := range e.Products {
+		ids[i] = v.ProductID
+	}
+	return fmt.Sprintf("insufficient stock for product %s", strings.Join(ids, ", "))
+}
+
+// InvalidTransitionError is returned by TransitionOrder when the order status cannot be moved to the requested status
+type InvalidTransitionError struct {
+	From models.OrderStatus
+	To   models.OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("order cannot be moved from %s to %s", e.From, e.To)
+}
+
 type orderRepository struct {
 	db *gorm.DB
 }
 
 type OrderRepositoryInterface interface {
-	TransactionOrder(order *models.Order, orderDetail *[]models.OrderDetail, wg *sync.WaitGroup, mu *sync.Mutex) error
+	TransactionOrder(order *models.Order, orderDetail *[]models.OrderDetail, calculator shipping.ShippingRateCalculator) error
+	TransactionCheckout(order *models.Order, calculator shipping.ShippingRateCalculator) (orderDetail []models.OrderDetail, err error)
+	GetMyOrders(pagination utils.Pagination, where map[string]string, userId string) ([]models.Order, int64, error)
+	GetMyOrderByInvoice(invoice string, userId string) (models.Order, error)
+	GetMyOrderDetails(invoice string) ([]models.OrderDetailView, error)
+	GetOrderStatusHistory(invoice string) ([]models.OrderStatusHistory, error)
+	TransitionOrder(invoice string, customerID string, to models.OrderStatus, updatedBy string) (models.Order, error)
+	TransactionCancelOrder(invoice string, customerID string, updatedBy string) (models.Order, *models.Payment, error)
 }
 
 func NewOrderRepository(db *gorm.DB) OrderRepositoryInterface {
@@ -23,32 +73,274 @@ func NewOrderRepository(db *gorm.DB) OrderRepositoryInterface {
 	}
 }
 
-func (or *orderRepository) TransactionOrder(order *models.Order, orderDetail *[]models.OrderDetail, wg *sync.WaitGroup, mu *sync.Mutex) error {
-	defer wg.Done()
+// lockProducts selects the ordered products and variants FOR UPDATE and checks the stock of the variants, or of the
+// products ordered without variant, against the total ordered qty. Products are locked by id order then variants by id
+// order so concurrent orders on the same products cannot deadlock.
+func lockProducts(tx *gorm.DB, orderDetail []models.OrderDetail) (map[string]models.Product, map[string]models.ProductVariant, error) {
+	qty := make(map[string]float64)
+	variantQty := make(map[string]float64)
+	productIDs := make(map[string]bool)
+	for _, v := range orderDetail {
+		productIDs[v.ProductID] = true
+		if v.VariantID != nil {
+			variantQty[*v.VariantID] += v.Qty
+		} else {
+			qty[v.ProductID] += v.Qty
+		}
+	}
 
-	// Acquire a lock to ensure safe concurrent access
-	mu.Lock()
-	defer mu.Unlock()
+	ids := make([]string, 0, len(productIDs))
+	for id := range productIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
 
-	// Begin a transaction
-	tx := or.db.Begin()
-	defer tx.Rollback()
+	var products []models.Product
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id in ? and status <> ?", ids, models.StatusDeleted).
+		Order("id").
+		Find(&products).Error; err != nil {
+		return nil, nil, fmt.Errorf("error getting product, %v", err)
+	}
+	if len(products) != len(ids) {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	variantIDs := make([]string, 0, len(variantQty))
+	for id := range variantQty {
+		variantIDs = append(variantIDs, id)
+	}
+	sort.Strings(variantIDs)
+
+	var variants []models.ProductVariant
+	if len(variantIDs) > 0 {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id in ? and status <> ?", variantIDs, models.StatusDeleted).
+			Order("id").
+			Find(&variants).Error; err != nil {
+			return nil, nil, fmt.Errorf("error getting product variant, %v", err)
+		}
+	}
+	variantMap := make(map[string]models.ProductVariant, len(variants))
+	for _, variant := range variants {
+		variantMap[variant.ID] = variant
+	}
+	for _, v := range orderDetail {
+		if v.VariantID != nil && variantMap[*v.VariantID].ProductID != v.ProductID {
+			return nil, nil, ErrVariantNotFound
+		}
+	}
+
+	// products with variants are sold by variant only
+	withoutVariant := make([]string, 0, len(qty))
+	for id := range qty {
+		withoutVariant = append(withoutVariant, id)
+	}
+	if len(withoutVariant) > 0 {
+		var count int64
+		if err := tx.Model(&models.ProductVariant{}).
+			Where("product_id in ? and status <> ?", withoutVariant, models.StatusDeleted).
+			Count(&count).Error; err != nil {
+			return nil, nil, fmt.Errorf("error getting product variant, %v", err)
+		}
+		if count > 0 {
+			return nil, nil, ErrVariantRequired
+		}
+	}
+
+	productMap := make(map[string]models.Product, len(products))
+	var insufficient []models.InsufficientStock
+	for _, product := range products {
+		productMap[product.ID] = product
+		if ordered, ok := qty[product.ID]; ok && product.Stock < ordered {
+			insufficient = append(insufficient, models.InsufficientStock{
+				ProductID: product.ID,
+				Name:      product.Name,
+				Qty:       ordered,
+				Stock:     product.Stock,
+			})
+		}
+	}
+	for _, variant := range variants {
+		if variant.Stock < variantQty[variant.ID] {
+			insufficient = append(insufficient, models.InsufficientStock{
+				ProductID: variant.ProductID,
+				VariantID: variant.ID,
+				Name:      productMap[variant.ProductID].Name,
+				Qty:       variantQty[variant.ID],
+				Stock:     variant.Stock,
+			})
+		}
+	}
+	if len(insufficient) > 0 {
+		return nil, nil, &InsufficientStockError{Products: insufficient}
+	}
+
+	return productMap, variantMap, nil
+}
+
+// updateStock adds qty to the stock of the variant of v, or of its product when it has no variant
+func updateStock(tx *gorm.DB, v models.OrderDetail, qty float64) error {
+	if v.VariantID != nil {
+		return tx.Model(&models.ProductVariant{}).Where("id = ?", *v.VariantID).Update("stock", gorm.Expr("stock + ?", qty)).Error
+	}
+	return tx.Model(&models.Product{}).Where(&models.Product{ID: v.ProductID}).Updates(map[string]interface{}{"stock": gorm.Expr("stock + ?", qty)}).Error
+}
+
+// createOrder prices orderDetail from the locked products and variants in order.Currency and snapshots its exchange
+// rate, discounts the lines with the promotion of order.CouponCode, taxes them with the rules of order.Region and
+// charges the shipping fee of calculator for their weight, then inserts the order with its details, redeems the
+// promotion and decrements the stock. A nil calculator charges no shipping fee.
+func createOrder(tx *gorm.DB, order *models.Order, orderDetail []models.OrderDetail, calculator shipping.ShippingRateCalculator) error {
+	products, variants, err := lockProducts(tx, orderDetail)
+	if err != nil {
+		return err
+	}
+
+	if order.Currency == "" {
+		order.Currency = money.DefaultCurrency()
+	}
+	productIDs := make([]string, 0, len(products))
+	for id := range products {
+		productIDs = append(productIDs, id)
+	}
+	priceList, err := getPriceList(tx, order.Currency, productIDs)
+	if err != nil {
+		return err
+	}
+	order.ExchangeRate = priceList.Rate
+
+	region := ""
+	if order.Region != nil {
+		region = *order.Region
+	}
+	taxTable, err := getTaxTable(tx, region)
+	if err != nil {
+		return err
+	}
+
+	weight := 0.0
+	lines := make([]models.PromotionLine, len(orderDetail))
+	for i, v := range orderDetail {
+		weight += products[v.ProductID].Weight * v.Qty
+		orderDetail[i].ID = uuid.New().String()
+		orderDetail[i].Invoice = order.Invoice
+		var override *money.Money
+		if v.VariantID != nil {
+			override = variants[*v.VariantID].Price
+		}
+		orderDetail[i].Price, err = priceList.VariantPrice(v.ProductID, products[v.ProductID].Price, override)
+		if err != nil {
+			return fmt.Errorf("error pricing product %s, %w", v.ProductID, err)
+		}
+		orderDetail[i].Amount, err = orderDetail[i].Price.MulQty(v.Qty)
+		if err != nil {
+			return fmt.Errorf("error pricing product %s, %w", v.ProductID, err)
+		}
+		orderDetail[i].DiscountAmount = money.Zero(order.Currency)
+		orderDetail[i].Status = models.StatusActive
+		lines[i] = models.PromotionLine{
+			ProductID:  v.ProductID,
+			CategoryID: products[v.ProductID].CategoryID,
+			Qty:        v.Qty,
+			Price:      orderDetail[i].Price,
+			Amount:     orderDetail[i].Amount,
+		}
+	}
+
+	var promotion models.Promotion
+	if order.CouponCode != nil {
+		if promotion, err = lockPromotion(tx, *order.CouponCode, order.CustomerID); err != nil {
+			return err
+		}
+		order.CouponCode = &promotion.Code
+		discounts, err := promotion.Discounts(lines, priceList.Rate)
+		if err != nil {
+			return err
+		}
+		for i := range orderDetail {
+			orderDetail[i].DiscountAmount = discounts[i]
+		}
+	}
+
+	// every line, its discount and its tax are rounded to the currency minor unit, the totals are the sums of the
+	// rounded lines
+	order.Totals = models.NewTotals(order.Currency)
+	for i, v := range orderDetail {
+		taxable, err := v.Amount.Sub(v.DiscountAmount)
+		if err != nil {
+			return fmt.Errorf("error pricing product %s, %w", v.ProductID, err)
+		}
+		orderDetail[i].LineTax, err = taxTable.LineTax(products[v.ProductID].CategoryID, taxable)
+		if err != nil {
+			return fmt.Errorf("error taxing product %s, %w", v.ProductID, err)
+		}
+		if err = order.Totals.AddLine(v.Amount, v.DiscountAmount, orderDetail[i].LineTax); err != nil {
+			return fmt.Errorf("error pricing order, %w", err)
+		}
+	}
+	if calculator != nil {
+		fee, err := calculator.Rate(shipping.Request{Region: order.ShippingAddress.Region, Weight: weight})
+		if err != nil {
+			return err
+		}
+		if order.Totals.ShippingAmount, err = priceList.Rate.Convert(fee, order.Currency); err != nil {
+			return fmt.Errorf("error pricing shipping, %w", err)
+		}
+	}
+	if order.Amount, err = order.Totals.GrandTotal(); err != nil {
+		return fmt.Errorf("error pricing order, %w", err)
+	}
 
-	// Perform database operations within the transaction (use 'tx' from this point)
 	if err := tx.Create(order).Error; err != nil {
-		return fmt.Errorf("error creating order, %v", err)
+		return fmt.Errorf("error creating order, %w", err)
 	}
 
-	if err := tx.Create(orderDetail).Error; err != nil {
-		return fmt.Errorf("error creating order detail, %v", err)
+	if err := tx.Create(&orderDetail).Error; err != nil {
+		return fmt.Errorf("error creating order detail, %w", err)
 	}
 
-	for _, v := range *orderDetail {
-		if err := tx.Model(&models.Product{}).Where(&models.Product{ID: v.ProductID}).Updates(map[string]interface{}{"stock": gorm.Expr("stock - ?", v.Qty)}).Error; err != nil {
+	if order.CouponCode != nil {
+		if err := redeemPromotion(tx, promotion, order); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range orderDetail {
+		if err := updateStock(tx, v, -v.Qty); err != nil {
 			return fmt.Errorf("error updating stock product, %v", err)
 		}
 	}
 
+	return createOrderStatusHistory(tx, order.Invoice, "", order.OrderStatus, order.CreatedBy)
+}
+
+func createOrderStatusHistory(tx *gorm.DB, invoice string, from models.OrderStatus, to models.OrderStatus, createdBy string) error {
+	history := models.OrderStatusHistory{
+		ID:         uuid.New().String(),
+		Invoice:    invoice,
+		FromStatus: from,
+		ToStatus:   to,
+		CreatedBy:  createdBy,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return fmt.Errorf("error creating order status history, %v", err)
+	}
+	return nil
+}
+
+func (or *orderRepository) TransactionOrder(order *models.Order, orderDetail *[]models.OrderDetail, calculator shipping.ShippingRateCalculator) error {
+	// Begin a transaction
+	tx := or.db.Begin()
+	defer tx.Rollback()
+
+	// Perform database operations within the transaction (use 'tx' from this point)
+	if err := createOrder(tx, order, *orderDetail, calculator); err != nil {
+		return err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		return fmt.Errorf("error committing transaction, %v", err)
@@ -57,6 +349,74 @@ func (or *orderRepository) TransactionOrder(order *models.Order, orderDetail *[]
 	return nil
 }
 
+// TransactionCheckout turns the active cart lines of order.CustomerID into order, pricing them from the current product price
+// and discounting them with the coupon applied to the cart. The cart is only marked as checkout when the whole transaction commits.
+func (or *orderRepository) TransactionCheckout(order *models.Order, calculator shipping.ShippingRateCalculator) (orderDetail []models.OrderDetail, err error) {
+	tx := or.db.Begin()
+	defer tx.Rollback()
+
+	var carts []models.Cart
+	if err := tx.
+		Where("customer_id = ? and status not in ?", order.CustomerID, []models.Status{models.StatusDeleted, models.StatusCheckout}).
+		Order("created_at").
+		Find(&carts).Error; err != nil {
+		return nil, fmt.Errorf("error getting cart, %v", err)
+	}
+	if len(carts) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	cartIds := make([]string, len(carts))
+	for i, cart := range carts {
+		orderDetail = append(orderDetail, models.OrderDetail{
+			ProductID: cart.ProductID,
+			VariantID: cart.VariantID,
+			Qty:       cart.Qty,
+			CreatedBy: order.CreatedBy,
+		})
+		cartIds[i] = cart.ID
+	}
+
+	if order.CouponCode == nil {
+		var codes []string
+		if err := tx.Table("cart_coupons").Select("promotions.code").
+			Joins("join promotions on promotions.id = cart_coupons.promotion_id").
+			Where("cart_coupons.customer_id = ?", order.CustomerID).
+			Scan(&codes).Error; err != nil {
+			return nil, fmt.Errorf("error getting cart coupon, %v", err)
+		}
+		if len(codes) > 0 {
+			order.CouponCode = &codes[0]
+		}
+	}
+
+	if err := createOrder(tx, order, orderDetail, calculator); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("customer_id = ?", order.CustomerID).Delete(&models.CartCoupon{}).Error; err != nil {
+		return nil, fmt.Errorf("error deleting cart coupon, %v", err)
+	}
+
+	if err := tx.Model(&models.Cart{}).
+		Where("id in ?", cartIds).
+		Updates(
+			map[string]interface{}{
+				"status":     models.StatusCheckout.String(),
+				"updated_at": gorm.Expr("now()"),
+				"updated_by": order.CreatedBy,
+			},
+		).Error; err != nil {
+		return nil, fmt.Errorf("error updating cart, %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return orderDetail, nil
+}
+
 func (or *orderRepository) GetMyOrders(pagination utils.Pagination, where map[string]string, userId string) ([]models.Order, int64, error) {
 	var count int64
 	var err error
@@ -65,15 +425,15 @@ func (or *orderRepository) GetMyOrders(pagination utils.Pagination, where map[st
 
 	queryBuilder := or.db.Model(&models.Order{}).Where("customer_id = ?", userId)
 
-	if id, ok := where["id"]; ok && id != "" {
-		queryBuilder = queryBuilder.Where("id = ?", id)
-	}
-
 	if invoice, ok := where["invoice"]; ok && invoice != "" {
 		invoice := fmt.Sprintf("%%%s%%", invoice)
 		queryBuilder = queryBuilder.Where(`"invoice" ILIKE ?`, invoice)
 	}
 
+	if orderStatus, ok := where["order_status"]; ok && orderStatus != "" {
+		queryBuilder = queryBuilder.Where("order_status = ?", orderStatus)
+	}
+
 	if pagination.SortField != "" {
 		if pagination.SortField == "invoice" {
 			sortField = `INITCAP("invoice")`
@@ -107,13 +467,157 @@ func (or *orderRepository) GetMyOrders(pagination utils.Pagination, where map[st
 	return orders, count, nil
 }
 
-func (or *orderRepository) GetMyOrderDetails(invoice string) ([]models.OrderDetail, error) {
-	var orderDetail []models.OrderDetail
+func (or *orderRepository) GetMyOrderByInvoice(invoice string, userId string) (models.Order, error) {
+	var order models.Order
+	if err := or.db.Where(&models.Order{Invoice: invoice, CustomerID: userId}).First(&order).Error; err != nil {
+		return order, err
+	}
+	return order, nil
+}
 
-	result := or.db.Model(&models.OrderDetail{}).Where(&models.OrderDetail{Invoice: invoice}).Find(&orderDetail)
+func (or *orderRepository) GetMyOrderDetails(invoice string) ([]models.OrderDetailView, error) {
+	var orderDetail []models.OrderDetailView
+
+	result := or.db.
+		Table("order_details").Select("order_details.*, products.name as name, product_variants.sku as sku, product_variants.options as options").
+		Joins("left join products on order_details.product_id = products.id").
+		Joins("left join product_variants on order_details.variant_id = product_variants.id").
+		Where("order_details.invoice = ?", invoice).
+		Order("order_details.created_at").
+		Scan(&orderDetail)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
 	return orderDetail, nil
 }
+
+func (or *orderRepository) GetOrderStatusHistory(invoice string) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+
+	result := or.db.Where(&models.OrderStatusHistory{Invoice: invoice}).Order("created_at").Find(&history)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return history, nil
+}
+
+// TransitionOrder moves the order to status to and records it in order_status_history.
+// An empty customerID skips the ownership check. Cancelling an order restores the stock of its details and releases its
+// promotion in the same transaction.
+func (or *orderRepository) TransitionOrder(invoice string, customerID string, to models.OrderStatus, updatedBy string) (models.Order, error) {
+	tx := or.db.Begin()
+	defer tx.Rollback()
+
+	order, err := transitionOrder(tx, invoice, customerID, to, updatedBy)
+	if err != nil {
+		return order, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return order, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return order, nil
+}
+
+// TransactionCancelOrder cancels the order. The succeeded payment of a paid order is marked refund_pending in the same
+// transaction and returned, the caller refunds it through the provider once the cancellation is committed.
+func (or *orderRepository) TransactionCancelOrder(invoice string, customerID string, updatedBy string) (models.Order, *models.Payment, error) {
+	tx := or.db.Begin()
+	defer tx.Rollback()
+
+	order, err := transitionOrder(tx, invoice, customerID, models.OrderStatusCancelled, updatedBy)
+	if err != nil {
+		return order, nil, err
+	}
+
+	var refund *models.Payment
+	if order.Payment {
+		var payment models.Payment
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(&models.Payment{Invoice: order.Invoice, PaymentStatus: models.PaymentStatusSucceeded}).
+			First(&payment).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return order, nil, ErrPaymentNotRefundable
+			}
+			return order, nil, fmt.Errorf("error getting payment, %v", err)
+		}
+
+		if err := tx.Model(&models.Payment{ID: payment.ID}).
+			Updates(
+				map[string]interface{}{
+					"payment_status": models.PaymentStatusRefundPending.String(),
+					"updated_at":     gorm.Expr("now()"),
+					"updated_by":     updatedBy,
+				},
+			).Error; err != nil {
+			return order, nil, fmt.Errorf("error updating payment, %v", err)
+		}
+		payment.PaymentStatus = models.PaymentStatusRefundPending
+		refund = &payment
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return order, nil, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return order, refund, nil
+}
+
+func transitionOrder(tx *gorm.DB, invoice string, customerID string, to models.OrderStatus, updatedBy string) (models.Order, error) {
+	var order models.Order
+
+	queryBuilder := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("invoice = ?", invoice)
+	if customerID != "" {
+		queryBuilder = queryBuilder.Where("customer_id = ?", customerID)
+	}
+	if err := queryBuilder.First(&order).Error; err != nil {
+		return order, err
+	}
+
+	from := order.OrderStatus
+	if !from.CanTransitionTo(to) {
+		return order, &InvalidTransitionError{From: from, To: to}
+	}
+
+	updates := map[string]interface{}{
+		"order_status": to.String(),
+		"updated_at":   gorm.Expr("now()"),
+		"updated_by":   updatedBy,
+	}
+	if to == models.OrderStatusPaid {
+		updates["payment"] = true
+	}
+	if err := tx.Model(&models.Order{Invoice: order.Invoice}).Updates(updates).Error; err != nil {
+		return order, fmt.Errorf("error updating order, %v", err)
+	}
+
+	if to == models.OrderStatusCancelled {
+		if err := releasePromotion(tx, order.Invoice); err != nil {
+			return order, err
+		}
+
+		var orderDetail []models.OrderDetail
+		if err := tx.Where(&models.OrderDetail{Invoice: order.Invoice}).Find(&orderDetail).Error; err != nil {
+			return order, fmt.Errorf("error getting order detail, %v", err)
+		}
+		for _, v := range orderDetail {
+			if err := updateStock(tx, v, v.Qty); err != nil {
+				return order, fmt.Errorf("error restoring stock product, %v", err)
+			}
+		}
+	}
+
+	if err := createOrderStatusHistory(tx, order.Invoice, from, to, updatedBy); err != nil {
+		return order, err
+	}
+
+	order.OrderStatus = to
+	if to == models.OrderStatusPaid {
+		order.Payment = true
+	}
+	return order, nil
+}
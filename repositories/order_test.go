@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"sync"
+	"testing"
+)
+
+// TestTransactionOrderDoesNotOversell places more concurrent orders of one unit than the product has in stock, exactly
+// stock of them have to succeed and the others have to fail with an InsufficientStockError.
+func TestTransactionOrderDoesNotOversell(t *testing.T) {
+	db := openTestDB(t, "repositories_test_order")
+
+	const stock, orders = 5, 20
+	seedProduct(t, db, "product-1", stock)
+	seedCustomers(t, db, orders)
+
+	orderRepository := NewOrderRepository(db)
+
+	var wg sync.WaitGroup
+	errs := make([]error, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			order := models.Order{
+				Invoice:     fmt.Sprintf("INV/TEST/%d", i),
+				CustomerID:  fmt.Sprintf("customer-%d", i),
+				OrderStatus: models.OrderStatusPending,
+				Status:      models.StatusActive,
+				CreatedBy:   "test",
+			}
+			orderDetail := []models.OrderDetail{{ProductID: "product-1", Qty: 1, CreatedBy: "test"}}
+			errs[i] = orderRepository.TransactionOrder(&order, &orderDetail, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		var stockErr *InsufficientStockError
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &stockErr):
+		default:
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if succeeded != stock {
+		t.Errorf("%d orders succeeded, want %d", succeeded, stock)
+	}
+
+	var remaining float64
+	if err := db.Table("products").Select("stock").Where("id = ?", "product-1").Scan(&remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Errorf("stock is %v, want 0", remaining)
+	}
+
+	var count int64
+	if err := db.Model(&models.Order{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != stock {
+		t.Errorf("%d orders stored, want %d", count, stock)
+	}
+}
+
+// TestTransactionCancelOrderRefundPending cancels a paid order, its payment has to wait for the refund as
+// refund_pending and the stock has to be back
+func TestTransactionCancelOrderRefundPending(t *testing.T) {
+	db := openTestDB(t, "repositories_test_cancel")
+
+	seedProduct(t, db, "product-1", 1)
+	seedCustomers(t, db, 1)
+
+	orderRepository := NewOrderRepository(db)
+	paymentRepository := NewPaymentRepository(db)
+
+	order := models.Order{
+		Invoice:     "INV/TEST/1",
+		CustomerID:  "customer-0",
+		OrderStatus: models.OrderStatusPending,
+		Status:      models.StatusActive,
+		CreatedBy:   "test",
+	}
+	orderDetail := []models.OrderDetail{{ProductID: "product-1", Qty: 1, CreatedBy: "test"}}
+	if err := orderRepository.TransactionOrder(&order, &orderDetail, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := paymentRepository.CreatePayment(&models.Payment{
+		ID:            "payment-1",
+		Invoice:       order.Invoice,
+		CustomerID:    order.CustomerID,
+		Provider:      "fake",
+		IntentID:      "intent-1",
+		Amount:        order.Amount,
+		Currency:      order.Currency,
+		PaymentStatus: models.PaymentStatusPending,
+		Status:        models.StatusActive,
+		CreatedBy:     "test",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := paymentRepository.TransactionPaymentEvent("intent-1", models.PaymentStatusSucceeded, order.Amount, "fake"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, refund, err := orderRepository.TransactionCancelOrder(order.Invoice, order.CustomerID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.OrderStatus != models.OrderStatusCancelled {
+		t.Errorf("order is %s, want cancelled", cancelled.OrderStatus)
+	}
+	if refund == nil || refund.ID != "payment-1" || refund.PaymentStatus != models.PaymentStatusRefundPending {
+		t.Fatalf("refund = %+v, want payment-1 refund_pending", refund)
+	}
+
+	var stock float64
+	if err := db.Table("products").Select("stock").Where("id = ?", "product-1").Scan(&stock).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stock != 1 {
+		t.Errorf("stock is %v, want 1", stock)
+	}
+
+	// a replayed succeeded event does not undo the pending refund
+	if _, err := paymentRepository.TransactionPaymentEvent("intent-1", models.PaymentStatusSucceeded, order.Amount, "fake"); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := paymentRepository.GetRefundPendingPayments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("%d refund pending payments, want 1", len(pending))
+	}
+
+	if err := paymentRepository.CompleteRefund("payment-1", "fake"); err != nil {
+		t.Fatal(err)
+	}
+	payment, err := paymentRepository.GetPaymentById("payment-1", order.CustomerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.PaymentStatus != models.PaymentStatusRefunded {
+		t.Errorf("payment is %s, want refunded", payment.PaymentStatus)
+	}
+}
@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPaymentAmountMismatch is returned by TransactionPaymentEvent when the event amount or currency is not the payment one
+var ErrPaymentAmountMismatch = errors.New("event amount does not match the payment")
+
+type paymentRepository struct {
+	db *gorm.DB
+}
+
+type PaymentRepositoryInterface interface {
+	CreatePayment(payment *models.Payment) error
+	GetPaymentById(id string, customerID string) (models.Payment, error)
+	TransactionPaymentEvent(intentID string, paymentStatus models.PaymentStatus, amount money.Money, updatedBy string) (models.Payment, error)
+	GetRefundPendingPayments() ([]models.Payment, error)
+	CompleteRefund(id string, updatedBy string) error
+}
+
+func NewPaymentRepository(db *gorm.DB) PaymentRepositoryInterface {
+	return &paymentRepository{
+		db: db,
+	}
+}
+
+func (pr *paymentRepository) CreatePayment(payment *models.Payment) error {
+	return pr.db.Create(payment).Error
+}
+
+func (pr *paymentRepository) GetPaymentById(id string, customerID string) (models.Payment, error) {
+	var payment models.Payment
+	if err := pr.db.Where(&models.Payment{ID: id, CustomerID: customerID}).First(&payment).Error; err != nil {
+		return payment, err
+	}
+	return payment, nil
+}
+
+// TransactionPaymentEvent applies a verified provider event of amount to the payment of intentID.
+// A succeeded payment moves its order to paid in the same transaction, replayed events are ignored. Once a refund is
+// pending only the refunded event applies.
+func (pr *paymentRepository) TransactionPaymentEvent(intentID string, paymentStatus models.PaymentStatus, amount money.Money, updatedBy string) (models.Payment, error) {
+	var payment models.Payment
+
+	tx := pr.db.Begin()
+	defer tx.Rollback()
+
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(&models.Payment{IntentID: intentID}).
+		First(&payment).Error; err != nil {
+		return payment, err
+	}
+
+	if amount.Currency() != payment.Currency {
+		return payment, ErrPaymentAmountMismatch
+	}
+	if cmp, err := amount.Cmp(payment.Amount); err != nil || cmp != 0 {
+		return payment, ErrPaymentAmountMismatch
+	}
+
+	if payment.PaymentStatus == paymentStatus {
+		return payment, nil
+	}
+	if (payment.PaymentStatus == models.PaymentStatusRefundPending || payment.PaymentStatus == models.PaymentStatusRefunded) &&
+		paymentStatus != models.PaymentStatusRefunded {
+		return payment, nil
+	}
+
+	if err := tx.Model(&models.Payment{ID: payment.ID}).
+		Updates(
+			map[string]interface{}{
+				"payment_status": paymentStatus.String(),
+				"updated_at":     gorm.Expr("now()"),
+				"updated_by":     updatedBy,
+			},
+		).Error; err != nil {
+		return payment, fmt.Errorf("error updating payment, %v", err)
+	}
+
+	if paymentStatus == models.PaymentStatusSucceeded {
+		if _, err := transitionOrder(tx, payment.Invoice, "", models.OrderStatusPaid, updatedBy); err != nil {
+			return payment, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return payment, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	payment.PaymentStatus = paymentStatus
+	return payment, nil
+}
+
+// GetRefundPendingPayments returns the payments of cancelled orders the provider has not refunded yet
+func (pr *paymentRepository) GetRefundPendingPayments() ([]models.Payment, error) {
+	var payments []models.Payment
+	if err := pr.db.Where(&models.Payment{PaymentStatus: models.PaymentStatusRefundPending}).Order("updated_at").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// CompleteRefund marks the refund_pending payment id refunded, a payment the refund webhook already marked is left as is
+func (pr *paymentRepository) CompleteRefund(id string, updatedBy string) error {
+	return pr.db.Model(&models.Payment{}).
+		Where("id = ? and payment_status = ?", id, models.PaymentStatusRefundPending.String()).
+		Updates(
+			map[string]interface{}{
+				"payment_status": models.PaymentStatusRefunded.String(),
+				"updated_at":     gorm.Expr("now()"),
+				"updated_by":     updatedBy,
+			},
+		).Error
+}
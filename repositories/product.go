@@ -33,14 +33,31 @@ func (pr *productRepository) CreateProduct(product *models.Product) error {
 func (pr *productRepository) GetProducts(pagination utils.Pagination, where map[string]string) ([]models.ProductView, int64, error) {
 	var count int64
 	var err error
-	var sortField, sortDirection string
 	var products []models.ProductView
 
-	queryBuilder := pr.db.
+	queryBuilder := filterProducts(pr.db.
 		Table("products").Select("products.*, product_categories.name as category_name").
 		Joins("left join product_categories on products.category_id = product_categories.id").
-		Where("products.status <> ?", models.StatusDeleted)
+		Where("products.status <> ?", models.StatusDeleted), where)
+
+	err = queryBuilder.Count(&count).Error
+	if err != nil {
+		return nil, count, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	limitBuilder := queryBuilder.Limit(pagination.Limit).Offset(offset).Order(productOrder(pagination))
+
+	result := limitBuilder.Scan(&products)
+	if result.Error != nil {
+		return nil, count, result.Error
+	}
 
+	return products, count, nil
+}
+
+// filterProducts adds the search filters of the product list to queryBuilder
+func filterProducts(queryBuilder *gorm.DB, where map[string]string) *gorm.DB {
 	if id, ok := where["id"]; ok && id != "" {
 		queryBuilder = queryBuilder.Where(`products.id = ?`, id)
 	}
@@ -50,8 +67,9 @@ func (pr *productRepository) GetProducts(pagination utils.Pagination, where map[
 		queryBuilder = queryBuilder.Where(`products."name" ILIKE ?`, name)
 	}
 
+	// a category lists the products of its descendants too
 	if categoryId, ok := where["category_id"]; ok && categoryId != "" {
-		queryBuilder = queryBuilder.Where(`products.category_id = ?`, categoryId)
+		queryBuilder = queryBuilder.Where(`products.category_id IN (`+descendantCategories+`)`, categoryId)
 	}
 
 	if categoryName, ok := where["category_name"]; ok && categoryName != "" {
@@ -59,6 +77,28 @@ func (pr *productRepository) GetProducts(pagination utils.Pagination, where map[
 		queryBuilder = queryBuilder.Where(`product_categories."name" ILIKE ?`, categoryName)
 	}
 
+	if sku, ok := where["sku"]; ok && sku != "" {
+		queryBuilder = queryBuilder.Where(`products.sku = ?`, sku)
+	}
+
+	if slug, ok := where["slug"]; ok && slug != "" {
+		queryBuilder = queryBuilder.Where(`products.slug = ?`, slug)
+	}
+
+	// the other keys filter on the attributes, e.g. search=brand=acme
+	for name, value := range where {
+		if models.ProductFilters[name] || !models.IsAttributeName(name) {
+			continue
+		}
+		queryBuilder = queryBuilder.Where(`products."attributes" ->> ? = ?`, name, value)
+	}
+	return queryBuilder
+}
+
+// productOrder returns the ORDER BY of the product list
+func productOrder(pagination utils.Pagination) string {
+	var sortField, sortDirection string
+
 	if pagination.SortField != "" {
 		if pagination.SortField == "name" {
 			sortField = `INITCAP(products."name")`
@@ -79,21 +119,7 @@ func (pr *productRepository) GetProducts(pagination utils.Pagination, where map[
 		sortDirection = models.SortDirectionDESC.String()
 	}
 
-	err = queryBuilder.Count(&count).Error
-	if err != nil {
-		return nil, count, err
-	}
-
-	offset := (pagination.Page - 1) * pagination.Limit
-	orderBy := fmt.Sprintf("%s %s", sortField, sortDirection)
-	limitBuilder := queryBuilder.Limit(pagination.Limit).Offset(offset).Order(orderBy)
-
-	result := limitBuilder.Scan(&products)
-	if result.Error != nil {
-		return nil, count, result.Error
-	}
-
-	return products, count, nil
+	return fmt.Sprintf("%s %s", sortField, sortDirection)
 }
 
 func (pr *productRepository) GetProductById(id string) (models.ProductView, error) {
@@ -114,8 +140,16 @@ func (pr *productRepository) UpdateProduct(product *models.ProductUpdate) error
 		Updates(
 			map[string]interface{}{
 				"name":        product.Name,
+				"description": product.Description,
+				"sku":         nullString(product.SKU),
+				"slug":        product.Slug,
 				"price":       product.Price,
 				"stock":       product.Stock,
+				"weight":      product.Weight,
+				"length":      product.Length,
+				"width":       product.Width,
+				"height":      product.Height,
+				"attributes":  product.Attributes,
 				"category_id": product.CategoryID,
 				"status":      product.Status,
 				"updated_at":  gorm.Expr("now()"),
@@ -135,3 +169,11 @@ func (pr *productRepository) DeleteProduct(product *models.ProductUpdate) (err e
 			},
 		).Error
 }
+
+// nullString stores an empty s as NULL, unique columns allow many NULL but one empty string
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
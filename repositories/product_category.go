@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"errors"
 	"fmt"
 	"mvp-shop-backend/models"
 	"mvp-shop-backend/pkg/utils"
@@ -8,6 +9,37 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrParentCategoryNotFound is returned when the parent of a category does not exist or is deleted
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+
+	// ErrCategoryCycle is returned when the parent of a category is the category itself or one of its descendants
+	ErrCategoryCycle = errors.New("category cannot be a descendant of itself")
+
+	// ErrCategoryHasChildren is returned when a category to delete still has children
+	ErrCategoryHasChildren = errors.New("category has children")
+
+	// ErrCategoryHasProducts is returned when a category to delete still has products
+	ErrCategoryHasProducts = errors.New("category has products")
+)
+
+// descendantCategories selects the id of a category and of all its descendants, UNION stops on a cycle
+const descendantCategories = `WITH RECURSIVE descendants AS (
+	SELECT id FROM product_categories WHERE id = ?
+	UNION
+	SELECT product_categories.id FROM product_categories JOIN descendants ON product_categories.parent_id = descendants.id
+) SELECT id FROM descendants`
+
+// ancestorCategories selects the path of every category of a list from itself up to its root, depth 0 is the category
+// itself. The depth bound stops on a cycle.
+const ancestorCategories = `WITH RECURSIVE ancestors AS (
+	SELECT id AS category_id, id, "name", parent_id, 0 AS depth FROM product_categories WHERE id IN ?
+	UNION ALL
+	SELECT ancestors.category_id, product_categories.id, product_categories."name", product_categories.parent_id, ancestors.depth + 1
+	FROM product_categories JOIN ancestors ON product_categories.id = ancestors.parent_id
+	WHERE ancestors.depth < 100
+) SELECT category_id, id, "name", depth FROM ancestors ORDER BY category_id, depth DESC`
+
 type productCategoryRepository struct {
 	db *gorm.DB
 }
@@ -16,6 +48,8 @@ type ProductCategoryRepositoryInterface interface {
 	CreateProductCategory(productCategory *models.ProductCategory) error
 	GetProductCategories(pagination utils.Pagination, where map[string]string) ([]models.ProductCategory, int64, error)
 	GetProductCategoryById(id string) (models.ProductCategory, error)
+	GetProductCategoryTree() ([]models.ProductCategory, error)
+	GetCategoryPaths(ids []string) (map[string][]models.CategoryCrumb, error)
 	UpdateProductCategory(productCategory *models.ProductCategoryUpdate) error
 	DeleteProductCategory(productCategory *models.ProductCategoryUpdate) (err error)
 }
@@ -26,8 +60,54 @@ func NewProductCategoryRepository(db *gorm.DB) ProductCategoryRepositoryInterfac
 	}
 }
 
+// lockCategoryTree makes the transactions moving or deleting categories run one at a time, two moves checked
+// concurrently could make a cycle together
+func lockCategoryTree(tx *gorm.DB) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('product_categories'))").Error; err != nil {
+		return fmt.Errorf("error locking category tree, %v", err)
+	}
+	return nil
+}
+
+// checkParentCategory refuses a parent that does not exist or that is the category id or one of its descendants
+func checkParentCategory(tx *gorm.DB, id string, parentID string) error {
+	var count int64
+	if err := tx.Model(&models.ProductCategory{}).Where("id = ? and status <> ?", parentID, models.StatusDeleted).Count(&count).Error; err != nil {
+		return fmt.Errorf("error getting parent category, %v", err)
+	}
+	if count == 0 {
+		return ErrParentCategoryNotFound
+	}
+	if id == "" {
+		return nil
+	}
+
+	if err := tx.Raw("SELECT count(*) FROM ("+descendantCategories+") descendants WHERE id = ?", id, parentID).Scan(&count).Error; err != nil {
+		return fmt.Errorf("error getting descendant categories, %v", err)
+	}
+	if count > 0 {
+		return ErrCategoryCycle
+	}
+	return nil
+}
+
 func (pr *productCategoryRepository) CreateProductCategory(productCategory *models.ProductCategory) error {
-	return pr.db.Create(productCategory).Error
+	tx := pr.db.Begin()
+	defer tx.Rollback()
+
+	if productCategory.ParentID != nil {
+		if err := lockCategoryTree(tx); err != nil {
+			return err
+		}
+		if err := checkParentCategory(tx, "", *productCategory.ParentID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Create(productCategory).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
 }
 
 func (pr *productCategoryRepository) GetProductCategories(pagination utils.Pagination, where map[string]string) ([]models.ProductCategory, int64, error) {
@@ -88,27 +168,106 @@ func (pr *productCategoryRepository) GetProductCategoryById(id string) (models.P
 	return productCategory, nil
 }
 
+// GetProductCategoryTree returns every category that is not deleted by name, CategoryTree nests them
+func (pr *productCategoryRepository) GetProductCategoryTree() (categories []models.ProductCategory, err error) {
+	return categories, pr.db.Where("status <> ?", models.StatusDeleted).Order(`"name", id`).Find(&categories).Error
+}
+
+// GetCategoryPaths returns the path from the root category to every category of ids
+func (pr *productCategoryRepository) GetCategoryPaths(ids []string) (map[string][]models.CategoryCrumb, error) {
+	paths := make(map[string][]models.CategoryCrumb, len(ids))
+	if len(ids) == 0 {
+		return paths, nil
+	}
+
+	var rows []struct {
+		CategoryID string
+		ID         string
+		Name       string
+	}
+	if err := pr.db.Raw(ancestorCategories, ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		paths[row.CategoryID] = append(paths[row.CategoryID], models.CategoryCrumb{ID: row.ID, Name: row.Name})
+	}
+	return paths, nil
+}
+
+// UpdateProductCategory replaces the category, gorm.ErrRecordNotFound is returned when it does not exist
 func (pr *productCategoryRepository) UpdateProductCategory(productCategory *models.ProductCategoryUpdate) error {
-	return pr.db.
+	tx := pr.db.Begin()
+	defer tx.Rollback()
+
+	if productCategory.ParentID != nil {
+		if err := lockCategoryTree(tx); err != nil {
+			return err
+		}
+		if err := checkParentCategory(tx, productCategory.ID, *productCategory.ParentID); err != nil {
+			return err
+		}
+	}
+
+	result := tx.
 		Model(&models.ProductCategory{ID: productCategory.ID}).
 		Updates(
 			map[string]interface{}{
-				"name":       productCategory.Name,
-				"status":     productCategory.Status,
-				"updated_at": gorm.Expr("now()"),
-				"updated_by": productCategory.UpdatedBy,
+				"parent_id":        productCategory.ParentID,
+				"name":             productCategory.Name,
+				"attribute_schema": productCategory.AttributeSchema,
+				"status":           productCategory.Status,
+				"updated_at":       gorm.Expr("now()"),
+				"updated_by":       productCategory.UpdatedBy,
 			},
-		).Error
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return tx.Commit().Error
 }
 
+// DeleteProductCategory deletes a category without children and products, gorm.ErrRecordNotFound is returned when it
+// does not exist
 func (pr *productCategoryRepository) DeleteProductCategory(productCategory *models.ProductCategoryUpdate) (err error) {
-	return pr.db.
-		Model(&models.ProductCategory{ID: productCategory.ID}).
+	tx := pr.db.Begin()
+	defer tx.Rollback()
+
+	if err := lockCategoryTree(tx); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.ProductCategory{}).Where("parent_id = ? and status <> ?", productCategory.ID, models.StatusDeleted).Count(&count).Error; err != nil {
+		return fmt.Errorf("error getting child categories, %v", err)
+	}
+	if count > 0 {
+		return ErrCategoryHasChildren
+	}
+	if err := tx.Model(&models.Product{}).Where("category_id = ? and status <> ?", productCategory.ID, models.StatusDeleted).Count(&count).Error; err != nil {
+		return fmt.Errorf("error getting category products, %v", err)
+	}
+	if count > 0 {
+		return ErrCategoryHasProducts
+	}
+
+	result := tx.
+		Model(&models.ProductCategory{}).
+		Where("id = ? and status <> ?", productCategory.ID, models.StatusDeleted).
 		Updates(
 			map[string]interface{}{
 				"status":     models.StatusDeleted.String(),
 				"updated_at": gorm.Expr("now()"),
 				"updated_by": productCategory.UpdatedBy,
 			},
-		).Error
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return tx.Commit().Error
 }
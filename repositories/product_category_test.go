@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"errors"
+	"mvp-shop-backend/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// TestUpdateProductCategoryParent refuses to move a category under itself, under one of its descendants or under a
+// category that does not exist
+func TestUpdateProductCategoryParent(t *testing.T) {
+	db := openTestDB(t, "repositories_test_category_parent")
+	productCategoryRepository := NewProductCategoryRepository(db)
+
+	parentID := func(id string) *string {
+		return &id
+	}
+	// root > child > grandchild, other and deleted are roots
+	for _, category := range []models.ProductCategory{
+		{ID: "root", Name: "Root"},
+		{ID: "child", ParentID: parentID("root"), Name: "Child"},
+		{ID: "grandchild", ParentID: parentID("child"), Name: "Grandchild"},
+		{ID: "other", Name: "Other"},
+		{ID: "deleted", Name: "Deleted"},
+	} {
+		category.Status = models.StatusActive
+		category.CreatedBy = "test"
+		if err := productCategoryRepository.CreateProductCategory(&category); err != nil {
+			t.Fatalf("CreateProductCategory(%s): %v", category.ID, err)
+		}
+	}
+	if err := productCategoryRepository.DeleteProductCategory(&models.ProductCategoryUpdate{ID: "deleted", UpdatedBy: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		parentID string
+		wantErr  error
+	}{
+		{name: "itself", id: "root", parentID: "root", wantErr: ErrCategoryCycle},
+		{name: "its child", id: "root", parentID: "child", wantErr: ErrCategoryCycle},
+		{name: "its grandchild", id: "root", parentID: "grandchild", wantErr: ErrCategoryCycle},
+		{name: "unknown category", id: "child", parentID: "unknown", wantErr: ErrParentCategoryNotFound},
+		{name: "deleted category", id: "child", parentID: "deleted", wantErr: ErrParentCategoryNotFound},
+		{name: "another root", id: "child", parentID: "other"},
+		{name: "its former ancestor", id: "root", parentID: "grandchild"},
+	}
+	for _, tt := range tests {
+		err := productCategoryRepository.UpdateProductCategory(&models.ProductCategoryUpdate{
+			ID:        tt.id,
+			ParentID:  parentID(tt.parentID),
+			Name:      tt.id,
+			Status:    models.StatusActive,
+			UpdatedBy: "test",
+		})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("UpdateProductCategory() of %s under %s = %v, want %v", tt.id, tt.name, err, tt.wantErr)
+		}
+	}
+
+	var parents []struct {
+		ID       string
+		ParentID *string
+	}
+	if err := db.Model(&models.ProductCategory{}).Where("id IN ?", []string{"root", "child"}).Find(&parents).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"root": "grandchild", "child": "other"}
+	for _, category := range parents {
+		if category.ParentID == nil || *category.ParentID != want[category.ID] {
+			t.Errorf("parent of %s is %v, want %s", category.ID, category.ParentID, want[category.ID])
+		}
+	}
+
+	category := models.ProductCategory{ID: "orphan", ParentID: parentID("unknown"), Name: "Orphan", Status: models.StatusActive, CreatedBy: "test"}
+	if err := productCategoryRepository.CreateProductCategory(&category); !errors.Is(err, ErrParentCategoryNotFound) {
+		t.Errorf("CreateProductCategory() under an unknown category = %v, want ErrParentCategoryNotFound", err)
+	}
+}
+
+// TestDeleteProductCategory refuses to delete a category that still has children or products, deleted children and
+// products do not count
+func TestDeleteProductCategory(t *testing.T) {
+	db := openTestDB(t, "repositories_test_category_delete")
+	productCategoryRepository := NewProductCategoryRepository(db)
+
+	seedProduct(t, db, "product-1", 1)
+	parentID := "category-product-1"
+	if err := productCategoryRepository.CreateProductCategory(&models.ProductCategory{
+		ID:        "child",
+		ParentID:  &parentID,
+		Name:      "Child",
+		Status:    models.StatusActive,
+		CreatedBy: "test",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deleteCategory := func(id string) error {
+		return productCategoryRepository.DeleteProductCategory(&models.ProductCategoryUpdate{ID: id, UpdatedBy: "test"})
+	}
+
+	if err := deleteCategory("category-product-1"); !errors.Is(err, ErrCategoryHasChildren) {
+		t.Errorf("DeleteProductCategory() of a category with a child = %v, want ErrCategoryHasChildren", err)
+	}
+	if err := deleteCategory("child"); err != nil {
+		t.Fatalf("DeleteProductCategory() of a category without children: %v", err)
+	}
+	if err := deleteCategory("child"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("DeleteProductCategory() of a deleted category = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if err := deleteCategory("unknown"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("DeleteProductCategory() of an unknown category = %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	if err := deleteCategory("category-product-1"); !errors.Is(err, ErrCategoryHasProducts) {
+		t.Errorf("DeleteProductCategory() of a category with a product = %v, want ErrCategoryHasProducts", err)
+	}
+	if err := db.Exec(`UPDATE products SET status = ? WHERE id = 'product-1'`, models.StatusDeleted.String()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteCategory("category-product-1"); err != nil {
+		t.Errorf("DeleteProductCategory() of a category with deleted children and products: %v", err)
+	}
+}
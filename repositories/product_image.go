@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrImageOrderMismatch is returned by ReorderProductImages when the ids are not exactly the images of the product
+var ErrImageOrderMismatch = errors.New("image order does not list every image of the product")
+
+type productImageRepository struct {
+	db *gorm.DB
+}
+
+type ProductImageRepositoryInterface interface {
+	GetProductImages(productIDs []string) ([]models.ProductImage, error)
+	CreateProductImage(image *models.ProductImage) error
+	ReorderProductImages(productID string, imageIDs []string) error
+	DeleteProductImage(productID string, imageID string) (models.ProductImage, error)
+	DeleteProductImages(productID string) ([]models.ProductImage, error)
+}
+
+func NewProductImageRepository(db *gorm.DB) ProductImageRepositoryInterface {
+	return &productImageRepository{
+		db: db,
+	}
+}
+
+// GetProductImages returns the images of productIDs by product then position
+func (ir *productImageRepository) GetProductImages(productIDs []string) (images []models.ProductImage, err error) {
+	if len(productIDs) == 0 {
+		return images, nil
+	}
+	return images, ir.db.
+		Where("product_id in ?", productIDs).
+		Order("product_id, position, created_at").
+		Find(&images).Error
+}
+
+// lockProductImages locks the product row so the positions of its images are changed by one transaction at a time,
+// gorm.ErrRecordNotFound is returned when the product does not exist
+func lockProductImages(tx *gorm.DB, productID string) error {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ? and status <> ?", productID, models.StatusDeleted).Take(&product).Error; err != nil {
+		return fmt.Errorf("error getting product, %w", err)
+	}
+	return nil
+}
+
+// CreateProductImage adds image after the other images of image.ProductID
+func (ir *productImageRepository) CreateProductImage(image *models.ProductImage) error {
+	tx := ir.db.Begin()
+	defer tx.Rollback()
+
+	if err := lockProductImages(tx, image.ProductID); err != nil {
+		return err
+	}
+
+	var position int
+	if err := tx.Model(&models.ProductImage{}).Select("coalesce(max(position), 0)").Where("product_id = ?", image.ProductID).Scan(&position).Error; err != nil {
+		return fmt.Errorf("error getting image position, %v", err)
+	}
+	image.Position = position + 1
+
+	if err := tx.Create(image).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// ReorderProductImages numbers the images of productID in the order of imageIDs
+func (ir *productImageRepository) ReorderProductImages(productID string, imageIDs []string) error {
+	tx := ir.db.Begin()
+	defer tx.Rollback()
+
+	if err := lockProductImages(tx, productID); err != nil {
+		return err
+	}
+
+	var ids []string
+	if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", productID).Pluck("id", &ids).Error; err != nil {
+		return fmt.Errorf("error getting product image, %v", err)
+	}
+	images := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		images[id] = true
+	}
+	if len(imageIDs) != len(ids) {
+		return ErrImageOrderMismatch
+	}
+	for _, id := range imageIDs {
+		if !images[id] {
+			return ErrImageOrderMismatch
+		}
+		// listing an image twice leaves another one out
+		delete(images, id)
+	}
+
+	for i, id := range imageIDs {
+		if err := tx.Model(&models.ProductImage{}).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+			return fmt.Errorf("error updating image position, %v", err)
+		}
+	}
+	return tx.Commit().Error
+}
+
+// DeleteProductImage deletes the image imageID of productID and returns it so its blobs can be deleted,
+// gorm.ErrRecordNotFound is returned when there is none
+func (ir *productImageRepository) DeleteProductImage(productID string, imageID string) (models.ProductImage, error) {
+	var deleted []models.ProductImage
+	result := ir.db.Clauses(clause.Returning{}).Where("id = ? and product_id = ?", imageID, productID).Delete(&deleted)
+	if result.Error != nil {
+		return models.ProductImage{}, result.Error
+	}
+	if len(deleted) == 0 {
+		return models.ProductImage{}, gorm.ErrRecordNotFound
+	}
+	return deleted[0], nil
+}
+
+// DeleteProductImages deletes every image of productID and returns them so their blobs can be deleted
+func (ir *productImageRepository) DeleteProductImages(productID string) (deleted []models.ProductImage, err error) {
+	return deleted, ir.db.Clauses(clause.Returning{}).Where("product_id = ?", productID).Delete(&deleted).Error
+}
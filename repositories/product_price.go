@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrCurrencyNotSupported is returned when prices are asked in a currency without exchange rate
+var ErrCurrencyNotSupported = errors.New("currency not supported")
+
+type productPriceRepository struct {
+	db *gorm.DB
+}
+
+type ProductPriceRepositoryInterface interface {
+	GetPriceList(currency money.Currency, productIDs []string) (models.PriceList, error)
+	SaveProductPrice(price *models.ProductPrice) error
+	DeleteProductPrice(productID string, currency string) error
+}
+
+func NewProductPriceRepository(db *gorm.DB) ProductPriceRepositoryInterface {
+	return &productPriceRepository{
+		db: db,
+	}
+}
+
+func (pr *productPriceRepository) GetPriceList(currency money.Currency, productIDs []string) (models.PriceList, error) {
+	return getPriceList(pr.db, currency, productIDs)
+}
+
+// getPriceList loads the exchange rate of currency and the prices productIDs have in currency
+func getPriceList(db *gorm.DB, currency money.Currency, productIDs []string) (models.PriceList, error) {
+	priceList := models.PriceList{
+		Currency: currency,
+		Rate:     money.OneRate(),
+		Prices:   make(map[string]money.Money),
+	}
+	if currency == money.DefaultCurrency() {
+		return priceList, nil
+	}
+
+	var rate models.ExchangeRate
+	if err := db.Where(&models.ExchangeRate{Currency: currency}).First(&rate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return priceList, ErrCurrencyNotSupported
+		}
+		return priceList, fmt.Errorf("error getting exchange rate, %v", err)
+	}
+	priceList.Rate = rate.Rate
+
+	if len(productIDs) == 0 {
+		return priceList, nil
+	}
+
+	var prices []models.ProductPrice
+	if err := db.Where("product_id in ? and currency = ?", productIDs, currency).Find(&prices).Error; err != nil {
+		return priceList, fmt.Errorf("error getting product prices, %v", err)
+	}
+	for _, price := range prices {
+		priceList.Prices[price.ProductID] = price.Price.WithCurrency(currency)
+	}
+
+	return priceList, nil
+}
+
+// SaveProductPrice creates the price of price.ProductID in price.Currency or replaces it
+func (pr *productPriceRepository) SaveProductPrice(price *models.ProductPrice) error {
+	return pr.db.
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "product_id"}, {Name: "currency"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"price":      price.Price,
+				"updated_at": gorm.Expr("now()"),
+				"updated_by": price.CreatedBy,
+			}),
+		}).
+		Create(price).Error
+}
+
+// DeleteProductPrice removes the price of productID in currency, gorm.ErrRecordNotFound is returned when there is none
+func (pr *productPriceRepository) DeleteProductPrice(productID string, currency string) error {
+	result := pr.db.Where("product_id = ? and currency = ?", productID, currency).Delete(&models.ProductPrice{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
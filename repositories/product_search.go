@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"html"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SortFieldRelevance orders search results by rank, it is the default of a search
+const SortFieldRelevance = "relevance"
+
+// Searcher finds the products matching a text query with the filters of the product list. Another engine can take
+// the place of the Postgres one as long as it is fed the products when they change.
+type Searcher interface {
+	SearchProducts(query string, pagination utils.Pagination, where map[string]string) ([]models.ProductView, int64, error)
+}
+
+// postgresSearcher matches the query against the search_vector column the triggers of the products keep up to date,
+// when no product matches it falls back to the names most similar to the query to forgive typos
+type postgresSearcher struct {
+	db *gorm.DB
+}
+
+func NewProductSearcher(db *gorm.DB) Searcher {
+	return &postgresSearcher{
+		db: db,
+	}
+}
+
+// highlights marks the matched words of a snippet, the rest of the text is escaped
+var highlights = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>")
+
+func (ps *postgresSearcher) SearchProducts(query string, pagination utils.Pagination, where map[string]string) ([]models.ProductView, int64, error) {
+	products, count, err := ps.search(query, pagination, where)
+	if err != nil || count > 0 {
+		return products, count, err
+	}
+	return ps.searchSimilar(query, pagination, where)
+}
+
+// search ranks the products whose name, category or description contain the words of query, query is in the syntax
+// of web search engines: "quoted phrase", or, -excluded
+func (ps *postgresSearcher) search(query string, pagination utils.Pagination, where map[string]string) ([]models.ProductView, int64, error) {
+	var count int64
+	var products []models.ProductView
+
+	queryBuilder := filterProducts(ps.db.
+		Table("products").
+		Joins("left join product_categories on products.category_id = product_categories.id").
+		Joins("cross join websearch_to_tsquery('english', ?) AS query", query).
+		Where("products.status <> ?", models.StatusDeleted).
+		Where("products.search_vector @@ query"), where)
+
+	if err := queryBuilder.Count(&count).Error; err != nil {
+		return nil, count, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	result := queryBuilder.
+		Select(`products.*, product_categories.name as category_name, ts_rank_cd(products.search_vector, query) AS rank,
+			ts_headline('english', products."name" || ' ' || products.description, query,
+				'StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30, MaxFragments=2') AS snippet`).
+		Limit(pagination.Limit).Offset(offset).Order(searchOrder(pagination)).
+		Scan(&products)
+	if result.Error != nil {
+		return nil, count, result.Error
+	}
+
+	for i := range products {
+		products[i].Snippet = highlights.Replace(html.EscapeString(products[i].Snippet))
+	}
+	return products, count, nil
+}
+
+// searchSimilar ranks the products by the trigram similarity of their name to query, the similarity has to reach
+// pg_trgm.word_similarity_threshold
+func (ps *postgresSearcher) searchSimilar(query string, pagination utils.Pagination, where map[string]string) ([]models.ProductView, int64, error) {
+	var count int64
+	var products []models.ProductView
+
+	queryBuilder := filterProducts(ps.db.
+		Table("products").
+		Joins("left join product_categories on products.category_id = product_categories.id").
+		Where("products.status <> ?", models.StatusDeleted).
+		Where(`products."name" %> ?`, query), where)
+
+	if err := queryBuilder.Count(&count).Error; err != nil {
+		return nil, count, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	result := queryBuilder.
+		Select(`products.*, product_categories.name as category_name, word_similarity(?, products."name") AS rank`, query).
+		Limit(pagination.Limit).Offset(offset).Order(searchOrder(pagination)).
+		Scan(&products)
+	if result.Error != nil {
+		return nil, count, result.Error
+	}
+	return products, count, nil
+}
+
+// searchOrder orders by rank unless another sort field is asked for
+func searchOrder(pagination utils.Pagination) string {
+	if pagination.SortField == SortFieldRelevance {
+		return "rank DESC, products.created_at DESC"
+	}
+	return productOrder(pagination)
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"mvp-shop-backend/pkg/utils"
+	"strings"
+	"testing"
+)
+
+func TestSearchOrder(t *testing.T) {
+	tests := []struct {
+		sortField     string
+		sortDirection string
+		want          string
+	}{
+		{sortField: SortFieldRelevance, sortDirection: "ASC", want: "rank DESC, products.created_at DESC"},
+		{sortField: "name", sortDirection: "ASC", want: `INITCAP(products."name") ASC`},
+		{sortField: "price", sortDirection: "DESC", want: "products.price DESC"},
+		{sortField: "stock", sortDirection: "ASC", want: "products.stock ASC"},
+		{sortField: "", sortDirection: "", want: "products.created_at DESC"},
+		// only relevance sorts by the rank, other fields are not passed to the query
+		{sortField: "rank", sortDirection: "ASC", want: "products.created_at ASC"},
+		{sortField: "description", sortDirection: "DESC", want: "products.created_at DESC"},
+	}
+	for _, tt := range tests {
+		got := searchOrder(utils.Pagination{SortField: tt.sortField, SortDirection: tt.sortDirection})
+		if got != tt.want {
+			t.Errorf("searchOrder(%q, %q) = %q, want %q", tt.sortField, tt.sortDirection, got, tt.want)
+		}
+	}
+}
+
+// TestSearchProducts ranks the products matching the words of the query by where they match, and falls back to the
+// names similar to the query when no product matches
+func TestSearchProducts(t *testing.T) {
+	db := openTestDB(t, "repositories_test_search")
+
+	for _, product := range []struct {
+		id, name, description string
+		price                 float64
+	}{
+		{id: "keyboard", name: "Wireless Keyboard", description: "A compact keyboard", price: 20000},
+		{id: "chair", name: "Office Chair", description: "Ergonomic chair with a wireless charging pad", price: 5000},
+		{id: "lamp", name: "Desk Lamp", description: "A dimmable LED lamp", price: 10000},
+	} {
+		seedProduct(t, db, product.id, 1)
+		if err := db.Exec(`UPDATE products SET "name" = ?, description = ?, price = ? WHERE id = ?`,
+			product.name, product.description, product.price, product.id).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	searcher := NewProductSearcher(db)
+	tests := []struct {
+		name      string
+		query     string
+		sortField string
+		want      []string
+	}{
+		// the name weighs more than the description
+		{name: "by relevance", query: "wireless", sortField: SortFieldRelevance, want: []string{"keyboard", "chair"}},
+		{name: "by price", query: "wireless", sortField: "price", want: []string{"chair", "keyboard"}},
+		{name: "excluded word", query: "wireless -keyboard", sortField: SortFieldRelevance, want: []string{"chair"}},
+		{name: "stemmed word", query: "lamps", sortField: SortFieldRelevance, want: []string{"lamp"}},
+		{name: "typo", query: "keyboad", sortField: SortFieldRelevance, want: []string{"keyboard"}},
+		{name: "nothing similar", query: "xylophone", sortField: SortFieldRelevance, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, count, err := searcher.SearchProducts(tt.query, utils.Pagination{
+				Limit:         10,
+				Page:          1,
+				SortField:     tt.sortField,
+				SortDirection: "ASC",
+			}, map[string]string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(products))
+			for _, product := range products {
+				got = append(got, product.ID)
+				if product.Rank <= 0 {
+					t.Errorf("rank of %s is %v, want more than 0", product.ID, product.Rank)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") || count != int64(len(tt.want)) {
+				t.Errorf("SearchProducts(%q) = %v of %d, want %v", tt.query, got, count, tt.want)
+			}
+		})
+	}
+
+	products, _, err := searcher.SearchProducts("wireless", utils.Pagination{Limit: 10, Page: 1, SortField: SortFieldRelevance}, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) == 0 || !strings.Contains(products[0].Snippet, "<mark>Wireless</mark>") {
+		t.Errorf("snippet of the first product does not mark the query: %+v", products)
+	}
+}
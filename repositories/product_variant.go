@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"mvp-shop-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type productVariantRepository struct {
+	db *gorm.DB
+}
+
+type ProductVariantRepositoryInterface interface {
+	GetProductVariants(productID string) ([]models.ProductVariant, error)
+	CreateProductVariant(variant *models.ProductVariant) error
+	UpdateProductVariant(variant *models.ProductVariant) error
+	DeleteProductVariant(variant *models.ProductVariant) error
+}
+
+func NewProductVariantRepository(db *gorm.DB) ProductVariantRepositoryInterface {
+	return &productVariantRepository{
+		db: db,
+	}
+}
+
+// GetProductVariants returns the variants of productID in the order they were added
+func (vr *productVariantRepository) GetProductVariants(productID string) (variants []models.ProductVariant, err error) {
+	return variants, vr.db.
+		Where("product_id = ? and status <> ?", productID, models.StatusDeleted).
+		Order("created_at, id").
+		Find(&variants).Error
+}
+
+func (vr *productVariantRepository) CreateProductVariant(variant *models.ProductVariant) error {
+	return vr.db.Create(variant).Error
+}
+
+// UpdateProductVariant replaces the variant variant.ID of variant.ProductID, gorm.ErrRecordNotFound is returned when
+// there is none
+func (vr *productVariantRepository) UpdateProductVariant(variant *models.ProductVariant) error {
+	result := vr.db.
+		Model(&models.ProductVariant{}).
+		Where("id = ? and product_id = ? and status <> ?", variant.ID, variant.ProductID, models.StatusDeleted).
+		Updates(
+			map[string]interface{}{
+				"sku":        variant.SKU,
+				"options":    variant.Options,
+				"price":      variant.Price,
+				"stock":      variant.Stock,
+				"updated_at": gorm.Expr("now()"),
+				"updated_by": variant.UpdatedBy,
+			},
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteProductVariant deletes the variant variant.ID of variant.ProductID, gorm.ErrRecordNotFound is returned when
+// there is none. Orders keep their lines of the variant.
+func (vr *productVariantRepository) DeleteProductVariant(variant *models.ProductVariant) error {
+	result := vr.db.
+		Model(&models.ProductVariant{}).
+		Where("id = ? and product_id = ? and status <> ?", variant.ID, variant.ProductID, models.StatusDeleted).
+		Updates(
+			map[string]interface{}{
+				"status":     models.StatusDeleted.String(),
+				"updated_at": gorm.Expr("now()"),
+				"updated_by": variant.UpdatedBy,
+			},
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"errors"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+	"testing"
+)
+
+// TestTransactionOrderVariants orders variants of a product, they are priced with their own price when they have one,
+// discounted by the coupon from that price and take the stock of the variant, not of the product
+func TestTransactionOrderVariants(t *testing.T) {
+	db := openTestDB(t, "repositories_test_variant")
+
+	seedProduct(t, db, "product-1", 10)
+	seedProduct(t, db, "product-2", 10)
+	seedCustomers(t, db, 1)
+	override := money.MustParse("15000", money.DefaultCurrency())
+	for _, variant := range []models.ProductVariant{
+		{ID: "variant-m", ProductID: "product-1", SKU: "P1-M", Options: models.VariantOptions{"Size": "M"}, Stock: 2},
+		{ID: "variant-l", ProductID: "product-1", SKU: "P1-L", Options: models.VariantOptions{"Size": "L"}, Price: &override, Stock: 2},
+		{ID: "variant-other", ProductID: "product-2", SKU: "P2-M", Options: models.VariantOptions{"Size": "M"}, Stock: 2},
+	} {
+		variant.Status = models.StatusActive
+		variant.CreatedBy = "test"
+		if err := db.Create(&variant).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec(`INSERT INTO promotions (id, code, "name", "type", "percent", status, created_by)
+		VALUES ('promotion-1', 'TEN', 'Ten', ?, 10, 'active', 'test')`, models.PromotionTypePercentage.String()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	orderRepository := NewOrderRepository(db)
+	newOrder := func(invoice string, coupon *string) models.Order {
+		return models.Order{
+			Invoice:     invoice,
+			CustomerID:  "customer-0",
+			CouponCode:  coupon,
+			OrderStatus: models.OrderStatusPending,
+			Status:      models.StatusActive,
+			CreatedBy:   "test",
+		}
+	}
+	variantID := func(id string) *string {
+		return &id
+	}
+
+	errs := []struct {
+		name    string
+		detail  models.OrderDetail
+		wantErr error
+	}{
+		{name: "without variant", detail: models.OrderDetail{ProductID: "product-1", Qty: 1}, wantErr: ErrVariantRequired},
+		{name: "variant of another product", detail: models.OrderDetail{ProductID: "product-1", VariantID: variantID("variant-other"), Qty: 1}, wantErr: ErrVariantNotFound},
+		{name: "unknown variant", detail: models.OrderDetail{ProductID: "product-1", VariantID: variantID("variant-xl"), Qty: 1}, wantErr: ErrVariantNotFound},
+	}
+	for _, tt := range errs {
+		order := newOrder("INV/TEST/"+tt.name, nil)
+		tt.detail.CreatedBy = "test"
+		if err := orderRepository.TransactionOrder(&order, &[]models.OrderDetail{tt.detail}, nil); !errors.Is(err, tt.wantErr) {
+			t.Errorf("TransactionOrder() %s = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	var stockErr *InsufficientStockError
+	order := newOrder("INV/TEST/stock", nil)
+	detail := []models.OrderDetail{{ProductID: "product-1", VariantID: variantID("variant-m"), Qty: 3, CreatedBy: "test"}}
+	if err := orderRepository.TransactionOrder(&order, &detail, nil); !errors.As(err, &stockErr) {
+		t.Fatalf("TransactionOrder() over the variant stock = %v, want an InsufficientStockError", err)
+	}
+	if len(stockErr.Products) != 1 || stockErr.Products[0].VariantID != "variant-m" || stockErr.Products[0].Stock != 2 {
+		t.Errorf("insufficient stock = %+v, want variant-m with a stock of 2", stockErr.Products)
+	}
+
+	coupon := "ten"
+	order = newOrder("INV/TEST/1", &coupon)
+	detail = []models.OrderDetail{
+		{ProductID: "product-1", VariantID: variantID("variant-m"), Qty: 2, CreatedBy: "test"},
+		{ProductID: "product-1", VariantID: variantID("variant-l"), Qty: 1, CreatedBy: "test"},
+	}
+	if err := orderRepository.TransactionOrder(&order, &detail, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	wantLines := []struct {
+		price, discount string
+	}{
+		{price: "10000.00", discount: "2000.00"},
+		{price: "15000.00", discount: "1500.00"},
+	}
+	for i, want := range wantLines {
+		if got := detail[i].Price.Decimal(); got != want.price {
+			t.Errorf("line %d price = %s, want %s", i, got, want.price)
+		}
+		if got := detail[i].DiscountAmount.Decimal(); got != want.discount {
+			t.Errorf("line %d discount = %s, want %s", i, got, want.discount)
+		}
+	}
+
+	stocks := map[string]float64{"variant-m": 0, "variant-l": 1, "variant-other": 2}
+	for id, want := range stocks {
+		var stock float64
+		if err := db.Table("product_variants").Select("stock").Where("id = ?", id).Scan(&stock).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stock != want {
+			t.Errorf("stock of %s is %v, want %v", id, stock, want)
+		}
+	}
+	var productStock float64
+	if err := db.Table("products").Select("stock").Where("id = ?", "product-1").Scan(&productStock).Error; err != nil {
+		t.Fatal(err)
+	}
+	if productStock != 10 {
+		t.Errorf("stock of product-1 is %v, want 10", productStock)
+	}
+
+	if _, _, err := orderRepository.TransactionCancelOrder(order.Invoice, order.CustomerID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]float64{"variant-m": 2, "variant-l": 2} {
+		var stock float64
+		if err := db.Table("product_variants").Select("stock").Where("id = ?", id).Scan(&stock).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stock != want {
+			t.Errorf("stock of %s after the cancellation is %v, want %v", id, stock, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type promotionRepository struct {
+	db *gorm.DB
+}
+
+type PromotionRepositoryInterface interface {
+	CreatePromotion(promotion *models.Promotion) error
+	GetPromotions(pagination utils.Pagination, where map[string]string) ([]models.Promotion, int64, error)
+	GetPromotionById(id string) (models.Promotion, error)
+	GetPromotionByCode(code string) (models.Promotion, error)
+	UpdatePromotion(promotion *models.Promotion) error
+	DeletePromotion(id string, updatedBy string) error
+	CountRedemptions(promotionID string, customerID string) (int64, error)
+}
+
+func NewPromotionRepository(db *gorm.DB) PromotionRepositoryInterface {
+	return &promotionRepository{
+		db: db,
+	}
+}
+
+func (pr *promotionRepository) CreatePromotion(promotion *models.Promotion) error {
+	return pr.db.Create(promotion).Error
+}
+
+func (pr *promotionRepository) GetPromotions(pagination utils.Pagination, where map[string]string) ([]models.Promotion, int64, error) {
+	var count int64
+	var promotions []models.Promotion
+
+	queryBuilder := pr.db.Model(&models.Promotion{}).Where("status <> ?", models.StatusDeleted)
+
+	if code, ok := where["code"]; ok && code != "" {
+		code := fmt.Sprintf("%%%s%%", code)
+		queryBuilder = queryBuilder.Where(`"code" ILIKE ?`, code)
+	}
+
+	sortDirection := models.SortDirectionDESC.String()
+	if pagination.SortDirection != "" {
+		sortDirection = pagination.SortDirection
+	}
+
+	if err := queryBuilder.Count(&count).Error; err != nil {
+		return nil, count, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	result := queryBuilder.Limit(pagination.Limit).Offset(offset).Order(fmt.Sprintf("created_at %s", sortDirection)).Find(&promotions)
+	if result.Error != nil {
+		return nil, count, result.Error
+	}
+
+	return promotions, count, nil
+}
+
+func (pr *promotionRepository) GetPromotionById(id string) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := pr.db.Where("id = ? and status <> ?", id, models.StatusDeleted).First(&promotion).Error
+	return promotion, err
+}
+
+// GetPromotionByCode returns the promotion of code in any case, gorm.ErrRecordNotFound is returned when there is none
+func (pr *promotionRepository) GetPromotionByCode(code string) (models.Promotion, error) {
+	var promotion models.Promotion
+	err := pr.db.Where("code = ? and status <> ?", strings.ToUpper(code), models.StatusDeleted).First(&promotion).Error
+	return promotion, err
+}
+
+// UpdatePromotion replaces the promotion promotion.ID, its usage count is kept. gorm.ErrRecordNotFound is returned
+// when there is none.
+func (pr *promotionRepository) UpdatePromotion(promotion *models.Promotion) error {
+	result := pr.db.
+		Model(&models.Promotion{}).
+		Where("id = ? and status <> ?", promotion.ID, models.StatusDeleted).
+		Updates(
+			map[string]interface{}{
+				"code":                     promotion.Code,
+				"name":                     promotion.Name,
+				"type":                     promotion.Type,
+				"percent":                  promotion.Percent,
+				"amount":                   promotion.Amount,
+				"buy_qty":                  promotion.BuyQty,
+				"get_qty":                  promotion.GetQty,
+				"min_spend":                promotion.MinSpend,
+				"category_id":              promotion.CategoryID,
+				"product_id":               promotion.ProductID,
+				"starts_at":                promotion.StartsAt,
+				"ends_at":                  promotion.EndsAt,
+				"usage_limit":              promotion.UsageLimit,
+				"usage_limit_per_customer": promotion.UsageLimitPerCustomer,
+				"updated_at":               gorm.Expr("now()"),
+				"updated_by":               promotion.UpdatedBy,
+			},
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeletePromotion marks the promotion id deleted, its redemptions are kept with the orders
+func (pr *promotionRepository) DeletePromotion(id string, updatedBy string) error {
+	result := pr.db.
+		Model(&models.Promotion{}).
+		Where("id = ? and status <> ?", id, models.StatusDeleted).
+		Updates(
+			map[string]interface{}{
+				"status":     models.StatusDeleted.String(),
+				"updated_at": gorm.Expr("now()"),
+				"updated_by": updatedBy,
+			},
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (pr *promotionRepository) CountRedemptions(promotionID string, customerID string) (int64, error) {
+	return countRedemptions(pr.db, promotionID, customerID)
+}
+
+func countRedemptions(db *gorm.DB, promotionID string, customerID string) (int64, error) {
+	var count int64
+	if err := db.Model(&models.PromotionRedemption{}).
+		Where("promotion_id = ? and customer_id = ?", promotionID, customerID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("error counting promotion redemptions, %v", err)
+	}
+	return count, nil
+}
+
+// lockPromotion selects the promotion of code FOR UPDATE and checks customerID can use it. Orders redeeming the same
+// promotion wait for each other, so its usage limits hold under concurrent checkouts.
+func lockPromotion(tx *gorm.DB, code string, customerID string) (models.Promotion, error) {
+	var promotion models.Promotion
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ? and status <> ?", strings.ToUpper(code), models.StatusDeleted).
+		First(&promotion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return promotion, &models.PromotionError{Reason: "Coupon not exist"}
+		}
+		return promotion, fmt.Errorf("error getting promotion, %v", err)
+	}
+
+	uses, err := countRedemptions(tx, promotion.ID, customerID)
+	if err != nil {
+		return promotion, err
+	}
+	return promotion, promotion.Check(time.Now(), uses)
+}
+
+// redeemPromotion records the use of promotion by order and counts it
+func redeemPromotion(tx *gorm.DB, promotion models.Promotion, order *models.Order) error {
+	redemption := models.PromotionRedemption{
+		ID:             uuid.New().String(),
+		PromotionID:    promotion.ID,
+		Invoice:        order.Invoice,
+		CustomerID:     order.CustomerID,
+		DiscountAmount: order.DiscountAmount,
+		Currency:       order.Currency,
+		CreatedBy:      order.CreatedBy,
+	}
+	if err := tx.Create(&redemption).Error; err != nil {
+		return fmt.Errorf("error creating promotion redemption, %w", err)
+	}
+
+	if err := tx.Model(&models.Promotion{}).Where("id = ?", promotion.ID).
+		Update("used_count", gorm.Expr("used_count + 1")).Error; err != nil {
+		return fmt.Errorf("error updating promotion, %v", err)
+	}
+	return nil
+}
+
+// releasePromotion removes the redemption of invoice, the promotion can be used again
+func releasePromotion(tx *gorm.DB, invoice string) error {
+	var redemption models.PromotionRedemption
+	result := tx.Clauses(clause.Returning{}).Where("invoice = ?", invoice).Delete(&redemption)
+	if result.Error != nil {
+		return fmt.Errorf("error deleting promotion redemption, %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.Promotion{}).Where("id = ?", redemption.PromotionID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+		return fmt.Errorf("error updating promotion, %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"sync"
+	"testing"
+)
+
+// TestPromotionUsageLimitUnderConcurrentOrders redeems a promotion of usage_limit 1 from concurrent orders of different
+// customers, exactly one of them gets it and the others fail with a PromotionError.
+func TestPromotionUsageLimitUnderConcurrentOrders(t *testing.T) {
+	db := openTestDB(t, "repositories_test_promotion")
+
+	const orders = 10
+	seedProduct(t, db, "product-1", orders)
+	seedCustomers(t, db, orders)
+	if err := db.Exec(`INSERT INTO promotions (id, code, "name", "type", "percent", usage_limit, status, created_by)
+		VALUES ('promotion-1', 'ONCE', 'Once', ?, 10, 1, 'active', 'test')`, models.PromotionTypePercentage.String()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	orderRepository := NewOrderRepository(db)
+
+	var wg sync.WaitGroup
+	errs := make([]error, orders)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := "once"
+			order := models.Order{
+				Invoice:     fmt.Sprintf("INV/TEST/%d", i),
+				CustomerID:  fmt.Sprintf("customer-%d", i),
+				CouponCode:  &code,
+				OrderStatus: models.OrderStatusPending,
+				Status:      models.StatusActive,
+				CreatedBy:   "test",
+			}
+			orderDetail := []models.OrderDetail{{ProductID: "product-1", Qty: 1, CreatedBy: "test"}}
+			errs[i] = orderRepository.TransactionOrder(&order, &orderDetail, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		var promotionErr *models.PromotionError
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.As(err, &promotionErr):
+		default:
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d orders redeemed the promotion, want 1", succeeded)
+	}
+
+	var promotion models.Promotion
+	if err := db.Where("id = ?", "promotion-1").First(&promotion).Error; err != nil {
+		t.Fatal(err)
+	}
+	if promotion.UsedCount != 1 {
+		t.Errorf("used_count is %d, want 1", promotion.UsedCount)
+	}
+
+	var redemptions int64
+	if err := db.Model(&models.PromotionRedemption{}).Count(&redemptions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if redemptions != 1 {
+		t.Errorf("%d redemptions stored, want 1", redemptions)
+	}
+}
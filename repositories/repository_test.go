@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"mvp-shop-backend/migration"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB applies every migration to a new empty schema of a local Postgres, dropped when the test ends. It skips
+// the test unless TEST_DATABASE_DSN is set, e.g.
+// TEST_DATABASE_DSN="host=localhost user=postgres password=postgres dbname=mvp_shop_test sslmode=disable"
+func openTestDB(t *testing.T, schema string) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s", schema, schema)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	separator := " "
+	if strings.Contains(dsn, "://") {
+		separator = "&"
+		if !strings.Contains(dsn, "?") {
+			separator = "?"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+separator+"search_path="+schema+",public"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema))
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migration.NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// seedProduct creates a category and a product of stock priced 10000 in the default currency
+func seedProduct(t *testing.T, db *gorm.DB, id string, stock float64) {
+	t.Helper()
+
+	if err := db.Exec(`INSERT INTO product_categories (id, "name", status, created_by) VALUES (?, ?, 'active', 'test')`,
+		"category-"+id, "Category "+id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`INSERT INTO products (id, "name", description, slug, price, stock, category_id, status, created_by)
+		VALUES (?, ?, '', ?, 10000, ?, ?, 'active', 'test')`,
+		id, "Product "+id, "product-"+id, stock, "category-"+id).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedCustomers creates the customers customer-0 to customer-<n-1>
+func seedCustomers(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if err := db.Exec(`INSERT INTO customers (id, email, "name", "password", status, created_by) VALUES (?, ?, ?, '', 'active', 'test')`,
+			fmt.Sprintf("customer-%d", i), fmt.Sprintf("customer-%d@example.com", i), fmt.Sprintf("Customer %d", i)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
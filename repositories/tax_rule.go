@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"fmt"
+	"mvp-shop-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type taxRuleRepository struct {
+	db *gorm.DB
+}
+
+type TaxRuleRepositoryInterface interface {
+	GetTaxRules() ([]models.TaxRule, error)
+	GetTaxTable(region string) (models.TaxTable, error)
+	TaxRuleExists(rule *models.TaxRule) (bool, error)
+	CreateTaxRule(rule *models.TaxRule) error
+	UpdateTaxRule(rule *models.TaxRule) error
+	DeleteTaxRule(id string) error
+}
+
+func NewTaxRuleRepository(db *gorm.DB) TaxRuleRepositoryInterface {
+	return &taxRuleRepository{
+		db: db,
+	}
+}
+
+func (tr *taxRuleRepository) GetTaxRules() (rules []models.TaxRule, err error) {
+	return rules, tr.db.Order("region nulls first, category_id nulls first, created_at").Find(&rules).Error
+}
+
+func (tr *taxRuleRepository) GetTaxTable(region string) (models.TaxTable, error) {
+	return getTaxTable(tr.db, region)
+}
+
+// getTaxTable loads the tax rules of region and of every region
+func getTaxTable(db *gorm.DB, region string) (models.TaxTable, error) {
+	taxTable := models.TaxTable{Region: region}
+	if err := db.Where("region is null or region = ?", region).Find(&taxTable.Rules).Error; err != nil {
+		return taxTable, fmt.Errorf("error getting tax rules, %v", err)
+	}
+	return taxTable, nil
+}
+
+// TaxRuleExists tells whether another rule than rule has its region and category
+func (tr *taxRuleRepository) TaxRuleExists(rule *models.TaxRule) (bool, error) {
+	var count int64
+	queryBuilder := tr.db.Model(&models.TaxRule{}).Where("id <> ?", rule.ID)
+	if rule.Region == nil {
+		queryBuilder = queryBuilder.Where("region is null")
+	} else {
+		queryBuilder = queryBuilder.Where("region = ?", *rule.Region)
+	}
+	if rule.CategoryID == nil {
+		queryBuilder = queryBuilder.Where("category_id is null")
+	} else {
+		queryBuilder = queryBuilder.Where("category_id = ?", *rule.CategoryID)
+	}
+	if err := queryBuilder.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("error getting tax rule, %v", err)
+	}
+	return count > 0, nil
+}
+
+func (tr *taxRuleRepository) CreateTaxRule(rule *models.TaxRule) error {
+	return tr.db.Create(rule).Error
+}
+
+// UpdateTaxRule replaces the rule rule.ID, gorm.ErrRecordNotFound is returned when there is none
+func (tr *taxRuleRepository) UpdateTaxRule(rule *models.TaxRule) error {
+	result := tr.db.
+		Model(&models.TaxRule{}).
+		Where("id = ?", rule.ID).
+		Updates(
+			map[string]interface{}{
+				"name":        rule.Name,
+				"region":      rule.Region,
+				"category_id": rule.CategoryID,
+				"rate":        rule.Rate,
+				"inclusive":   rule.Inclusive,
+				"updated_at":  gorm.Expr("now()"),
+				"updated_by":  rule.UpdatedBy,
+			},
+		)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteTaxRule removes the rule id, gorm.ErrRecordNotFound is returned when there is none
+func (tr *taxRuleRepository) DeleteTaxRule(id string) error {
+	result := tr.db.Where("id = ?", id).Delete(&models.TaxRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrRefreshTokenReused is returned by RotateRefreshToken when an already rotated token is presented again,
+	// the whole token family is revoked before returning it
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	// ErrRefreshTokenExpired is returned by RotateRefreshToken when the token is expired
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+)
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+type TokenRepositoryInterface interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	RotateRefreshToken(tokenHash string, next *models.RefreshToken) (models.RefreshToken, error)
+	RevokeRefreshTokenFamily(tokenHash string, customerID string) error
+	RevokeCustomerRefreshTokens(customerID string) error
+	RevokeAccessToken(jti string, expiresAt time.Time) error
+	IsRevoked(token *models.CustomerClaims) (bool, error)
+}
+
+func NewTokenRepository(db *gorm.DB) TokenRepositoryInterface {
+	return &tokenRepository{
+		db: db,
+	}
+}
+
+func (tr *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return tr.db.Create(token).Error
+}
+
+// RotateRefreshToken revokes the refresh token of tokenHash and creates next in the same family
+func (tr *tokenRepository) RotateRefreshToken(tokenHash string, next *models.RefreshToken) (models.RefreshToken, error) {
+	var current models.RefreshToken
+
+	tx := tr.db.Begin()
+	defer tx.Rollback()
+
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(&models.RefreshToken{TokenHash: tokenHash}).
+		First(&current).Error; err != nil {
+		return current, err
+	}
+
+	if current.RevokedAt != nil {
+		if err := revokeFamily(tx, current.FamilyID); err != nil {
+			return current, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			return current, fmt.Errorf("error committing transaction, %v", err)
+		}
+		return current, ErrRefreshTokenReused
+	}
+
+	if current.ExpiresAt.Before(time.Now()) {
+		return current, ErrRefreshTokenExpired
+	}
+
+	next.CustomerID = current.CustomerID
+	next.FamilyID = current.FamilyID
+	if err := tx.Model(&models.RefreshToken{ID: current.ID}).
+		Updates(
+			map[string]interface{}{
+				"revoked_at":  gorm.Expr("now()"),
+				"replaced_by": next.ID,
+			},
+		).Error; err != nil {
+		return current, fmt.Errorf("error revoking refresh token, %v", err)
+	}
+
+	if err := tx.Create(next).Error; err != nil {
+		return current, fmt.Errorf("error creating refresh token, %v", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return current, fmt.Errorf("error committing transaction, %v", err)
+	}
+
+	return current, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token of the family the token of tokenHash belongs to
+func (tr *tokenRepository) RevokeRefreshTokenFamily(tokenHash string, customerID string) error {
+	var token models.RefreshToken
+	if err := tr.db.Where(&models.RefreshToken{TokenHash: tokenHash, CustomerID: customerID}).First(&token).Error; err != nil {
+		return err
+	}
+	return revokeFamily(tr.db, token.FamilyID)
+}
+
+func (tr *tokenRepository) RevokeCustomerRefreshTokens(customerID string) error {
+	return tr.db.
+		Model(&models.RefreshToken{}).
+		Where("customer_id = ? and revoked_at is null", customerID).
+		Update("revoked_at", gorm.Expr("now()")).Error
+}
+
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	if err := tx.
+		Model(&models.RefreshToken{}).
+		Where("family_id = ? and revoked_at is null", familyID).
+		Update("revoked_at", gorm.Expr("now()")).Error; err != nil {
+		return fmt.Errorf("error revoking refresh token family, %v", err)
+	}
+	return nil
+}
+
+// RevokeAccessToken deny-lists jti until expiresAt, entries of already expired tokens are purged on the way
+func (tr *tokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	if err := tr.db.Where("expires_at < now()").Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return tr.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+}
+
+// IsRevoked reports whether token is deny-listed or was issued before the last password change of its customer
+func (tr *tokenRepository) IsRevoked(token *models.CustomerClaims) (bool, error) {
+	var count int64
+	if err := tr.db.Model(&models.RevokedToken{}).Where("jti = ?", token.Id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	if err := tr.db.
+		Model(&models.Customer{}).
+		Where("id = ? and token_version > ?", token.ID, token.TokenVersion).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"mvp-shop-backend/models"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// TestRevokeAccessToken deny-lists a jti twice and purges the entries of expired tokens on the next revocation
+func TestRevokeAccessToken(t *testing.T) {
+	db := openTestDB(t, "repositories_test_token")
+	tokenRepository := NewTokenRepository(db)
+
+	if err := db.Create(&models.RevokedToken{JTI: "expired", ExpiresAt: time.Now().Add(-time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := tokenRepository.RevokeAccessToken("jti-1", expiresAt); err != nil {
+			t.Fatalf("RevokeAccessToken() #%d: %v", i+1, err)
+		}
+	}
+
+	tests := []struct {
+		jti  string
+		want bool
+	}{
+		{jti: "jti-1", want: true},
+		{jti: "jti-2", want: false},
+		{jti: "expired", want: false},
+	}
+	for _, tt := range tests {
+		revoked, err := tokenRepository.IsRevoked(&models.CustomerClaims{StandardClaims: jwt.StandardClaims{Id: tt.jti}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != tt.want {
+			t.Errorf("IsRevoked(%q) = %v, want %v", tt.jti, revoked, tt.want)
+		}
+	}
+}
+
+// TestIsRevokedAfterPasswordChange refuses the access tokens issued before the last password change of their customer
+func TestIsRevokedAfterPasswordChange(t *testing.T) {
+	db := openTestDB(t, "repositories_test_token_password")
+	seedCustomers(t, db, 2)
+	tokenRepository := NewTokenRepository(db)
+	customerRepository := NewCustomerRepository(db)
+
+	isRevoked := func(customerID string, tokenVersion int) bool {
+		t.Helper()
+		revoked, err := tokenRepository.IsRevoked(&models.CustomerClaims{
+			StandardClaims: jwt.StandardClaims{Id: "jti-" + customerID},
+			ID:             customerID,
+			TokenVersion:   tokenVersion,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return revoked
+	}
+
+	if isRevoked("customer-0", 0) {
+		t.Error("a token of a customer who never changed their password is revoked")
+	}
+
+	if err := customerRepository.UpdateCustomerPassword("customer-0", "new-password-hash", "test"); err != nil {
+		t.Fatal(err)
+	}
+	customer, err := customerRepository.GetCustomerById("customer-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if customer.TokenVersion != 1 {
+		t.Fatalf("token version is %d, want 1 after the password change", customer.TokenVersion)
+	}
+
+	if !isRevoked("customer-0", 0) {
+		t.Error("a token issued before the password change is accepted")
+	}
+	if isRevoked("customer-0", 1) {
+		t.Error("a token issued after the password change is revoked")
+	}
+	if isRevoked("customer-1", 0) {
+		t.Error("the password change of another customer revoked the token")
+	}
+}
@@ -3,61 +3,153 @@ package routes
 import (
 	"mvp-shop-backend/controllers"
 	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(customerController controllers.CustomerControllerInterface, authController controllers.AuthControllerInterface, productCategoryController controllers.ProductCategoryControllerInterface, productController controllers.ProductControllerInterface, cartController controllers.CartControllerInterface, orderController controllers.OrderControllerInterface) *gin.Engine {
+func NewRouter(customerController controllers.CustomerControllerInterface, authController controllers.AuthControllerInterface, productCategoryController controllers.ProductCategoryControllerInterface, productController controllers.ProductControllerInterface, cartController controllers.CartControllerInterface, orderController controllers.OrderControllerInterface, paymentController controllers.PaymentControllerInterface, exchangeRateController controllers.ExchangeRateControllerInterface, taxRuleController controllers.TaxRuleControllerInterface, promotionController controllers.PromotionControllerInterface, addressController controllers.AddressControllerInterface, denyList middleware.TokenDenyList) *gin.Engine {
 	router := gin.Default()
+	// ClientIP keys the login lockout, so X-Forwarded-For is only read from the proxies of TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		panic(err)
+	}
+	// invoices contain a slash (INV/...), so path params are matched on the escaped path
+	router.UseRawPath = true
 	router.Use(middleware.CORSMiddleware())
+	router.GET("/.well-known/jwks.json", authController.JWKS)
 	baseRouter := router.Group("/v1")
+	baseRouter.Use(middleware.CurrencyMiddleware(), middleware.RegionMiddleware())
+	authMiddleware := middleware.AuthMiddleware(denyList)
+	requireAdmin := middleware.RequireRole(models.RoleAdmin)
 
 	//* customers
 	customers := baseRouter.Group("/customers")
 	customers.POST("", customerController.CreateCustomer)
 	customersWithAuth := baseRouter.Group("/customers")
-	customersWithAuth.Use(middleware.AuthMiddleware())
-	customersWithAuth.GET("", customerController.GetCustomers)
+	customersWithAuth.Use(authMiddleware)
+	customersWithAuth.GET("", requireAdmin, customerController.GetCustomers)
 	customersWithAuth.GET("/:id", customerController.GetCustomerById)
 	customersWithAuth.PUT("/:id", customerController.UpdateCustomer)
 	customersWithAuth.DELETE("/:id", customerController.DeleteCustomer)
 
+	//* me
+	me := baseRouter.Group("/me")
+	me.Use(authMiddleware)
+	me.GET("", customerController.GetMe)
+	me.PUT("", customerController.UpdateMe)
+	me.POST("/password", customerController.ChangeMyPassword)
+	me.GET("/addresses", addressController.GetMyAddresses)
+	me.POST("/addresses", addressController.CreateMyAddress)
+	me.GET("/addresses/:id", addressController.GetMyAddressById)
+	me.PUT("/addresses/:id", addressController.UpdateMyAddress)
+	me.DELETE("/addresses/:id", addressController.DeleteMyAddress)
+
 	//* auth
 	auth := baseRouter.Group("/auth")
 	auth.POST("/login", authController.Login)
+	auth.POST("/refresh", authController.Refresh)
+	auth.POST("/verify-email", authController.VerifyEmail)
+	auth.POST("/verify-email/resend", authController.ResendVerifyEmail)
+	auth.POST("/forgot-password", authController.ForgotPassword)
+	auth.POST("/reset-password", authController.ResetPassword)
+	authWithAuth := baseRouter.Group("/auth")
+	authWithAuth.Use(authMiddleware)
+	authWithAuth.POST("/logout", authController.Logout)
 
 	//* products/categories
-	productCategories := baseRouter.Group("/products/categories")
-	productCategories.POST("", productCategoryController.CreateProductCategory)
 	productCategoriesWithAuth := baseRouter.Group("/products/categories")
-	productCategoriesWithAuth.Use(middleware.AuthMiddleware())
+	productCategoriesWithAuth.Use(authMiddleware)
+	productCategoriesWithAuth.POST("", requireAdmin, productCategoryController.CreateProductCategory)
 	productCategoriesWithAuth.GET("", productCategoryController.GetProductCategories)
+	productCategoriesWithAuth.GET("/tree", productCategoryController.GetProductCategoryTree)
 	productCategoriesWithAuth.GET("/:id", productCategoryController.GetProductCategoryById)
-	productCategoriesWithAuth.PUT("/:id", productCategoryController.UpdateProductCategory)
-	productCategoriesWithAuth.DELETE("/:id", productCategoryController.DeleteProductCategory)
+	productCategoriesWithAuth.PUT("/:id", requireAdmin, productCategoryController.UpdateProductCategory)
+	productCategoriesWithAuth.DELETE("/:id", requireAdmin, productCategoryController.DeleteProductCategory)
 
 	//* products
-	products := baseRouter.Group("/products")
-	products.POST("", productController.CreateProduct)
 	productsWithAuth := baseRouter.Group("/products")
-	productsWithAuth.Use(middleware.AuthMiddleware())
+	productsWithAuth.Use(authMiddleware)
+	productsWithAuth.POST("", requireAdmin, productController.CreateProduct)
 	productsWithAuth.GET("", productController.GetProducts)
 	productsWithAuth.GET("/:id", productController.GetProductById)
-	productsWithAuth.PUT("/:id", productController.UpdateProduct)
-	productsWithAuth.DELETE("/:id", productController.DeleteProduct)
+	productsWithAuth.PUT("/:id", requireAdmin, productController.UpdateProduct)
+	productsWithAuth.DELETE("/:id", requireAdmin, productController.DeleteProduct)
+	productsWithAuth.PUT("/:id/prices/:currency", requireAdmin, productController.SaveProductPrice)
+	productsWithAuth.DELETE("/:id/prices/:currency", requireAdmin, productController.DeleteProductPrice)
+	productsWithAuth.POST("/:id/variants", requireAdmin, productController.CreateProductVariant)
+	productsWithAuth.PUT("/:id/variants/:variantId", requireAdmin, productController.UpdateProductVariant)
+	productsWithAuth.DELETE("/:id/variants/:variantId", requireAdmin, productController.DeleteProductVariant)
+	productsWithAuth.POST("/:id/images", requireAdmin, productController.UploadProductImage)
+	productsWithAuth.PUT("/:id/images/order", requireAdmin, productController.ReorderProductImages)
+	productsWithAuth.DELETE("/:id/images/:imageId", requireAdmin, productController.DeleteProductImage)
+
+	//* exchange rates
+	exchangeRates := baseRouter.Group("/exchange-rates")
+	exchangeRates.GET("", exchangeRateController.GetExchangeRates)
+	exchangeRatesWithAuth := baseRouter.Group("/exchange-rates")
+	exchangeRatesWithAuth.Use(authMiddleware, requireAdmin)
+	exchangeRatesWithAuth.PUT("/:currency", exchangeRateController.SaveExchangeRate)
+	exchangeRatesWithAuth.DELETE("/:currency", exchangeRateController.DeleteExchangeRate)
+
+	//* tax rules
+	taxRules := baseRouter.Group("/tax-rules")
+	taxRules.Use(authMiddleware, requireAdmin)
+	taxRules.GET("", taxRuleController.GetTaxRules)
+	taxRules.POST("", taxRuleController.CreateTaxRule)
+	taxRules.PUT("/:id", taxRuleController.UpdateTaxRule)
+	taxRules.DELETE("/:id", taxRuleController.DeleteTaxRule)
+
+	//* promotions
+	promotions := baseRouter.Group("/promotions")
+	promotions.Use(authMiddleware, requireAdmin)
+	promotions.POST("", promotionController.CreatePromotion)
+	promotions.GET("", promotionController.GetPromotions)
+	promotions.GET("/:id", promotionController.GetPromotionById)
+	promotions.PUT("/:id", promotionController.UpdatePromotion)
+	promotions.DELETE("/:id", promotionController.DeletePromotion)
 
 	//* carts
 	cartsWithAuth := baseRouter.Group("/carts")
-	cartsWithAuth.Use(middleware.AuthMiddleware())
+	cartsWithAuth.Use(authMiddleware)
 	cartsWithAuth.POST("", cartController.CreateCart)
 	cartsWithAuth.GET("", cartController.GetCartByCustomerID)
+	cartsWithAuth.POST("/coupon", cartController.ApplyCoupon)
+	cartsWithAuth.DELETE("/coupon", cartController.RemoveCoupon)
 	cartsWithAuth.PUT("/:id", cartController.UpdateCart)
 	cartsWithAuth.DELETE("/:id", cartController.DeleteCart)
 
 	//* orders
 	orders := baseRouter.Group("/orders")
-	orders.Use(middleware.AuthMiddleware())
+	orders.Use(authMiddleware)
 	orders.POST("", orderController.CreateOrder)
+	orders.POST("/checkout", orderController.CheckoutOrder)
+	orders.GET("", orderController.GetMyOrders)
+	orders.GET("/:invoice", orderController.GetMyOrderByInvoice)
+	orders.POST("/:invoice/cancel", orderController.CancelOrder)
+	orders.POST("/:invoice/ship", requireAdmin, orderController.ShipOrder)
+	orders.POST("/:invoice/deliver", requireAdmin, orderController.DeliverOrder)
+	orders.POST("/:invoice/pay", paymentController.PayOrder)
+
+	//* payments
+	payments := baseRouter.Group("/payments")
+	payments.POST("/webhook", paymentController.Webhook)
+	paymentsWithAuth := baseRouter.Group("/payments")
+	paymentsWithAuth.Use(authMiddleware)
+	paymentsWithAuth.POST("/:id/confirm", paymentController.ConfirmPayment)
 
 	return router
 }
+
+// trustedProxies returns the comma separated ips and cidrs of TRUSTED_PROXIES, none by default
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
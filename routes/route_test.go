@@ -0,0 +1,287 @@
+package routes
+
+import (
+	"mvp-shop-backend/controllers"
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/services"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubController implements every controller interface, its handlers answer 200 so a test only sees the middlewares
+type stubController struct{}
+
+func stub(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (stubController) ApplyCoupon(c *gin.Context)            { stub(c) }
+func (stubController) CancelOrder(c *gin.Context)            { stub(c) }
+func (stubController) ChangeMyPassword(c *gin.Context)       { stub(c) }
+func (stubController) CheckoutOrder(c *gin.Context)          { stub(c) }
+func (stubController) ConfirmPayment(c *gin.Context)         { stub(c) }
+func (stubController) CreateCart(c *gin.Context)             { stub(c) }
+func (stubController) CreateCustomer(c *gin.Context)         { stub(c) }
+func (stubController) CreateMyAddress(c *gin.Context)        { stub(c) }
+func (stubController) CreateOrder(c *gin.Context)            { stub(c) }
+func (stubController) CreateProduct(c *gin.Context)          { stub(c) }
+func (stubController) CreateProductCategory(c *gin.Context)  { stub(c) }
+func (stubController) CreateProductVariant(c *gin.Context)   { stub(c) }
+func (stubController) CreatePromotion(c *gin.Context)        { stub(c) }
+func (stubController) CreateTaxRule(c *gin.Context)          { stub(c) }
+func (stubController) DeleteCart(c *gin.Context)             { stub(c) }
+func (stubController) DeleteCustomer(c *gin.Context)         { stub(c) }
+func (stubController) DeleteExchangeRate(c *gin.Context)     { stub(c) }
+func (stubController) DeleteMyAddress(c *gin.Context)        { stub(c) }
+func (stubController) DeleteProduct(c *gin.Context)          { stub(c) }
+func (stubController) DeleteProductCategory(c *gin.Context)  { stub(c) }
+func (stubController) DeleteProductImage(c *gin.Context)     { stub(c) }
+func (stubController) DeleteProductPrice(c *gin.Context)     { stub(c) }
+func (stubController) DeleteProductVariant(c *gin.Context)   { stub(c) }
+func (stubController) DeletePromotion(c *gin.Context)        { stub(c) }
+func (stubController) DeleteTaxRule(c *gin.Context)          { stub(c) }
+func (stubController) DeliverOrder(c *gin.Context)           { stub(c) }
+func (stubController) ForgotPassword(c *gin.Context)         { stub(c) }
+func (stubController) GetCartByCustomerID(c *gin.Context)    { stub(c) }
+func (stubController) GetCustomerById(c *gin.Context)        { stub(c) }
+func (stubController) GetCustomers(c *gin.Context)           { stub(c) }
+func (stubController) GetExchangeRates(c *gin.Context)       { stub(c) }
+func (stubController) GetMe(c *gin.Context)                  { stub(c) }
+func (stubController) GetMyAddressById(c *gin.Context)       { stub(c) }
+func (stubController) GetMyAddresses(c *gin.Context)         { stub(c) }
+func (stubController) GetMyOrderByInvoice(c *gin.Context)    { stub(c) }
+func (stubController) GetMyOrders(c *gin.Context)            { stub(c) }
+func (stubController) GetProductById(c *gin.Context)         { stub(c) }
+func (stubController) GetProductCategories(c *gin.Context)   { stub(c) }
+func (stubController) GetProductCategoryById(c *gin.Context) { stub(c) }
+func (stubController) GetProductCategoryTree(c *gin.Context) { stub(c) }
+func (stubController) GetProducts(c *gin.Context)            { stub(c) }
+func (stubController) GetPromotionById(c *gin.Context)       { stub(c) }
+func (stubController) GetPromotions(c *gin.Context)          { stub(c) }
+func (stubController) GetTaxRules(c *gin.Context)            { stub(c) }
+func (stubController) JWKS(c *gin.Context)                   { stub(c) }
+func (stubController) Login(c *gin.Context)                  { stub(c) }
+func (stubController) Logout(c *gin.Context)                 { stub(c) }
+func (stubController) PayOrder(c *gin.Context)               { stub(c) }
+func (stubController) Refresh(c *gin.Context)                { stub(c) }
+func (stubController) RemoveCoupon(c *gin.Context)           { stub(c) }
+func (stubController) ReorderProductImages(c *gin.Context)   { stub(c) }
+func (stubController) ResendVerifyEmail(c *gin.Context)      { stub(c) }
+func (stubController) ResetPassword(c *gin.Context)          { stub(c) }
+func (stubController) SaveExchangeRate(c *gin.Context)       { stub(c) }
+func (stubController) SaveProductPrice(c *gin.Context)       { stub(c) }
+func (stubController) ShipOrder(c *gin.Context)              { stub(c) }
+func (stubController) UpdateCart(c *gin.Context)             { stub(c) }
+func (stubController) UpdateCustomer(c *gin.Context)         { stub(c) }
+func (stubController) UpdateMe(c *gin.Context)               { stub(c) }
+func (stubController) UpdateMyAddress(c *gin.Context)        { stub(c) }
+func (stubController) UpdateProduct(c *gin.Context)          { stub(c) }
+func (stubController) UpdateProductCategory(c *gin.Context)  { stub(c) }
+func (stubController) UpdateProductVariant(c *gin.Context)   { stub(c) }
+func (stubController) UpdatePromotion(c *gin.Context)        { stub(c) }
+func (stubController) UpdateTaxRule(c *gin.Context)          { stub(c) }
+func (stubController) UploadProductImage(c *gin.Context)     { stub(c) }
+func (stubController) VerifyEmail(c *gin.Context)            { stub(c) }
+func (stubController) Webhook(c *gin.Context)                { stub(c) }
+
+// stubDenyList revokes no token
+type stubDenyList struct{}
+
+func (stubDenyList) IsRevoked(token *models.CustomerClaims) (bool, error) {
+	return false, nil
+}
+
+// stubCustomerService records the customer ids it is asked about and answers 200
+type stubCustomerService struct {
+	services.CustomerServiceInterface
+	mu  sync.Mutex
+	ids []string
+}
+
+func (ss *stubCustomerService) record(id string) (*models.Response, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.ids = append(ss.ids, id)
+	return &models.Response{Code: http.StatusOK, Message: http.StatusText(http.StatusOK)}, nil
+}
+
+func (ss *stubCustomerService) GetCustomerById(id string) (*models.Response, error) {
+	return ss.record(id)
+}
+
+func (ss *stubCustomerService) UpdateCustomer(customer *models.CustomerUpdate) (*models.Response, error) {
+	return ss.record(customer.ID)
+}
+
+func (ss *stubCustomerService) DeleteCustomer(customer *models.CustomerUpdate) (*models.Response, error) {
+	return ss.record(customer.ID)
+}
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("JWT_ALGORITHM", "HS256")
+	os.Setenv("SECRET_KEY", "routes-test-secret-of-at-least-32-bytes")
+	os.Exit(m.Run())
+}
+
+func newTestRouter() *gin.Engine {
+	s := stubController{}
+	return NewRouter(s, s, s, s, s, s, s, s, s, s, s, stubDenyList{})
+}
+
+func token(t *testing.T, role models.Role) string {
+	t.Helper()
+
+	tokenString, _, err := middleware.GenerateToken(models.CustomerClaims{
+		ID:     "customer-1",
+		Email:  "customer@example.com",
+		Name:   "Customer",
+		Role:   role,
+		Status: models.StatusActive,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenString
+}
+
+func TestAdminRoutes(t *testing.T) {
+	router := newTestRouter()
+
+	paths := []string{"/v1/products", "/v1/products/categories"}
+	tests := []struct {
+		name string
+		role models.Role
+		want int
+	}{
+		{name: "anonymous", want: http.StatusUnauthorized},
+		{name: "customer", role: models.RoleCustomer, want: http.StatusForbidden},
+		{name: "admin", role: models.RoleAdmin, want: http.StatusOK},
+	}
+	for _, path := range paths {
+		for _, tt := range tests {
+			t.Run(tt.name+" "+path, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				if tt.role != "" {
+					req.Header.Set("Authorization", "Bearer "+token(t, tt.role))
+				}
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != tt.want {
+					t.Errorf("POST %s got %d, want %d", path, w.Code, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestCustomerOwnership checks that a customer only reaches their own customer by id, admins reach every customer
+func TestCustomerOwnership(t *testing.T) {
+	methods := []string{http.MethodGet, http.MethodPut, http.MethodDelete}
+	tests := []struct {
+		name string
+		role models.Role
+		id   string
+		want int
+	}{
+		{name: "own customer", role: models.RoleCustomer, id: "customer-1", want: http.StatusOK},
+		{name: "other customer", role: models.RoleCustomer, id: "customer-2", want: http.StatusForbidden},
+		{name: "admin on another customer", role: models.RoleAdmin, id: "customer-2", want: http.StatusOK},
+	}
+	for _, method := range methods {
+		for _, tt := range tests {
+			t.Run(tt.name+" "+method, func(t *testing.T) {
+				customerService := &stubCustomerService{}
+				s := stubController{}
+				router := NewRouter(controllers.NewCustomerController(customerService), s, s, s, s, s, s, s, s, s, s, stubDenyList{})
+
+				req := httptest.NewRequest(method, "/v1/customers/"+tt.id, strings.NewReader(`{"name":"Customer"}`))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token(t, tt.role))
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != tt.want {
+					t.Errorf("%s /v1/customers/%s got %d, want %d", method, tt.id, w.Code, tt.want)
+				}
+
+				reached := len(customerService.ids) > 0
+				if reached != (tt.want == http.StatusOK) {
+					t.Errorf("%s /v1/customers/%s reached the service with %v", method, tt.id, customerService.ids)
+				}
+			})
+		}
+	}
+}
+
+// loginCounter counts the logins of every client ip the way the lockout counter is keyed
+type loginCounter struct {
+	stubController
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (lc *loginCounter) Login(c *gin.Context) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.counts[c.ClientIP()]++
+	c.Status(http.StatusUnauthorized)
+}
+
+// TestLoginClientIP sends logins from one address with a different X-Forwarded-For each time, they have to be counted
+// against that address unless it is a trusted proxy
+func TestLoginClientIP(t *testing.T) {
+	spoofed := []string{"203.0.113.7", "198.51.100.9", "203.0.113.7, 198.51.100.9"}
+	tests := []struct {
+		name           string
+		trustedProxies string
+		want           map[string]int
+	}{
+		{name: "no trusted proxy", want: map[string]int{"192.0.2.1": 3}},
+		{name: "other trusted proxy", trustedProxies: "10.0.0.0/8", want: map[string]int{"192.0.2.1": 3}},
+		{name: "trusted proxy", trustedProxies: "10.0.0.1, 192.0.2.0/24", want: map[string]int{"203.0.113.7": 1, "198.51.100.9": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.trustedProxies)
+			s := stubController{}
+			counter := &loginCounter{counts: map[string]int{}}
+			router := NewRouter(s, counter, s, s, s, s, s, s, s, s, s, stubDenyList{})
+
+			for _, forwardedFor := range spoofed {
+				req := httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader("{}"))
+				req.RemoteAddr = "192.0.2.1:54321"
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				router.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			if len(counter.counts) != len(tt.want) {
+				t.Fatalf("logins counted by ip %v, want %v", counter.counts, tt.want)
+			}
+			for ip, want := range tt.want {
+				if counter.counts[ip] != want {
+					t.Errorf("logins counted by ip %v, want %v", counter.counts, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid trusted proxy", func(t *testing.T) {
+		t.Setenv("TRUSTED_PROXIES", "not an ip")
+		defer func() {
+			if recover() == nil {
+				t.Error("NewRouter() accepted an invalid TRUSTED_PROXIES")
+			}
+		}()
+		newTestRouter()
+	})
+}
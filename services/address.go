@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/repositories"
+	"net/http"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type addressService struct {
+	addressRepository repositories.AddressRepositoryInterface
+}
+
+type AddressServiceInterface interface {
+	GetAddresses(customerID string) (res *models.Response, err error)
+	GetAddressById(id string, customerID string) (res *models.Response, err error)
+	CreateAddress(address *models.Address) (res *models.Response, err error)
+	UpdateAddress(address *models.Address) (res *models.Response, err error)
+	DeleteAddress(id string, customerID string) (res *models.Response, err error)
+}
+
+func NewAddressService(addressRepository repositories.AddressRepositoryInterface) AddressServiceInterface {
+	return &addressService{
+		addressRepository: addressRepository,
+	}
+}
+
+func (as *addressService) GetAddresses(customerID string) (res *models.Response, err error) {
+	addresses, err := as.addressRepository.GetAddresses(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Address list successfully",
+		Data:    addresses,
+	}, nil
+}
+
+func (as *addressService) GetAddressById(id string, customerID string) (res *models.Response, err error) {
+	address, err := as.addressRepository.GetAddressById(id, customerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return addressNotFoundResponse(), nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Address get successfully",
+		Data:    address,
+	}, nil
+}
+
+func (as *addressService) CreateAddress(address *models.Address) (res *models.Response, err error) {
+	address.ID = uuid.New().String()
+	if res := validateAddress(address); res != nil {
+		return res, nil
+	}
+
+	err = as.addressRepository.CreateAddress(address)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Default address already exist", "Customer not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Address created successfully",
+		Data:    address,
+	}, nil
+}
+
+// UpdateAddress replaces the address address.ID, orders keep the address they were shipped to
+func (as *addressService) UpdateAddress(address *models.Address) (res *models.Response, err error) {
+	if res := validateAddress(address); res != nil {
+		return res, nil
+	}
+
+	err = as.addressRepository.UpdateAddress(address)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return addressNotFoundResponse(), nil
+		}
+		if res, ok := constraintResponse(err, "Default address already exist", "Customer not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Address updated successfully",
+	}, nil
+}
+
+func (as *addressService) DeleteAddress(id string, customerID string) (res *models.Response, err error) {
+	err = as.addressRepository.DeleteAddress(id, customerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return addressNotFoundResponse(), nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Address deleted successfully",
+	}, nil
+}
+
+// validateAddress normalizes the region of address
+func validateAddress(address *models.Address) *models.Response {
+	region, err := models.ParseRegion(address.Region)
+	if err != nil {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		}
+	}
+	address.Region = region
+	return nil
+}
+
+func addressNotFoundResponse() *models.Response {
+	return &models.Response{
+		Code:    http.StatusNotFound,
+		Message: "Address not exist",
+	}
+}
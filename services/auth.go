@@ -3,59 +3,412 @@ package services
 import (
 	"mvp-shop-backend/middleware"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/mailer"
 	"mvp-shop-backend/pkg/utils"
 	"mvp-shop-backend/repositories"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type authService struct {
-	customerRepository repositories.CustomerRepositoryInterface
+	customerRepository      repositories.CustomerRepositoryInterface
+	tokenRepository         repositories.TokenRepositoryInterface
+	loginAttemptRepository  repositories.LoginAttemptRepositoryInterface
+	loginAuditRepository    repositories.LoginAuditRepositoryInterface
+	customerTokenRepository repositories.CustomerTokenRepositoryInterface
+	customerTokenMailer     customerTokenMailer
+	emailLockout            repositories.LoginLockout
+	ipLockout               repositories.LoginLockout
+	requireVerifiedEmail    bool
 }
 
 type AuthServiceInterface interface {
-	Login(auth *models.AuthLogin) (res *models.Response, err error)
+	Login(auth *models.AuthLogin, ip string) (res *models.Response, err error)
+	Refresh(refreshToken string) (res *models.Response, err error)
+	Logout(customer *models.CustomerClaims, refreshToken string) (res *models.Response, err error)
+	VerifyEmail(token string) (res *models.Response, err error)
+	ResendVerifyEmail(email string) (res *models.Response, err error)
+	ForgotPassword(email string) (res *models.Response, err error)
+	ResetPassword(auth *models.AuthResetPassword) (res *models.Response, err error)
 }
 
-func NewAuthService(customerRepository repositories.CustomerRepositoryInterface) AuthServiceInterface {
+func NewAuthService(customerRepository repositories.CustomerRepositoryInterface, tokenRepository repositories.TokenRepositoryInterface, loginAttemptRepository repositories.LoginAttemptRepositoryInterface, loginAuditRepository repositories.LoginAuditRepositoryInterface, customerTokenRepository repositories.CustomerTokenRepositoryInterface, mailer mailer.Mailer) AuthServiceInterface {
+	base := utils.ParseDuration(os.Getenv("LOGIN_LOCKOUT"), time.Minute)
+	max := utils.ParseDuration(os.Getenv("LOGIN_LOCKOUT_MAX"), time.Hour)
+
 	return &authService{
-		customerRepository: customerRepository,
+		customerRepository:      customerRepository,
+		tokenRepository:         tokenRepository,
+		loginAttemptRepository:  loginAttemptRepository,
+		loginAuditRepository:    loginAuditRepository,
+		customerTokenRepository: customerTokenRepository,
+		customerTokenMailer: customerTokenMailer{
+			customerTokenRepository: customerTokenRepository,
+			mailer:                  mailer,
+		},
+		emailLockout: repositories.LoginLockout{
+			MaxFailures: envInt("LOGIN_MAX_ATTEMPTS", 5),
+			Base:        base,
+			Max:         max,
+		},
+		ipLockout: repositories.LoginLockout{
+			MaxFailures: envInt("LOGIN_MAX_ATTEMPTS_IP", 20),
+			Base:        base,
+			Max:         max,
+		},
+		requireVerifiedEmail: envBool("AUTH_REQUIRE_VERIFIED_EMAIL", false),
 	}
 }
 
-func (as *authService) Login(auth *models.AuthLogin) (res *models.Response, err error) {
+// Login answers every wrong email or password with the same error and locks the email and the ip
+// out for a while once they fail too many times in a row
+func (as *authService) Login(auth *models.AuthLogin, ip string) (res *models.Response, err error) {
+	emailKey := repositories.LoginKeyEmail + auth.Email
+	ipKey := repositories.LoginKeyIP + ip
+
+	now := time.Now()
+	for _, key := range []string{emailKey, ipKey} {
+		attempt, err := as.loginAttemptRepository.GetLoginAttempt(key)
+		if err != nil {
+			return nil, err
+		}
+		if attempt.IsLocked(now) {
+			return &models.Response{
+				Code:    http.StatusTooManyRequests,
+				Message: "Too many failed login attempts, try again later",
+			}, nil
+		}
+	}
+
 	authCust, err := as.customerRepository.GetCustomerByEmail(auth.Email)
-	if err != nil {
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err == gorm.ErrRecordNotFound || authCust.Status == models.StatusDeleted {
+		// spend the same time as a wrong password so unknown and deleted emails can't be told apart
+		utils.CheckPassword(auth.Password, dummyPasswordHash())
+		return as.failLogin(emailKey, ipKey, ip)
+	}
+	if err = utils.CheckPassword(auth.Password, authCust.Password); err != nil {
+		return as.failLogin(emailKey, ipKey, ip)
+	}
+
+	if err = as.loginAttemptRepository.ResetLoginAttempt(emailKey); err != nil {
+		return nil, err
+	}
+
+	// the password is known here, upgrade hashes created with an outdated algorithm or cost
+	if utils.PasswordNeedsRehash(authCust.Password) {
+		if err = as.rehashPassword(authCust, auth.Password); err != nil {
+			logger.Err(err.Error())
+		}
+	}
+
+	if as.requireVerifiedEmail && authCust.EmailVerifiedAt == nil {
 		return &models.Response{
-			Code:    http.StatusBadRequest,
-			Message: "Email not valid",
+			Code:    http.StatusForbidden,
+			Message: "Email not verified",
 		}, nil
 	}
 
-	err = utils.CheckPassword(auth.Password, authCust.Password)
+	token, err := newSession(as.tokenRepository, authCust)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Customer logged in successfully",
+		Data:    token,
+	}, nil
+}
+
+// Refresh rotates the refresh token and issues a new access token, a reused refresh token revokes its whole family
+func (as *authService) Refresh(refreshToken string) (res *models.Response, err error) {
+	nextToken, nextTokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := as.tokenRepository.RotateRefreshToken(utils.HashToken(refreshToken), &models.RefreshToken{
+		ID:        uuid.New().String(),
+		TokenHash: nextTokenHash,
+		ExpiresAt: time.Now().Add(middleware.RefreshTokenLifetime()),
+	})
 	if err != nil {
+		if err == gorm.ErrRecordNotFound || err == repositories.ErrRefreshTokenReused || err == repositories.ErrRefreshTokenExpired {
+			return &models.Response{
+				Code:    http.StatusUnauthorized,
+				Message: "Refresh token not valid",
+			}, nil
+		}
+		return nil, err
+	}
+
+	authCust, err := as.customerRepository.GetCustomerById(current.CustomerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusUnauthorized,
+				Message: "Refresh token not valid",
+			}, nil
+		}
+		return nil, err
+	}
+	if authCust.Status == models.StatusDeleted {
 		return &models.Response{
-			Code:    http.StatusBadRequest,
-			Message: "Password not valid",
+			Code:    http.StatusUnauthorized,
+			Message: "Refresh token not valid",
 		}, nil
 	}
 
-	customerClaims := models.CustomerClaims{
-		ID:     authCust.ID,
-		Name:   authCust.Name,
-		Email:  authCust.Email,
-		Status: authCust.Status,
+	token, err := generateAuthToken(authCust, nextToken)
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := middleware.GenerateToken(customerClaims)
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Token refreshed successfully",
+		Data:    token,
+	}, nil
+}
+
+// Logout deny-lists the current access token and revokes the family of refreshToken when given
+func (as *authService) Logout(customer *models.CustomerClaims, refreshToken string) (res *models.Response, err error) {
+	err = as.tokenRepository.RevokeAccessToken(customer.Id, time.Unix(customer.ExpiresAt, 0))
 	if err != nil {
 		return nil, err
 	}
 
+	if refreshToken != "" {
+		err = as.tokenRepository.RevokeRefreshTokenFamily(utils.HashToken(refreshToken), customer.ID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+	}
+
 	return &models.Response{
 		Code:    http.StatusOK,
-		Message: "Customer logged in successfully",
-		Data: models.AuthToken{
-			Token: token,
-		},
+		Message: "Customer logged out successfully",
+	}, nil
+}
+
+func (as *authService) VerifyEmail(token string) (res *models.Response, err error) {
+	_, err = as.customerTokenRepository.TransactionVerifyEmail(customerTokenHash(token))
+	if err != nil {
+		if err == repositories.ErrCustomerTokenInvalid {
+			return &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Token not valid or expired",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Email verified successfully",
+	}, nil
+}
+
+// ResendVerifyEmail mails a new verification token, the response is the same whether the email is registered or not
+func (as *authService) ResendVerifyEmail(email string) (res *models.Response, err error) {
+	customer, err := as.customerRepository.GetCustomerByEmail(strings.ToLower(email))
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err == nil && customer.Status != models.StatusDeleted && customer.EmailVerifiedAt == nil {
+		if err = as.customerTokenMailer.send(customer, models.CustomerTokenVerifyEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "A verification email has been sent if the email is registered and not verified yet",
+	}, nil
+}
+
+// ForgotPassword mails a password reset token, the response is the same whether the email is registered or not
+func (as *authService) ForgotPassword(email string) (res *models.Response, err error) {
+	customer, err := as.customerRepository.GetCustomerByEmail(strings.ToLower(email))
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if err == nil && customer.Status != models.StatusDeleted {
+		if err = as.customerTokenMailer.send(customer, models.CustomerTokenResetPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "A password reset email has been sent if the email is registered",
+	}, nil
+}
+
+// ResetPassword sets a new password and signs the customer out of every session
+func (as *authService) ResetPassword(auth *models.AuthResetPassword) (res *models.Response, err error) {
+	if res := validatePassword(auth.Password); res != nil {
+		return res, nil
+	}
+
+	password, err := utils.HashPassword(auth.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := as.customerTokenRepository.TransactionResetPassword(customerTokenHash(auth.Token), password)
+	if err != nil {
+		if err == repositories.ErrCustomerTokenInvalid {
+			return &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Token not valid or expired",
+			}, nil
+		}
+		return nil, err
+	}
+
+	if err = as.tokenRepository.RevokeCustomerRefreshTokens(token.CustomerID); err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Password reset successfully",
+	}, nil
+}
+
+func (as *authService) rehashPassword(customer models.Customer, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return as.customerRepository.RehashCustomerPassword(customer.ID, customer.Password, hashedPassword)
+}
+
+// failLogin counts a failed login against the email and the ip, auditing every lockout it starts
+func (as *authService) failLogin(emailKey, ipKey, ip string) (res *models.Response, err error) {
+	lockouts := map[string]repositories.LoginLockout{
+		emailKey: as.emailLockout,
+		ipKey:    as.ipLockout,
+	}
+	for _, key := range []string{emailKey, ipKey} {
+		attempt, err := as.loginAttemptRepository.FailLoginAttempt(key, lockouts[key])
+		if err != nil {
+			return nil, err
+		}
+		if !attempt.IsLocked(time.Now()) {
+			continue
+		}
+
+		logger.Warnf("login locked for %s after %d failed attempts until %s", key, attempt.Failures, attempt.LockedUntil.Format(time.RFC3339))
+		err = as.loginAuditRepository.CreateLoginAudit(&models.LoginAudit{
+			ID:          uuid.New().String(),
+			Key:         key,
+			Event:       models.LoginEventLocked,
+			IP:          ip,
+			Failures:    attempt.Failures,
+			LockedUntil: attempt.LockedUntil,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.Response{
+		Code:    http.StatusUnauthorized,
+		Message: "Invalid email or password",
+	}, nil
+}
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// dummyPasswordHash returns a bcrypt hash to compare against when the email is unknown
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword(uuid.New().String())
+	})
+	return dummyHash
+}
+
+func envInt(name string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+func envBool(name string, fallback bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// newSession starts a new refresh token family for customer and returns its first token pair
+func newSession(tokenRepository repositories.TokenRepositoryInterface, customer models.Customer) (models.AuthToken, error) {
+	refreshToken, refreshTokenHash, err := newRefreshToken()
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+	err = tokenRepository.CreateRefreshToken(&models.RefreshToken{
+		ID:         uuid.New().String(),
+		CustomerID: customer.ID,
+		FamilyID:   uuid.New().String(),
+		TokenHash:  refreshTokenHash,
+		ExpiresAt:  time.Now().Add(middleware.RefreshTokenLifetime()),
+	})
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+
+	return generateAuthToken(customer, refreshToken)
+}
+
+func newRefreshToken() (token string, tokenHash string, err error) {
+	token, err = utils.GenerateRandomToken()
+	if err != nil {
+		return "", "", err
+	}
+	return token, utils.HashToken(token), nil
+}
+
+func generateAuthToken(customer models.Customer, refreshToken string) (models.AuthToken, error) {
+	customerClaims := models.CustomerClaims{
+		ID:           customer.ID,
+		Name:         customer.Name,
+		Email:        customer.Email,
+		Role:         customer.Role,
+		Status:       customer.Status,
+		TokenVersion: customer.TokenVersion,
+	}
+
+	token, expiredAt, err := middleware.GenerateToken(customerClaims)
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+
+	return models.AuthToken{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiredAt,
 	}, nil
 }
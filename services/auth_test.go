@@ -0,0 +1,260 @@
+package services
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/repositories"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newTestAuthService returns an auth service over fakes holding customers, its emails are kept by the returned mailer
+func newTestAuthService(customers ...models.Customer) (*authService, *fakeCustomerRepository, *fakeTokenRepository, *recordingMailer) {
+	customerRepository := &fakeCustomerRepository{customers: map[string]models.Customer{}}
+	for _, customer := range customers {
+		customerRepository.customers[customer.ID] = customer
+	}
+	tokenRepository := newFakeTokenRepository(customerRepository)
+	customerTokenRepository := &fakeCustomerTokenRepository{tokens: map[string]models.CustomerToken{}, customerRepository: customerRepository}
+	mailer := &recordingMailer{}
+
+	as := NewAuthService(customerRepository, tokenRepository, repositories.NewMemoryLoginAttemptRepository(), fakeLoginAuditRepository{}, customerTokenRepository, mailer).(*authService)
+	return as, customerRepository, tokenRepository, mailer
+}
+
+func TestLoginDeletedCustomer(t *testing.T) {
+	active := testCustomer(t, "customer-1", "Secret-123")
+	deleted := testCustomer(t, "customer-2", "Secret-123")
+	deleted.Status = models.StatusDeleted
+	as, _, _, _ := newTestAuthService(active, deleted)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     int
+	}{
+		{name: "active", email: active.Email, password: "Secret-123", want: http.StatusOK},
+		{name: "wrong password", email: active.Email, password: "Secret-124", want: http.StatusUnauthorized},
+		{name: "unknown email", email: "unknown@example.com", password: "Secret-123", want: http.StatusUnauthorized},
+		{name: "deleted", email: deleted.Email, password: "Secret-123", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := as.Login(&models.AuthLogin{Email: tt.email, Password: tt.password}, "192.0.2.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Code != tt.want {
+				t.Errorf("Login() = %d %s, want %d", res.Code, res.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshDeletedCustomer(t *testing.T) {
+	customer := testCustomer(t, "customer-1", "Secret-123")
+	as, customerRepository, _, _ := newTestAuthService(customer)
+
+	res, err := as.Login(&models.AuthLogin{Email: customer.Email, Password: "Secret-123"}, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("Login() = %d %s, want 200", res.Code, res.Message)
+	}
+	token := res.Data.(models.AuthToken)
+
+	res, err = as.Refresh(token.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("Refresh() = %d %s, want 200", res.Code, res.Message)
+	}
+	token = res.Data.(models.AuthToken)
+
+	customer.Status = models.StatusDeleted
+	customerRepository.customers[customer.ID] = customer
+
+	res, err = as.Refresh(token.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh() of a deleted customer = %d %s, want 401", res.Code, res.Message)
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	customer := testCustomer(t, "customer-1", "Secret-123")
+	as, _, tokenRepository, _ := newTestAuthService(customer)
+
+	res, err := as.Login(&models.AuthLogin{Email: customer.Email, Password: "Secret-123"}, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := res.Data.(models.AuthToken)
+	claims, err := middleware.JwtClaim(token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = as.Logout(claims, token.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("Logout() = %d %s, want 200", res.Code, res.Message)
+	}
+
+	if revoked, _ := tokenRepository.IsRevoked(claims); !revoked {
+		t.Error("the access token is not deny-listed after the logout")
+	}
+	res, err = as.Refresh(token.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh() after the logout = %d %s, want 401", res.Code, res.Message)
+	}
+}
+
+// assertRevoked checks the deny-list answer for the access token tokenString
+func assertRevoked(t *testing.T, denyList middleware.TokenDenyList, tokenString string, want bool) {
+	t.Helper()
+
+	claims, err := middleware.JwtClaim(tokenString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := denyList.IsRevoked(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked != want {
+		t.Errorf("access token of version %d revoked = %v, want %v", claims.TokenVersion, revoked, want)
+	}
+}
+
+// mailedToken returns the token at the end of the body of the last email sent by m
+func mailedToken(t *testing.T, m *recordingMailer) string {
+	t.Helper()
+
+	if len(m.messages) == 0 {
+		t.Fatal("no email was sent")
+	}
+	fields := strings.Fields(m.messages[len(m.messages)-1].Body)
+	return fields[len(fields)-1]
+}
+
+func TestForgotPassword(t *testing.T) {
+	active := testCustomer(t, "customer-1", "Secret-123")
+	deleted := testCustomer(t, "customer-2", "Secret-123")
+	deleted.Status = models.StatusDeleted
+
+	tests := []struct {
+		name  string
+		email string
+		mails int
+	}{
+		{name: "registered", email: active.Email, mails: 1},
+		{name: "registered in another case", email: strings.ToUpper(active.Email), mails: 1},
+		{name: "unknown", email: "unknown@example.com", mails: 0},
+		{name: "deleted", email: deleted.Email, mails: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as, _, _, mailer := newTestAuthService(active, deleted)
+
+			res, err := as.ForgotPassword(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the response does not tell whether the email is registered
+			if res.Code != http.StatusOK {
+				t.Errorf("ForgotPassword() = %d %s, want 200", res.Code, res.Message)
+			}
+			if len(mailer.messages) != tt.mails {
+				t.Fatalf("%d emails sent, want %d", len(mailer.messages), tt.mails)
+			}
+			if tt.mails > 0 && mailer.messages[0].To != active.Email {
+				t.Errorf("email sent to %s, want %s", mailer.messages[0].To, active.Email)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Setenv("APP_URL", "")
+	customer := testCustomer(t, "customer-1", "Secret-123")
+	as, _, tokenRepository, mailer := newTestAuthService(customer)
+
+	res, err := as.Login(&models.AuthLogin{Email: customer.Email, Password: "Secret-123"}, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := res.Data.(models.AuthToken)
+
+	if _, err := as.ForgotPassword(customer.Email); err != nil {
+		t.Fatal(err)
+	}
+	replaced := mailedToken(t, mailer)
+	if _, err := as.ForgotPassword(customer.Email); err != nil {
+		t.Fatal(err)
+	}
+	token := mailedToken(t, mailer)
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		want     int
+	}{
+		{name: "replaced token", token: replaced, password: "New-Secret-456", want: http.StatusBadRequest},
+		{name: "unknown token", token: "unknown", password: "New-Secret-456", want: http.StatusBadRequest},
+		{name: "weak password", token: token, password: "secret", want: http.StatusBadRequest},
+		{name: "valid", token: token, password: "New-Secret-456", want: http.StatusOK},
+		{name: "used token", token: token, password: "Other-Secret-789", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		res, err := as.ResetPassword(&models.AuthResetPassword{Token: tt.token, Password: tt.password})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Code != tt.want {
+			t.Errorf("ResetPassword() with the %s = %d %s, want %d", tt.name, res.Code, res.Message, tt.want)
+		}
+	}
+
+	logins := []struct {
+		password string
+		want     int
+	}{
+		{password: "Secret-123", want: http.StatusUnauthorized},
+		{password: "New-Secret-456", want: http.StatusOK},
+	}
+	for _, login := range logins {
+		res, err := as.Login(&models.AuthLogin{Email: customer.Email, Password: login.password}, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Code != login.want {
+			t.Errorf("Login() with %s after the reset = %d, want %d", login.password, res.Code, login.want)
+		}
+		if res.Code == http.StatusOK {
+			assertRevoked(t, tokenRepository, res.Data.(models.AuthToken).Token, false)
+		}
+	}
+
+	assertRevoked(t, tokenRepository, session.Token, true)
+
+	res, err = as.Refresh(session.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh() of a session started before the reset = %d %s, want 401", res.Code, res.Message)
+	}
+}
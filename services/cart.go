@@ -1,43 +1,73 @@
 package services
 
 import (
+	"errors"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
 	"mvp-shop-backend/repositories"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type cartService struct {
-	cartRepository repositories.CartRepositoryInterface
+	cartRepository           repositories.CartRepositoryInterface
+	productRepository        repositories.ProductRepositoryInterface
+	productPriceRepository   repositories.ProductPriceRepositoryInterface
+	productVariantRepository repositories.ProductVariantRepositoryInterface
+	taxRuleRepository        repositories.TaxRuleRepositoryInterface
+	promotionRepository      repositories.PromotionRepositoryInterface
 }
 
 type CartServiceInterface interface {
 	CreateCart(cart *models.Cart) (res *models.Response, err error)
-	GetCartByCustomerID(id string) (res *models.Response, err error)
+	GetCartByCustomerID(id string, currency money.Currency, region string) (res *models.Response, err error)
 	UpdateCart(cart *models.CartUpdate) (res *models.Response, err error)
 	DeleteCart(cart *models.CartUpdate) (res *models.Response, err error)
+	ApplyCoupon(customerID string, code string, createdBy string, currency money.Currency, region string) (res *models.Response, err error)
+	RemoveCoupon(customerID string) (res *models.Response, err error)
 }
 
-func NewCartService(cartRepository repositories.CartRepositoryInterface) CartServiceInterface {
+func NewCartService(cartRepository repositories.CartRepositoryInterface, productRepository repositories.ProductRepositoryInterface, productPriceRepository repositories.ProductPriceRepositoryInterface, productVariantRepository repositories.ProductVariantRepositoryInterface, taxRuleRepository repositories.TaxRuleRepositoryInterface, promotionRepository repositories.PromotionRepositoryInterface) CartServiceInterface {
 	return &cartService{
-		cartRepository: cartRepository,
+		cartRepository:           cartRepository,
+		productRepository:        productRepository,
+		productPriceRepository:   productPriceRepository,
+		productVariantRepository: productVariantRepository,
+		taxRuleRepository:        taxRuleRepository,
+		promotionRepository:      promotionRepository,
 	}
 }
 
+// CreateCart adds a line to the cart, or adds its qty to the line of the same product. The line is priced with the
+// current price of the product, a price sent by the client is ignored.
 func (cs *cartService) CreateCart(cart *models.Cart) (res *models.Response, err error) {
 	if cart.Status == "" {
 		cart.Status = models.StatusActive
 	}
-	exisitingCart, err := cs.cartRepository.GetCartByCustomerIDAndProductID(cart.CustomerID, cart.ProductID)
+	variant, res, err := cs.validateCartVariant(cart)
+	if res != nil || err != nil {
+		return res, err
+	}
+	var override *money.Money
+	if variant != nil {
+		override = variant.Price
+	}
+	if cart.Price, res, err = cs.linePrice(cart.ProductID, override); res != nil || err != nil {
+		return res, err
+	}
+	exisitingCart, err := cs.cartRepository.GetCartByCustomerIDAndProductID(cart.CustomerID, cart.ProductID, cart.VariantID)
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	if exisitingCart.ID != "" {
 		cart.ID = exisitingCart.ID
 		cart.Qty = exisitingCart.Qty + cart.Qty
-		cart.Amount = cart.Qty * cart.Price
+		if cart.Amount, res = cartAmount(cart.Price, cart.Qty); res != nil {
+			return res, nil
+		}
 		err = cs.cartRepository.UpdateCart(&models.CartUpdate{
 			ID:         cart.ID,
 			CustomerID: cart.CustomerID,
@@ -49,6 +79,9 @@ func (cs *cartService) CreateCart(cart *models.Cart) (res *models.Response, err
 			UpdatedBy:  cart.CreatedBy,
 		})
 		if err != nil {
+			if res, ok := constraintResponse(err, "Product already in cart", "Product not exist"); ok {
+				return res, nil
+			}
 			return nil, err
 		}
 		return &models.Response{
@@ -58,9 +91,15 @@ func (cs *cartService) CreateCart(cart *models.Cart) (res *models.Response, err
 	}
 
 	cart.ID = uuid.New().String()
-	cart.Amount = cart.Qty * cart.Price
+	if cart.Amount, res = cartAmount(cart.Price, cart.Qty); res != nil {
+		return res, nil
+	}
 	err = cs.cartRepository.CreateCart(cart)
 	if err != nil {
+		// a concurrent request may have added the same product first
+		if res, ok := constraintResponse(err, "Product already in cart", "Product not exist"); ok {
+			return res, nil
+		}
 		return nil, err
 	}
 
@@ -70,13 +109,65 @@ func (cs *cartService) CreateCart(cart *models.Cart) (res *models.Response, err
 	}, nil
 }
 
+// validateCartVariant checks that a product with variants is added to the cart as one of them and returns it
+func (cs *cartService) validateCartVariant(cart *models.Cart) (*models.ProductVariant, *models.Response, error) {
+	variants, err := cs.productVariantRepository.GetProductVariants(cart.ProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cart.VariantID == nil {
+		if len(variants) > 0 {
+			return nil, &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Variant required for a product with variants",
+			}, nil
+		}
+		return nil, nil, nil
+	}
+	for i, variant := range variants {
+		if variant.ID == *cart.VariantID {
+			return &variants[i], nil, nil
+		}
+	}
+	return nil, &models.Response{
+		Code:    http.StatusNotFound,
+		Message: "Variant not exist",
+	}, nil
+}
+
+// UpdateCart changes the qty of a cart line and prices it again with the current price of its product
 func (cs *cartService) UpdateCart(cart *models.CartUpdate) (res *models.Response, err error) {
 	if cart.Status == "" {
 		cart.Status = models.StatusActive
 	}
-	cart.Amount = cart.Qty * cart.Price
+	line, err := cs.cartRepository.GetCartById(cart.ID, cart.CustomerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Cart not exist",
+			}, nil
+		}
+		return nil, err
+	}
+	cart.ProductID = line.ProductID
+	if cart.Price, res, err = cs.linePrice(line.ProductID, line.VariantPrice); res != nil || err != nil {
+		return res, err
+	}
+	if cart.Amount, res = cartAmount(cart.Price, cart.Qty); res != nil {
+		return res, nil
+	}
 	err = cs.cartRepository.UpdateCart(cart)
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Cart not exist",
+			}, nil
+		}
+		if res, ok := constraintResponse(err, "Product already in cart", "Product not exist"); ok {
+			return res, nil
+		}
 		return nil, err
 	}
 	return &models.Response{
@@ -86,34 +177,213 @@ func (cs *cartService) UpdateCart(cart *models.CartUpdate) (res *models.Response
 
 }
 
-func (cs *cartService) GetCartByCustomerID(id string) (res *models.Response, err error) {
+// linePrice returns the price of productID in the default currency, override is the price of its variant when it
+// replaces the price of the product
+func (cs *cartService) linePrice(productID string, override *money.Money) (money.Money, *models.Response, error) {
+	product, err := cs.productRepository.GetProductById(productID)
+	if err != nil {
+		return money.Money{}, nil, err
+	}
+	if product.ID == "" {
+		return money.Money{}, &models.Response{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Product not exist",
+		}, nil
+	}
+	if override != nil {
+		return override.WithCurrency(money.DefaultCurrency()), nil, nil
+	}
+	return product.Price, nil, nil
+}
 
-	carts, err := cs.cartRepository.GetCartByCustomerID(id)
+// GetCartByCustomerID returns the cart lines priced in currency, discounted with the applied coupon and taxed with the
+// rules of region, the lines are stored in the default currency
+func (cs *cartService) GetCartByCustomerID(id string, currency money.Currency, region string) (res *models.Response, err error) {
+	var coupon *models.Promotion
+	promotion, err := cs.cartRepository.GetCartCoupon(id)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == nil {
+		coupon = &promotion
+	}
+
+	cartView, res, err := cs.cartView(id, currency, region, coupon)
+	if res != nil || err != nil {
+		return res, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Cart get successfully",
+		Data:    cartView,
+	}, nil
+}
+
+// ApplyCoupon applies the promotion of code to the cart of customerID when it discounts the cart, it replaces the
+// coupon applied before
+func (cs *cartService) ApplyCoupon(customerID string, code string, createdBy string, currency money.Currency, region string) (res *models.Response, err error) {
+	promotion, err := cs.promotionRepository.GetPromotionByCode(code)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return &models.Response{
 				Code:    http.StatusNotFound,
-				Message: "Cart not exist",
+				Message: "Coupon not exist",
 			}, nil
 		}
 		return nil, err
 	}
 
-	var totalAmount float64
-	for _, cart := range carts {
-		totalAmount += cart.Amount
+	cartView, res, err := cs.cartView(customerID, currency, region, &promotion)
+	if res != nil || err != nil {
+		return res, err
+	}
+	if cartView.CouponError != "" {
+		return &models.Response{
+			Code:    http.StatusUnprocessableEntity,
+			Message: cartView.CouponError,
+		}, nil
+	}
+
+	err = cs.cartRepository.SaveCartCoupon(&models.CartCoupon{
+		CustomerID:  customerID,
+		PromotionID: promotion.ID,
+		CreatedBy:   createdBy,
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &models.Response{
 		Code:    http.StatusOK,
-		Message: "Cart get successfully",
-		Data:    models.CartView{Products: carts, TotalAmount: totalAmount},
+		Message: "Coupon applied successfully",
+		Data:    cartView,
 	}, nil
 }
 
+func (cs *cartService) RemoveCoupon(customerID string) (res *models.Response, err error) {
+	err = cs.cartRepository.DeleteCartCoupon(customerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Coupon not applied",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Coupon removed successfully",
+	}, nil
+}
+
+// cartView prices the cart of id like TransactionCheckout would, a coupon which does not discount the cart is
+// reported in CouponError
+func (cs *cartService) cartView(id string, currency money.Currency, region string, coupon *models.Promotion) (cartView models.CartView, res *models.Response, err error) {
+	carts, err := cs.cartRepository.GetCartByCustomerID(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return cartView, &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Cart not exist",
+			}, nil
+		}
+		return cartView, nil, err
+	}
+
+	productIDs := make([]string, len(carts))
+	for i, cart := range carts {
+		productIDs[i] = cart.ProductID
+	}
+	priceList, err := cs.productPriceRepository.GetPriceList(currency, productIDs)
+	if err != nil {
+		if err == repositories.ErrCurrencyNotSupported {
+			return cartView, currencyNotSupportedResponse(), nil
+		}
+		return cartView, nil, err
+	}
+
+	taxTable, err := cs.taxRuleRepository.GetTaxTable(region)
+	if err != nil {
+		return cartView, nil, err
+	}
+
+	lines := make([]models.PromotionLine, len(carts))
+	for i, cart := range carts {
+		if carts[i].Price, err = priceList.VariantPrice(cart.ProductID, cart.Price, cart.VariantPrice); err != nil {
+			return cartView, nil, err
+		}
+		if carts[i].Amount, err = carts[i].Price.MulQty(cart.Qty); err != nil {
+			return cartView, nil, err
+		}
+		carts[i].DiscountAmount = money.Zero(currency)
+		lines[i] = models.PromotionLine{
+			ProductID:  cart.ProductID,
+			CategoryID: cart.CategoryID,
+			Qty:        cart.Qty,
+			Price:      carts[i].Price,
+			Amount:     carts[i].Amount,
+		}
+	}
+
+	cartView = models.CartView{Products: carts, Totals: models.NewTotals(currency)}
+	if coupon != nil {
+		cartView.CouponCode = &coupon.Code
+		discounts, err := cs.couponDiscounts(id, coupon, lines, priceList.Rate)
+		var promotionErr *models.PromotionError
+		switch {
+		case errors.As(err, &promotionErr):
+			cartView.CouponError = promotionErr.Reason
+		case err != nil:
+			return cartView, nil, err
+		default:
+			for i := range carts {
+				carts[i].DiscountAmount = discounts[i]
+			}
+		}
+	}
+
+	for i, cart := range carts {
+		taxable, err := cart.Amount.Sub(cart.DiscountAmount)
+		if err != nil {
+			return cartView, nil, err
+		}
+		if carts[i].LineTax, err = taxTable.LineTax(cart.CategoryID, taxable); err != nil {
+			return cartView, nil, err
+		}
+		if err = cartView.Totals.AddLine(cart.Amount, cart.DiscountAmount, carts[i].LineTax); err != nil {
+			return cartView, nil, err
+		}
+	}
+	if cartView.TotalAmount, err = cartView.Totals.GrandTotal(); err != nil {
+		return cartView, nil, err
+	}
+	return cartView, nil, nil
+}
+
+// couponDiscounts checks customerID can use coupon now and returns the discount of every line
+func (cs *cartService) couponDiscounts(customerID string, coupon *models.Promotion, lines []models.PromotionLine, rate money.Rate) ([]money.Money, error) {
+	uses, err := cs.promotionRepository.CountRedemptions(coupon.ID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := coupon.Check(time.Now(), uses); err != nil {
+		return nil, err
+	}
+	return coupon.Discounts(lines, rate)
+}
+
 func (cs *cartService) DeleteCart(cart *models.CartUpdate) (res *models.Response, err error) {
 	err = cs.cartRepository.DeleteCart(cart)
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Cart not exist",
+			}, nil
+		}
 		return nil, err
 	}
 
@@ -122,3 +392,18 @@ func (cs *cartService) DeleteCart(cart *models.CartUpdate) (res *models.Response
 		Message: "Cart deleted successfully",
 	}, nil
 }
+
+// cartAmount prices a cart line, the amount is rounded to the currency minor unit
+func cartAmount(price money.Money, qty float64) (money.Money, *models.Response) {
+	if res := validatePrice(price); res != nil {
+		return money.Money{}, res
+	}
+	amount, err := price.MulQty(qty)
+	if err != nil {
+		return money.Money{}, &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid qty",
+		}
+	}
+	return amount, nil
+}
@@ -3,6 +3,8 @@ package services
 import (
 	"math"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/mailer"
 	"mvp-shop-backend/pkg/utils"
 	"mvp-shop-backend/repositories"
 	"net/http"
@@ -13,7 +15,9 @@ import (
 )
 
 type customerService struct {
-	customerRepository repositories.CustomerRepositoryInterface
+	customerRepository  repositories.CustomerRepositoryInterface
+	tokenRepository     repositories.TokenRepositoryInterface
+	customerTokenMailer customerTokenMailer
 }
 
 type CustomerServiceInterface interface {
@@ -22,11 +26,18 @@ type CustomerServiceInterface interface {
 	GetCustomers(filter map[string][]string) (res *models.Response, err error)
 	UpdateCustomer(customer *models.CustomerUpdate) (res *models.Response, err error)
 	DeleteCustomer(customer *models.CustomerUpdate) (res *models.Response, err error)
+	UpdateProfile(customer *models.CustomerClaims, profile *models.CustomerProfileUpdate) (res *models.Response, err error)
+	ChangePassword(customer *models.CustomerClaims, change *models.CustomerPasswordChange) (res *models.Response, err error)
 }
 
-func NewCustomerService(customerRepository repositories.CustomerRepositoryInterface) CustomerServiceInterface {
+func NewCustomerService(customerRepository repositories.CustomerRepositoryInterface, tokenRepository repositories.TokenRepositoryInterface, customerTokenRepository repositories.CustomerTokenRepositoryInterface, mailer mailer.Mailer) CustomerServiceInterface {
 	return &customerService{
 		customerRepository: customerRepository,
+		tokenRepository:    tokenRepository,
+		customerTokenMailer: customerTokenMailer{
+			customerTokenRepository: customerTokenRepository,
+			mailer:                  mailer,
+		},
 	}
 }
 
@@ -46,6 +57,10 @@ func (cs *customerService) CreateCustomer(customer *models.Customer) (res *model
 		}, nil
 	}
 
+	if res := validatePassword(customer.Password); res != nil {
+		return res, nil
+	}
+
 	password, err := utils.HashPassword(customer.Password)
 	if err != nil {
 		return nil, err
@@ -53,12 +68,21 @@ func (cs *customerService) CreateCustomer(customer *models.Customer) (res *model
 
 	customer.ID = uuid.New().String()
 	customer.Password = password
+	customer.Role = models.RoleCustomer
 	customer.Status = models.StatusActive
 	err = cs.customerRepository.CreateCustomer(customer)
 	if err != nil {
+		if res, ok := constraintResponse(err, "Email already exist", "Invalid customer"); ok {
+			return res, nil
+		}
 		return nil, err
 	}
 
+	// the customer is created anyway, a new verification email can be requested
+	if err = cs.customerTokenMailer.send(*customer, models.CustomerTokenVerifyEmail); err != nil {
+		logger.Err(err.Error())
+	}
+
 	return &models.Response{
 		Code:    http.StatusCreated,
 		Message: "Customer created successfully",
@@ -114,7 +138,15 @@ func (cs *customerService) GetCustomers(filter map[string][]string) (res *models
 	}, nil
 }
 
+// UpdateCustomer updates the non empty fields of customer, the role has to be admin or customer
 func (cs *customerService) UpdateCustomer(customer *models.CustomerUpdate) (res *models.Response, err error) {
+	if customer.Role != "" && !customer.Role.IsValid() {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Role must be admin or customer",
+		}, nil
+	}
+
 	err = cs.customerRepository.UpdateCustomer(customer)
 	if err != nil {
 		return nil, err
@@ -137,3 +169,129 @@ func (cs *customerService) DeleteCustomer(customer *models.CustomerUpdate) (res
 		Message: "Customer deleted successfully",
 	}, nil
 }
+
+// UpdateProfile updates the name and the email of the customer, a new email is mailed a verification token
+func (cs *customerService) UpdateProfile(customer *models.CustomerClaims, profile *models.CustomerProfileUpdate) (res *models.Response, err error) {
+	current, err := cs.customerRepository.GetCustomerById(customer.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Customer not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	profile.Email = strings.ToLower(profile.Email)
+	if profile.Email == current.Email {
+		profile.Email = ""
+	}
+	if profile.Email != "" {
+		exists, err := cs.customerRepository.GetCustomerByEmail(profile.Email)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
+		if exists.ID != "" {
+			return &models.Response{
+				Code:    http.StatusFound,
+				Message: "Email already exist",
+			}, nil
+		}
+	}
+
+	err = cs.customerRepository.UpdateCustomerProfile(customer.ID, profile, customer.Name)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Email already exist", "Invalid customer"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	updated, err := cs.customerRepository.GetCustomerById(customer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.Email != "" {
+		if err = cs.customerTokenMailer.send(updated, models.CustomerTokenVerifyEmail); err != nil {
+			logger.Err(err.Error())
+		}
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Profile updated successfully",
+		Data:    updated,
+	}, nil
+}
+
+// ChangePassword sets a new password after checking the current one, every other session is signed out
+// and a new token pair is returned for the current one
+func (cs *customerService) ChangePassword(customer *models.CustomerClaims, change *models.CustomerPasswordChange) (res *models.Response, err error) {
+	currentPassword, err := cs.customerRepository.GetCustomerPassword(customer.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Customer not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	if err = utils.CheckPassword(change.CurrentPassword, currentPassword); err != nil {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Current password not valid",
+		}, nil
+	}
+
+	if res := validatePassword(change.NewPassword); res != nil {
+		return res, nil
+	}
+
+	password, err := utils.HashPassword(change.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cs.customerRepository.UpdateCustomerPassword(customer.ID, password, customer.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the password change bumped the token version, the access tokens issued before are refused
+	if err = cs.tokenRepository.RevokeCustomerRefreshTokens(customer.ID); err != nil {
+		return nil, err
+	}
+
+	updated, err := cs.customerRepository.GetCustomerById(customer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newSession(cs.tokenRepository, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Password changed successfully",
+		Data:    token,
+	}, nil
+}
+
+// validatePassword returns the 400 response listing the policy violations of password, nil when it is valid
+func validatePassword(password string) *models.Response {
+	err := utils.ValidatePassword(password)
+	if policyErr, ok := err.(*utils.PasswordPolicyError); ok {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Password does not meet the policy",
+			Data:    policyErr.Violations,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"mvp-shop-backend/middleware"
+	"mvp-shop-backend/models"
+	"net/http"
+	"testing"
+)
+
+// TestChangePasswordSignsOutOtherSessions changes the password from one of two sessions, the access and refresh tokens
+// of both are refused afterwards while the token pair returned by the change is accepted
+func TestChangePasswordSignsOutOtherSessions(t *testing.T) {
+	customer := testCustomer(t, "customer-1", "Secret-123")
+	as, customerRepository, tokenRepository, _ := newTestAuthService(customer)
+	cs := NewCustomerService(customerRepository, tokenRepository, nil, nil)
+
+	sessions := make([]models.AuthToken, 2)
+	for i := range sessions {
+		res, err := as.Login(&models.AuthLogin{Email: customer.Email, Password: "Secret-123"}, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[i] = res.Data.(models.AuthToken)
+	}
+	claims, err := middleware.JwtClaim(sessions[0].Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cs.ChangePassword(claims, &models.CustomerPasswordChange{CurrentPassword: "Secret-124", NewPassword: "New-Secret-456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("ChangePassword() with a wrong current password = %d %s, want 400", res.Code, res.Message)
+	}
+	for _, session := range sessions {
+		assertRevoked(t, tokenRepository, session.Token, false)
+	}
+
+	res, err = cs.ChangePassword(claims, &models.CustomerPasswordChange{CurrentPassword: "Secret-123", NewPassword: "New-Secret-456"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("ChangePassword() = %d %s, want 200", res.Code, res.Message)
+	}
+	changed := res.Data.(models.AuthToken)
+
+	for _, session := range sessions {
+		assertRevoked(t, tokenRepository, session.Token, true)
+
+		res, err := as.Refresh(session.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Code != http.StatusUnauthorized {
+			t.Errorf("Refresh() of a session started before the change = %d %s, want 401", res.Code, res.Message)
+		}
+	}
+
+	assertRevoked(t, tokenRepository, changed.Token, false)
+	res, err = as.Refresh(changed.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Code != http.StatusOK {
+		t.Fatalf("Refresh() of the session of the change = %d %s, want 200", res.Code, res.Message)
+	}
+	assertRevoked(t, tokenRepository, res.Data.(models.AuthToken).Token, false)
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/mailer"
+	"mvp-shop-backend/pkg/utils"
+	"mvp-shop-backend/repositories"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// customerTokenMailer creates the single-use tokens of the email verification and password reset flows and mails them
+type customerTokenMailer struct {
+	customerTokenRepository repositories.CustomerTokenRepositoryInterface
+	mailer                  mailer.Mailer
+}
+
+func (ctm customerTokenMailer) send(customer models.Customer, purpose models.CustomerTokenPurpose) error {
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	lifetime, subject, path := customerTokenMail(purpose)
+	err = ctm.customerTokenRepository.CreateCustomerToken(&models.CustomerToken{
+		ID:         uuid.New().String(),
+		CustomerID: customer.ID,
+		Purpose:    purpose,
+		TokenHash:  customerTokenHash(token),
+		ExpiresAt:  time.Now().Add(lifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nUse this token within %s: %s\n", customer.Name, lifetime, token)
+	if appURL := os.Getenv("APP_URL"); appURL != "" {
+		body = fmt.Sprintf("Hi %s,\n\nOpen this link within %s: %s%s?token=%s\n", customer.Name, lifetime, strings.TrimSuffix(appURL, "/"), path, token)
+	}
+
+	return ctm.mailer.Send(context.Background(), mailer.Message{
+		To:      customer.Email,
+		Subject: subject,
+		Body:    body,
+	})
+}
+
+func customerTokenMail(purpose models.CustomerTokenPurpose) (lifetime time.Duration, subject string, path string) {
+	switch purpose {
+	case models.CustomerTokenResetPassword:
+		return utils.ParseDuration(os.Getenv("AUTH_RESET_PASSWORD_EXPIRED"), time.Hour), "Reset your password", "/reset-password"
+	default:
+		return utils.ParseDuration(os.Getenv("AUTH_VERIFY_EMAIL_EXPIRED"), 24*time.Hour), "Verify your email", "/verify-email"
+	}
+}
+
+// customerTokenHash signs token with CUSTOMER_TOKEN_SECRET, SECRET_KEY when it is empty
+func customerTokenHash(token string) string {
+	secret := os.Getenv("CUSTOMER_TOKEN_SECRET")
+	if secret == "" {
+		secret = os.Getenv("SECRET_KEY")
+	}
+	return utils.SignToken(token, secret)
+}
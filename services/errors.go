@@ -0,0 +1,27 @@
+package services
+
+import (
+	"errors"
+	"mvp-shop-backend/models"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// constraintResponse turns a violated unique constraint into a 409 with conflict and a violated foreign key or
+// check constraint into a 422 with unprocessable, ok is false for any other error
+func constraintResponse(err error, conflict string, unprocessable string) (res *models.Response, ok bool) {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &models.Response{
+			Code:    http.StatusConflict,
+			Message: conflict,
+		}, true
+	case errors.Is(err, gorm.ErrForeignKeyViolated), errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return &models.Response{
+			Code:    http.StatusUnprocessableEntity,
+			Message: unprocessable,
+		}, true
+	}
+	return nil, false
+}
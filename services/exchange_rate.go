@@ -0,0 +1,94 @@
+package services
+
+import (
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+	"mvp-shop-backend/repositories"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+type exchangeRateService struct {
+	exchangeRateRepository repositories.ExchangeRateRepositoryInterface
+}
+
+type ExchangeRateServiceInterface interface {
+	GetExchangeRates() (res *models.Response, err error)
+	SaveExchangeRate(rate *models.ExchangeRate) (res *models.Response, err error)
+	DeleteExchangeRate(currency money.Currency) (res *models.Response, err error)
+}
+
+func NewExchangeRateService(exchangeRateRepository repositories.ExchangeRateRepositoryInterface) ExchangeRateServiceInterface {
+	return &exchangeRateService{
+		exchangeRateRepository: exchangeRateRepository,
+	}
+}
+
+func (es *exchangeRateService) GetExchangeRates() (res *models.Response, err error) {
+	rates, err := es.exchangeRateRepository.GetExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Exchange rate list successfully",
+		Data:    models.ListExchangeRate{Base: money.DefaultCurrency(), Rates: rates},
+	}, nil
+}
+
+// SaveExchangeRate sets the rate of rate.Currency, orders keep the rate they were created with
+func (es *exchangeRateService) SaveExchangeRate(rate *models.ExchangeRate) (res *models.Response, err error) {
+	if res := validateForeignCurrency(rate.Currency); res != nil {
+		return res, nil
+	}
+
+	err = es.exchangeRateRepository.SaveExchangeRate(rate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Exchange rate saved successfully",
+		Data:    rate,
+	}, nil
+}
+
+// DeleteExchangeRate stops selling in currency, the prices set in currency are kept for when a rate is set again
+func (es *exchangeRateService) DeleteExchangeRate(currency money.Currency) (res *models.Response, err error) {
+	err = es.exchangeRateRepository.DeleteExchangeRate(currency.String())
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Exchange rate not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Exchange rate deleted successfully",
+	}, nil
+}
+
+// validateForeignCurrency refuses the default currency, it has no exchange rate and its prices are the product prices
+func validateForeignCurrency(currency money.Currency) *models.Response {
+	if currency == money.DefaultCurrency() {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: currency.String() + " is the default currency",
+		}
+	}
+	return nil
+}
+
+func currencyNotSupportedResponse() *models.Response {
+	return &models.Response{
+		Code:    http.StatusBadRequest,
+		Message: "Currency not supported",
+	}
+}
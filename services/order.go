@@ -1,93 +1,334 @@
 package services
 
 import (
-	"context"
+	"errors"
 	"fmt"
+	"math"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/payment"
+	"mvp-shop-backend/pkg/shipping"
 	"mvp-shop-backend/pkg/utils"
 	"mvp-shop-backend/repositories"
 	"net/http"
-	"sync"
+	"strings"
 
-	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
 )
 
 type orderService struct {
 	orderRepository   repositories.OrderRepositoryInterface
 	productRepository repositories.ProductRepositoryInterface
+	addressRepository repositories.AddressRepositoryInterface
+	shippingMethods   shipping.Methods
+	paymentRepository repositories.PaymentRepositoryInterface
+	paymentProvider   payment.PaymentProvider
 }
 
 type OrderServiceInterface interface {
-	CreateOrder(order *models.Order, orderDetail *[]models.OrderDetail) (res *models.Response, err error)
+	CreateOrder(order *models.Order, orderDetail *[]models.OrderDetail, addressID string, shippingMethod string) (res *models.Response, err error)
+	CheckoutOrder(order *models.Order, addressID string, shippingMethod string) (res *models.Response, err error)
+	GetMyOrders(filter map[string][]string, customerID string) (res *models.Response, err error)
+	GetMyOrderByInvoice(invoice string, customerID string) (res *models.Response, err error)
+	CancelOrder(invoice string, customerID string, updatedBy string) (res *models.Response, err error)
+	ShipOrder(invoice string, updatedBy string) (res *models.Response, err error)
+	DeliverOrder(invoice string, updatedBy string) (res *models.Response, err error)
 }
 
-func NewOrderService(orderRepository repositories.OrderRepositoryInterface, productRepository repositories.ProductRepositoryInterface) OrderServiceInterface {
+func NewOrderService(orderRepository repositories.OrderRepositoryInterface, productRepository repositories.ProductRepositoryInterface, addressRepository repositories.AddressRepositoryInterface, shippingMethods shipping.Methods, paymentRepository repositories.PaymentRepositoryInterface, paymentProvider payment.PaymentProvider) OrderServiceInterface {
 	return &orderService{
 		orderRepository:   orderRepository,
 		productRepository: productRepository,
+		addressRepository: addressRepository,
+		shippingMethods:   shippingMethods,
+		paymentRepository: paymentRepository,
+		paymentProvider:   paymentProvider,
 	}
 }
 
-func (os *orderService) CreateOrder(order *models.Order, orderDetail *[]models.OrderDetail) (res *models.Response, err error) {
-	// Simulate concurrent order insertions
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+// CreateOrder ships order to the address addressID of the customer, or to its default address, with shippingMethod
+func (os *orderService) CreateOrder(order *models.Order, orderDetail *[]models.OrderDetail, addressID string, shippingMethod string) (res *models.Response, err error) {
+	order.Invoice = utils.GenerateInvoice()
+	order.Status = models.StatusActive
+	order.OrderStatus = models.OrderStatusPending
+
+	calculator, res, err := os.shipOrder(order, addressID, shippingMethod)
+	if res != nil || err != nil {
+		return res, err
+	}
+
+	for i := range *orderDetail {
+		(*orderDetail)[i].CreatedBy = order.CreatedBy
+	}
+
+	err = os.orderRepository.TransactionOrder(order, orderDetail, calculator)
+	if err != nil {
+		return orderErrorResponse(err)
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Order created successfully",
+	}, nil
+}
 
-	errs, _ := errgroup.WithContext(context.Background())
+// CheckoutOrder ships the cart to the address addressID of the customer, or to its default address, with shippingMethod
+func (os *orderService) CheckoutOrder(order *models.Order, addressID string, shippingMethod string) (res *models.Response, err error) {
+	order.Invoice = utils.GenerateInvoice()
+	order.Status = models.StatusActive
+	order.OrderStatus = models.OrderStatusPending
 
-	var arrOrderDetail []models.OrderDetail
-	var amountOrder float64
-	invoice := utils.GenerateInvoice()
+	calculator, res, err := os.shipOrder(order, addressID, shippingMethod)
+	if res != nil || err != nil {
+		return res, err
+	}
 
-	for _, v := range *orderDetail {
-		product, err := os.productRepository.GetProductById(v.ProductID)
-		if err != nil {
+	_, err = os.orderRepository.TransactionCheckout(order, calculator)
+	if err != nil {
+		if err == repositories.ErrCartEmpty {
 			return &models.Response{
-				Code:    http.StatusNotFound,
-				Message: "Product Not Found",
-			}, err
+				Code:    http.StatusBadRequest,
+				Message: "Cart is empty",
+			}, nil
 		}
+		return orderErrorResponse(err)
+	}
 
-		if product.Stock == 0 {
-			continue
-		}
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Order created successfully",
+		Data:    order,
+	}, nil
+}
 
-		amountDetail := v.Qty * product.Price
-		detail := models.OrderDetail{
-			Invoice:   invoice,
-			ProductID: v.ProductID,
-			Qty:       v.Qty,
-			Price:     product.Price,
-			Amount:    amountDetail,
-			CreatedBy: v.CreatedBy,
-		}
-		amountOrder += amountDetail
-		detail.Status = models.StatusActive
-		arrOrderDetail = append(arrOrderDetail, detail)
+func (os *orderService) GetMyOrders(filter map[string][]string, customerID string) (res *models.Response, err error) {
+	pagination, search := utils.GeneratePaginationFromRequest(filter)
+	orders, count, err := os.orderRepository.GetMyOrders(pagination, search, customerID)
+	if err != nil {
+		return nil, err
 	}
 
-	order.Invoice = invoice
-	order.Amount = amountOrder
-	order.Status = models.StatusActive
+	if count == 0 {
+		return &models.Response{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}, nil
+	}
+
+	data := models.ListOrder{
+		Page:      pagination.Page,
+		Limit:     pagination.Limit,
+		Total:     int(count),
+		TotalPage: int(math.Ceil(float64(count) / float64(pagination.Limit))),
+		Orders:    orders,
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Order list successfully",
+		Data:    data,
+	}, nil
+}
 
-	wg.Add(1)
-	errs.Go(func() error {
-		errGo := os.orderRepository.TransactionOrder(order, &arrOrderDetail, &wg, &mu)
-		if errGo != nil {
-			return fmt.Errorf("error in go routine, TransactionOrder, %+v", errGo)
+func (os *orderService) GetMyOrderByInvoice(invoice string, customerID string) (res *models.Response, err error) {
+	order, err := os.orderRepository.GetMyOrderByInvoice(invoice, customerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Order not exist",
+			}, nil
 		}
-		return nil
-	})
+		return nil, err
+	}
 
-	// Wait for completion and return the first error (if any)
-	if err := errs.Wait(); err != nil {
+	details, err := os.orderRepository.GetMyOrderDetails(order.Invoice)
+	if err != nil {
 		return nil, err
 	}
+	for i := range details {
+		details[i].Price = details[i].Price.WithCurrency(order.Currency)
+		details[i].Amount = details[i].Amount.WithCurrency(order.Currency)
+		details[i].DiscountAmount = details[i].DiscountAmount.WithCurrency(order.Currency)
+		details[i].TaxAmount = details[i].TaxAmount.WithCurrency(order.Currency)
+	}
 
-	wg.Wait()
+	history, err := os.orderRepository.GetOrderStatusHistory(order.Invoice)
+	if err != nil {
+		return nil, err
+	}
 
 	return &models.Response{
-		Code:    http.StatusCreated,
-		Message: "Order created successfully",
+		Code:    http.StatusOK,
+		Message: "Order get successfully",
+		Data:    models.OrderView{Order: order, Details: details, History: history},
+	}, nil
+}
+
+// CancelOrder cancels the order of the customer. The payment of a paid order is refunded through the payment provider
+// once the cancellation is committed, a failed refund stays refund_pending and is retried by RetryRefunds.
+func (os *orderService) CancelOrder(invoice string, customerID string, updatedBy string) (res *models.Response, err error) {
+	order, refund, err := os.orderRepository.TransactionCancelOrder(invoice, customerID, updatedBy)
+	if err == repositories.ErrPaymentNotRefundable {
+		return &models.Response{
+			Code:    http.StatusConflict,
+			Message: "Order payment cannot be refunded",
+		}, nil
+	}
+	if err != nil || refund == nil {
+		return transitionResponse(order, err, "Order cancelled successfully")
+	}
+
+	if err := refundPayment(os.paymentProvider, os.paymentRepository, *refund, updatedBy); err != nil {
+		logger.Warnf("order %s cancelled, its refund is pending: %v", order.Invoice, err)
+		return transitionResponse(order, nil, "Order cancelled successfully, the refund is pending")
+	}
+	return transitionResponse(order, nil, "Order cancelled successfully")
+}
+
+func (os *orderService) ShipOrder(invoice string, updatedBy string) (res *models.Response, err error) {
+	return os.transitionOrder(invoice, "", models.OrderStatusShipped, updatedBy, "Order shipped successfully")
+}
+
+func (os *orderService) DeliverOrder(invoice string, updatedBy string) (res *models.Response, err error) {
+	return os.transitionOrder(invoice, "", models.OrderStatusDelivered, updatedBy, "Order delivered successfully")
+}
+
+func (os *orderService) transitionOrder(invoice string, customerID string, to models.OrderStatus, updatedBy string, message string) (res *models.Response, err error) {
+	order, err := os.orderRepository.TransitionOrder(invoice, customerID, to, updatedBy)
+	return transitionResponse(order, err, message)
+}
+
+// transitionResponse maps the errors of an order transition to a response
+func transitionResponse(order models.Order, err error, message string) (*models.Response, error) {
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Order not exist",
+			}, nil
+		}
+
+		var transitionErr *repositories.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			return &models.Response{
+				Code:    http.StatusConflict,
+				Message: fmt.Sprintf("Order is %s and cannot be %s", transitionErr.From, transitionErr.To),
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    order,
 	}, nil
 }
+
+// shipOrder copies the address addressID of the customer of order, or its default address, into order and returns the
+// calculator of the shipping fee. The order is taxed in the region of the address. Orders of customers without address
+// and orders placed while no shipping method is configured have no shipping fee.
+func (os *orderService) shipOrder(order *models.Order, addressID string, shippingMethod string) (calculator shipping.ShippingRateCalculator, res *models.Response, err error) {
+	var address models.Address
+	if addressID != "" {
+		address, err = os.addressRepository.GetAddressById(addressID, order.CustomerID)
+		if err == gorm.ErrRecordNotFound {
+			return nil, addressNotFoundResponse(), nil
+		}
+	} else {
+		address, err = os.addressRepository.GetDefaultAddress(order.CustomerID)
+		if err == gorm.ErrRecordNotFound {
+			if shippingMethod != "" {
+				return nil, &models.Response{
+					Code:    http.StatusBadRequest,
+					Message: "Shipping address required",
+				}, nil
+			}
+			return nil, nil, nil
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order.ShippingAddress = address.Snapshot()
+	order.Region = &address.Region
+	if len(os.shippingMethods) == 0 {
+		if shippingMethod == "" {
+			return nil, nil, nil
+		}
+		return nil, &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Shipping method not available",
+		}, nil
+	}
+
+	calculator, ok := os.shippingMethods[shippingMethod]
+	if !ok {
+		return nil, &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Shipping method must be one of " + strings.Join(os.shippingMethods.Names(), ", "),
+		}, nil
+	}
+	order.ShippingMethod = &shippingMethod
+	return calculator, nil, nil
+}
+
+// orderErrorResponse maps the errors of an order transaction to a response
+func orderErrorResponse(err error) (res *models.Response, errRes error) {
+	if errors.Is(err, repositories.ErrCurrencyNotSupported) {
+		return currencyNotSupportedResponse(), nil
+	}
+
+	if errors.Is(err, shipping.ErrNotDeliverable) {
+		return &models.Response{
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Shipping method does not deliver to the address",
+		}, nil
+	}
+
+	if errors.Is(err, repositories.ErrVariantRequired) {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Variant required for a product with variants",
+		}, nil
+	}
+
+	if errors.Is(err, repositories.ErrVariantNotFound) {
+		return &models.Response{
+			Code:    http.StatusNotFound,
+			Message: "Variant not exist",
+		}, nil
+	}
+
+	var promotionErr *models.PromotionError
+	if errors.As(err, &promotionErr) {
+		return &models.Response{
+			Code:    http.StatusUnprocessableEntity,
+			Message: promotionErr.Reason,
+		}, nil
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		return &models.Response{
+			Code:    http.StatusNotFound,
+			Message: "Product Not Found",
+		}, nil
+	}
+
+	var stockErr *repositories.InsufficientStockError
+	if errors.As(err, &stockErr) {
+		return &models.Response{
+			Code:    http.StatusConflict,
+			Message: "Insufficient stock",
+			Data:    stockErr.Products,
+		}, nil
+	}
+
+	if res, ok := constraintResponse(err, "Order already exist", "Product Not Found"); ok {
+		return res, nil
+	}
+
+	return nil, err
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/payment"
+	"mvp-shop-backend/repositories"
+	"net/http"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type paymentService struct {
+	paymentRepository repositories.PaymentRepositoryInterface
+	orderRepository   repositories.OrderRepositoryInterface
+	paymentProvider   payment.PaymentProvider
+}
+
+type PaymentServiceInterface interface {
+	PayOrder(invoice string, customerID string, createdBy string) (res *models.Response, err error)
+	ConfirmPayment(id string, customerID string) (res *models.Response, err error)
+	HandleWebhook(payload []byte, signature string) (res *models.Response, err error)
+	RetryRefunds() error
+}
+
+func NewPaymentService(paymentRepository repositories.PaymentRepositoryInterface, orderRepository repositories.OrderRepositoryInterface, paymentProvider payment.PaymentProvider) PaymentServiceInterface {
+	return &paymentService{
+		paymentRepository: paymentRepository,
+		orderRepository:   orderRepository,
+		paymentProvider:   paymentProvider,
+	}
+}
+
+func (ps *paymentService) PayOrder(invoice string, customerID string, createdBy string) (res *models.Response, err error) {
+	order, err := ps.orderRepository.GetMyOrderByInvoice(invoice, customerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Order not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	if order.OrderStatus != models.OrderStatusPending {
+		return &models.Response{
+			Code:    http.StatusConflict,
+			Message: "Order is " + order.OrderStatus.String() + " and cannot be paid",
+		}, nil
+	}
+
+	intent, err := ps.paymentProvider.CreateIntent(context.Background(), payment.IntentRequest{
+		Reference: order.Invoice,
+		Amount:    order.Amount,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pay := models.Payment{
+		ID:            uuid.New().String(),
+		Invoice:       order.Invoice,
+		CustomerID:    order.CustomerID,
+		Provider:      ps.paymentProvider.Name(),
+		IntentID:      intent.ID,
+		Amount:        intent.Amount,
+		Currency:      intent.Amount.Currency(),
+		PaymentStatus: models.PaymentStatusPending,
+		Status:        models.StatusActive,
+		CreatedBy:     createdBy,
+	}
+	err = ps.paymentRepository.CreatePayment(&pay)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Payment already exist", "Order not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Payment created successfully",
+		Data:    models.PaymentIntentView{Payment: pay, ClientSecret: intent.ClientSecret},
+	}, nil
+}
+
+// ConfirmPayment asks the provider to capture the payment, the order is only paid once the provider webhook arrives
+func (ps *paymentService) ConfirmPayment(id string, customerID string) (res *models.Response, err error) {
+	pay, err := ps.paymentRepository.GetPaymentById(id, customerID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Payment not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	if pay.PaymentStatus != models.PaymentStatusPending {
+		return &models.Response{
+			Code:    http.StatusConflict,
+			Message: "Payment is " + pay.PaymentStatus.String(),
+		}, nil
+	}
+
+	intent, err := ps.paymentProvider.ConfirmIntent(context.Background(), pay.IntentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Payment confirmed successfully",
+		Data:    intent,
+	}, nil
+}
+
+func (ps *paymentService) HandleWebhook(payload []byte, signature string) (res *models.Response, err error) {
+	event, err := ps.paymentProvider.ParseWebhook(payload, signature)
+	if err != nil {
+		if err == payment.ErrInvalidSignature {
+			return &models.Response{
+				Code:    http.StatusUnauthorized,
+				Message: "Invalid signature",
+			}, nil
+		}
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: http.StatusText(http.StatusBadRequest),
+		}, nil
+	}
+
+	var paymentStatus models.PaymentStatus
+	switch event.Type {
+	case payment.EventPaymentSucceeded:
+		paymentStatus = models.PaymentStatusSucceeded
+	case payment.EventPaymentFailed:
+		paymentStatus = models.PaymentStatusFailed
+	case payment.EventPaymentRefunded:
+		paymentStatus = models.PaymentStatusRefunded
+	default:
+		return &models.Response{
+			Code:    http.StatusOK,
+			Message: "Event ignored",
+		}, nil
+	}
+
+	pay, err := ps.paymentRepository.TransactionPaymentEvent(event.IntentID, paymentStatus, event.Amount, ps.paymentProvider.Name())
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Payment not exist",
+			}, nil
+		}
+		if err == repositories.ErrPaymentAmountMismatch {
+			logger.Warnf("payment %s event %s amount %s does not match the payment", event.IntentID, event.ID, event.Amount)
+			return &models.Response{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Event amount does not match the payment",
+			}, nil
+		}
+
+		// the order was cancelled while the customer was paying, give the money back
+		var transitionErr *repositories.InvalidTransitionError
+		if errors.As(err, &transitionErr) {
+			logger.Warnf("payment %s succeeded for a %s order, refunding", event.IntentID, transitionErr.From)
+			if _, err := ps.paymentProvider.Refund(context.Background(), event.IntentID, event.Amount); err != nil {
+				return nil, err
+			}
+			return &models.Response{
+				Code:    http.StatusOK,
+				Message: "Payment refunded",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Payment " + pay.PaymentStatus.String(),
+	}, nil
+}
+
+// RetryRefunds refunds the payments of cancelled orders whose refund failed, they stay refund_pending until it succeeds
+func (ps *paymentService) RetryRefunds() error {
+	payments, err := ps.paymentRepository.GetRefundPendingPayments()
+	if err != nil {
+		return fmt.Errorf("error getting refund pending payments, %v", err)
+	}
+
+	for _, pay := range payments {
+		if err := refundPayment(ps.paymentProvider, ps.paymentRepository, pay, ps.paymentProvider.Name()); err != nil {
+			logger.Warnf("refund of payment %s is still pending: %v", pay.ID, err)
+		}
+	}
+	return nil
+}
+
+// refundPayment gives the refund_pending payment pay back through provider and marks it refunded
+func refundPayment(provider payment.PaymentProvider, paymentRepository repositories.PaymentRepositoryInterface, pay models.Payment, updatedBy string) error {
+	if _, err := provider.Refund(context.Background(), pay.IntentID, pay.Amount); err != nil {
+		return fmt.Errorf("error refunding payment %s, %v", pay.ID, err)
+	}
+	if err := paymentRepository.CompleteRefund(pay.ID, updatedBy); err != nil {
+		return fmt.Errorf("error completing refund of payment %s, %v", pay.ID, err)
+	}
+	return nil
+}
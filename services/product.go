@@ -1,40 +1,102 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"math"
 	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/logger"
+	"mvp-shop-backend/pkg/media"
+	"mvp-shop-backend/pkg/money"
+	"mvp-shop-backend/pkg/storage"
 	"mvp-shop-backend/pkg/utils"
 	"mvp-shop-backend/repositories"
 	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// maxQueryLength bounds the text of a product search
+const maxQueryLength = 200
+
+// slugPattern is a URL slug, lower case letters and digits separated by single dashes
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 type productService struct {
-	productRepository repositories.ProductRepositoryInterface
+	productRepository         repositories.ProductRepositoryInterface
+	productCategoryRepository repositories.ProductCategoryRepositoryInterface
+	productPriceRepository    repositories.ProductPriceRepositoryInterface
+	productVariantRepository  repositories.ProductVariantRepositoryInterface
+	productImageRepository    repositories.ProductImageRepositoryInterface
+	searcher                  repositories.Searcher
+	blobStore                 storage.BlobStore
+	mediaOptions              media.Options
 }
 
 type ProductServiceInterface interface {
 	CreateProduct(product *models.Product) (res *models.Response, err error)
-	GetProducts(filter map[string][]string) (res *models.Response, err error)
-	GetProductById(id string) (res *models.Response, err error)
+	GetProducts(filter map[string][]string, currency money.Currency) (res *models.Response, err error)
+	GetProductById(id string, currency money.Currency) (res *models.Response, err error)
 	UpdateProduct(product *models.ProductUpdate) (res *models.Response, err error)
 	DeleteProduct(product *models.ProductUpdate) (res *models.Response, err error)
+	SaveProductPrice(price *models.ProductPrice) (res *models.Response, err error)
+	DeleteProductPrice(productID string, currency money.Currency) (res *models.Response, err error)
+	CreateProductVariant(variant *models.ProductVariant) (res *models.Response, err error)
+	UpdateProductVariant(variant *models.ProductVariant) (res *models.Response, err error)
+	DeleteProductVariant(variant *models.ProductVariant) (res *models.Response, err error)
+	UploadProductImage(productID string, r io.Reader, createdBy string) (res *models.Response, err error)
+	ReorderProductImages(productID string, imageIDs []string) (res *models.Response, err error)
+	DeleteProductImage(productID string, imageID string) (res *models.Response, err error)
 }
 
-func NewProductService(productRepository repositories.ProductRepositoryInterface) ProductServiceInterface {
+func NewProductService(productRepository repositories.ProductRepositoryInterface, productCategoryRepository repositories.ProductCategoryRepositoryInterface, productPriceRepository repositories.ProductPriceRepositoryInterface, productVariantRepository repositories.ProductVariantRepositoryInterface, productImageRepository repositories.ProductImageRepositoryInterface, searcher repositories.Searcher, blobStore storage.BlobStore, mediaOptions media.Options) ProductServiceInterface {
 	return &productService{
-		productRepository: productRepository,
+		productRepository:         productRepository,
+		productCategoryRepository: productCategoryRepository,
+		productPriceRepository:    productPriceRepository,
+		productVariantRepository:  productVariantRepository,
+		productImageRepository:    productImageRepository,
+		searcher:                  searcher,
+		blobStore:                 blobStore,
+		mediaOptions:              mediaOptions,
 	}
 }
 
 func (ps *productService) CreateProduct(product *models.Product) (res *models.Response, err error) {
+	if res := validatePrice(product.Price); res != nil {
+		return res, nil
+	}
 	product.ID = uuid.New().String()
-	product.CreatedBy = "admin"
 	product.Status = models.StatusActive
+	if product.SKU != nil {
+		if sku := strings.TrimSpace(*product.SKU); sku != "" {
+			product.SKU = &sku
+		} else {
+			product.SKU = nil
+		}
+	}
+	generated := product.Slug == ""
+	if res, err := ps.validateProductDetails(product.Name, product.CategoryID, &product.Slug, product.Attributes); res != nil || err != nil {
+		return res, err
+	}
+
 	err = ps.productRepository.CreateProduct(product)
+	if err != nil && generated && errors.Is(err, gorm.ErrDuplicatedKey) {
+		// another product has the same name, its id tells them apart
+		product.Slug = fmt.Sprintf("%s-%s", product.Slug, product.ID[:8])
+		err = ps.productRepository.CreateProduct(product)
+	}
 	if err != nil {
+		if res, ok := constraintResponse(err, "Product SKU or slug already exist", "Product category not exist"); ok {
+			return res, nil
+		}
 		return nil, err
 	}
 
@@ -44,10 +106,33 @@ func (ps *productService) CreateProduct(product *models.Product) (res *models.Re
 	}, nil
 }
 
-func (ps *productService) GetProducts(filter map[string][]string) (res *models.Response, err error) {
+// GetProducts lists the products with their price in currency, with a q the products matching it are ranked by
+// relevance unless another sort field is given
+func (ps *productService) GetProducts(filter map[string][]string, currency money.Currency) (res *models.Response, err error) {
 
 	pagination, search := utils.GeneratePaginationFromRequest(filter)
-	products, count, err := ps.productRepository.GetProducts(pagination, search)
+
+	var query string
+	if q, ok := filter["q"]; ok && len(q) > 0 {
+		query = strings.TrimSpace(q[0])
+	}
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("Search query is longer than %d characters", maxQueryLength),
+		}, nil
+	}
+
+	var products []models.ProductView
+	var count int64
+	if query != "" {
+		if _, ok := filter["sort_field"]; !ok {
+			pagination.SortField = repositories.SortFieldRelevance
+		}
+		products, count, err = ps.searcher.SearchProducts(query, pagination, search)
+	} else {
+		products, count, err = ps.productRepository.GetProducts(pagination, search)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -59,6 +144,16 @@ func (ps *productService) GetProducts(filter map[string][]string) (res *models.R
 		}, nil
 	}
 
+	if _, res, err := ps.priceProducts(products, currency); res != nil || err != nil {
+		return res, err
+	}
+	if err := ps.addImages(products); err != nil {
+		return nil, err
+	}
+	if err := ps.addBreadcrumbs(products); err != nil {
+		return nil, err
+	}
+
 	data := models.ListProduct{
 		Page:      pagination.Page,
 		Limit:     pagination.Limit,
@@ -74,7 +169,8 @@ func (ps *productService) GetProducts(filter map[string][]string) (res *models.R
 	}, nil
 }
 
-func (ps *productService) GetProductById(id string) (res *models.Response, err error) {
+// GetProductById returns the product with its variant matrix, priced in currency
+func (ps *productService) GetProductById(id string, currency money.Currency) (res *models.Response, err error) {
 
 	product, err := ps.productRepository.GetProductById(id)
 	if err != nil {
@@ -87,16 +183,64 @@ func (ps *productService) GetProductById(id string) (res *models.Response, err e
 		return nil, err
 	}
 
+	variants, err := ps.productVariantRepository.GetProductVariants(product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	base := product.Price
+	products := []models.ProductView{product}
+	priceList, res, err := ps.priceProducts(products, currency)
+	if res != nil || err != nil {
+		return res, err
+	}
+	if err := ps.addImages(products); err != nil {
+		return nil, err
+	}
+	if err := ps.addBreadcrumbs(products); err != nil {
+		return nil, err
+	}
+
+	view := models.ProductDetailView{
+		ProductView: products[0],
+		Options:     models.VariantMatrix(variants),
+		Variants:    make([]models.ProductVariantView, len(variants)),
+	}
+	for i, variant := range variants {
+		price, err := priceList.VariantPrice(product.ID, base, variant.Price)
+		if err != nil {
+			return nil, err
+		}
+		view.Variants[i] = models.ProductVariantView{
+			ID:      variant.ID,
+			SKU:     variant.SKU,
+			Options: variant.Options,
+			Price:   price,
+			Stock:   variant.Stock,
+		}
+	}
+
 	return &models.Response{
 		Code:    http.StatusOK,
 		Message: "Product get successfully",
-		Data:    product,
+		Data:    view,
 	}, nil
 }
 
 func (ps *productService) UpdateProduct(product *models.ProductUpdate) (res *models.Response, err error) {
+	if res := validatePrice(product.Price); res != nil {
+		return res, nil
+	}
+	product.SKU = strings.TrimSpace(product.SKU)
+	if res, err := ps.validateProductDetails(product.Name, product.CategoryID, &product.Slug, product.Attributes); res != nil || err != nil {
+		return res, err
+	}
+
 	err = ps.productRepository.UpdateProduct(product)
 	if err != nil {
+		if res, ok := constraintResponse(err, "Product SKU or slug already exist", "Product category not exist"); ok {
+			return res, nil
+		}
 		return nil, err
 	}
 
@@ -106,14 +250,444 @@ func (ps *productService) UpdateProduct(product *models.ProductUpdate) (res *mod
 	}, nil
 }
 
+// DeleteProduct deletes the product with its images and their blobs
 func (ps *productService) DeleteProduct(product *models.ProductUpdate) (res *models.Response, err error) {
 	err = ps.productRepository.DeleteProduct(product)
 	if err != nil {
 		return nil, err
 	}
 
+	images, err := ps.productImageRepository.DeleteProductImages(product.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		ps.deleteImageBlobs(image)
+	}
+
 	return &models.Response{
 		Code:    http.StatusOK,
 		Message: "Product deleted successfully",
 	}, nil
 }
+
+// SaveProductPrice sets the price of price.ProductID in price.Currency, it replaces the converted default currency price
+func (ps *productService) SaveProductPrice(price *models.ProductPrice) (res *models.Response, err error) {
+	if res := validateForeignCurrency(price.Currency); res != nil {
+		return res, nil
+	}
+	if price.Price.Currency() != price.Currency {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Price must be in " + price.Currency.String(),
+		}, nil
+	}
+	if price.Price.IsNegative() {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Price must not be negative",
+		}, nil
+	}
+
+	err = ps.productPriceRepository.SaveProductPrice(price)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Product price already exist", "Product not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Product price saved successfully",
+		Data:    price,
+	}, nil
+}
+
+// DeleteProductPrice removes the price of productID in currency, the product is then sold at its converted price
+func (ps *productService) DeleteProductPrice(productID string, currency money.Currency) (res *models.Response, err error) {
+	err = ps.productPriceRepository.DeleteProductPrice(productID, currency.String())
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Product price not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Product price deleted successfully",
+	}, nil
+}
+
+// validateProductDetails makes the slug of a product from its name when it is empty and checks its attributes against
+// the attribute schema of its category
+func (ps *productService) validateProductDetails(name string, categoryID string, slug *string, attributes models.ProductAttributes) (res *models.Response, err error) {
+	if *slug == "" {
+		*slug = utils.GenerateSlug(name)
+	} else if !slugPattern.MatchString(*slug) {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Slug must be lower case letters and digits separated by dashes",
+		}, nil
+	}
+
+	category, err := ps.productCategoryRepository.GetProductCategoryById(categoryID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusUnprocessableEntity,
+				Message: "Product category not exist",
+			}, nil
+		}
+		return nil, err
+	}
+	if err := category.AttributeSchema.Validate(attributes); err != nil {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid attributes, " + err.Error(),
+		}, nil
+	}
+	return nil, nil
+}
+
+// priceProducts replaces the default currency price of products with their price in currency, it returns the price
+// list of products
+func (ps *productService) priceProducts(products []models.ProductView, currency money.Currency) (priceList models.PriceList, res *models.Response, err error) {
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+
+	priceList, err = ps.productPriceRepository.GetPriceList(currency, ids)
+	if err != nil {
+		if err == repositories.ErrCurrencyNotSupported {
+			return priceList, currencyNotSupportedResponse(), nil
+		}
+		return priceList, nil, err
+	}
+
+	for i, product := range products {
+		if products[i].Price, err = priceList.Price(product.ID, product.Price); err != nil {
+			return priceList, nil, err
+		}
+	}
+	return priceList, nil, nil
+}
+
+func (ps *productService) CreateProductVariant(variant *models.ProductVariant) (res *models.Response, err error) {
+	variant.ID = uuid.New().String()
+	variant.Status = models.StatusActive
+	if res, err := ps.validateProductVariant(variant); res != nil || err != nil {
+		return res, err
+	}
+
+	err = ps.productVariantRepository.CreateProductVariant(variant)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Variant SKU or options already exist", "Product not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Product variant created successfully",
+		Data:    variant,
+	}, nil
+}
+
+// UpdateProductVariant replaces the variant variant.ID, orders keep the price they were created with
+func (ps *productService) UpdateProductVariant(variant *models.ProductVariant) (res *models.Response, err error) {
+	if res, err := ps.validateProductVariant(variant); res != nil || err != nil {
+		return res, err
+	}
+
+	err = ps.productVariantRepository.UpdateProductVariant(variant)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return productVariantNotFoundResponse(), nil
+		}
+		if res, ok := constraintResponse(err, "Variant SKU or options already exist", "Product not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Product variant updated successfully",
+	}, nil
+}
+
+func (ps *productService) DeleteProductVariant(variant *models.ProductVariant) (res *models.Response, err error) {
+	err = ps.productVariantRepository.DeleteProductVariant(variant)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return productVariantNotFoundResponse(), nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Product variant deleted successfully",
+	}, nil
+}
+
+// validateProductVariant trims the options of variant and refuses options other than the ones of the other variants of
+// the product, the variant matrix would have holes otherwise
+func (ps *productService) validateProductVariant(variant *models.ProductVariant) (res *models.Response, err error) {
+	options := make(models.VariantOptions, len(variant.Options))
+	for name, value := range variant.Options {
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if name == "" || value == "" || len(name) > 50 || len(value) > 50 {
+			return &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Option names and values must have 1 to 50 characters",
+			}, nil
+		}
+		options[name] = value
+	}
+	variant.Options = options
+
+	if variant.Price != nil {
+		if res := validatePrice(*variant.Price); res != nil {
+			return res, nil
+		}
+	}
+
+	variants, err := ps.productVariantRepository.GetProductVariants(variant.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	names := strings.Join(variant.Options.Names(), ", ")
+	for _, v := range variants {
+		if v.ID == variant.ID {
+			continue
+		}
+		if want := strings.Join(v.Options.Names(), ", "); want != names {
+			return &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Variant options must be " + want,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// UploadProductImage stores the image read from r with its thumbnail and adds it after the other images of productID
+func (ps *productService) UploadProductImage(productID string, r io.Reader, createdBy string) (res *models.Response, err error) {
+	img, err := media.Read(r, ps.mediaOptions.MaxSize)
+	if err != nil {
+		if res := imageErrorResponse(err, ps.mediaOptions.MaxSize); res != nil {
+			return res, nil
+		}
+		return nil, err
+	}
+	thumbnail, err := media.Thumbnail(img, ps.mediaOptions.ThumbnailSize)
+	if err != nil {
+		if res := imageErrorResponse(err, ps.mediaOptions.MaxSize); res != nil {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	image := models.ProductImage{
+		ID:           id,
+		ProductID:    productID,
+		Key:          fmt.Sprintf("products/%s/%s%s", productID, id, img.Extension()),
+		ThumbnailKey: fmt.Sprintf("products/%s/%s_thumb%s", productID, id, thumbnail.Extension()),
+		ContentType:  img.ContentType,
+		Size:         int64(len(img.Data)),
+		Width:        img.Width,
+		Height:       img.Height,
+		CreatedBy:    createdBy,
+	}
+
+	ctx := context.Background()
+	if err := ps.blobStore.Put(ctx, image.Key, bytes.NewReader(img.Data), image.Size, img.ContentType); err != nil {
+		return nil, fmt.Errorf("error storing image, %v", err)
+	}
+	if err := ps.blobStore.Put(ctx, image.ThumbnailKey, bytes.NewReader(thumbnail.Data), int64(len(thumbnail.Data)), thumbnail.ContentType); err != nil {
+		ps.deleteImageBlobs(image)
+		return nil, fmt.Errorf("error storing thumbnail, %v", err)
+	}
+
+	err = ps.productImageRepository.CreateProductImage(&image)
+	if err != nil {
+		ps.deleteImageBlobs(image)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Product not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Product image uploaded successfully",
+		Data:    ps.imageView(image),
+	}, nil
+}
+
+// ReorderProductImages orders the images of productID like imageIDs, which must list every one of them
+func (ps *productService) ReorderProductImages(productID string, imageIDs []string) (res *models.Response, err error) {
+	err = ps.productImageRepository.ReorderProductImages(productID, imageIDs)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Product not exist",
+			}, nil
+		}
+		if err == repositories.ErrImageOrderMismatch {
+			return &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: "Image order must list every image of the product once",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Product images reordered successfully",
+	}, nil
+}
+
+func (ps *productService) DeleteProductImage(productID string, imageID string) (res *models.Response, err error) {
+	image, err := ps.productImageRepository.DeleteProductImage(productID, imageID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Product image not exist",
+			}, nil
+		}
+		return nil, err
+	}
+	ps.deleteImageBlobs(image)
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Product image deleted successfully",
+	}, nil
+}
+
+// addImages sets the ordered images of products
+func (ps *productService) addImages(products []models.ProductView) error {
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	images, err := ps.productImageRepository.GetProductImages(ids)
+	if err != nil {
+		return err
+	}
+
+	views := make(map[string][]models.ProductImageView, len(products))
+	for _, image := range images {
+		views[image.ProductID] = append(views[image.ProductID], ps.imageView(image))
+	}
+	for i, product := range products {
+		products[i].Images = views[product.ID]
+		if products[i].Images == nil {
+			products[i].Images = []models.ProductImageView{}
+		}
+	}
+	return nil
+}
+
+// addBreadcrumbs sets the category path of products from the root category
+func (ps *productService) addBreadcrumbs(products []models.ProductView) error {
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.CategoryID)
+	}
+	paths, err := ps.productCategoryRepository.GetCategoryPaths(ids)
+	if err != nil {
+		return err
+	}
+
+	for i, product := range products {
+		products[i].Breadcrumbs = paths[product.CategoryID]
+		if products[i].Breadcrumbs == nil {
+			products[i].Breadcrumbs = []models.CategoryCrumb{}
+		}
+	}
+	return nil
+}
+
+func (ps *productService) imageView(image models.ProductImage) models.ProductImageView {
+	return models.ProductImageView{
+		ID:           image.ID,
+		URL:          ps.blobStore.URL(image.Key),
+		ThumbnailURL: ps.blobStore.URL(image.ThumbnailKey),
+		ContentType:  image.ContentType,
+		Width:        image.Width,
+		Height:       image.Height,
+		Position:     image.Position,
+	}
+}
+
+// deleteImageBlobs deletes the blobs of image, a failure only leaves an orphan blob behind so it is logged
+func (ps *productService) deleteImageBlobs(image models.ProductImage) {
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		if err := ps.blobStore.Delete(context.Background(), key); err != nil {
+			logger.Err(fmt.Sprintf("error deleting blob %s, %v", key, err))
+		}
+	}
+}
+
+// imageErrorResponse answers the upload of an image media refuses, nil is returned for other errors
+func imageErrorResponse(err error, maxSize int64) *models.Response {
+	switch err {
+	case media.ErrTooLarge:
+		return &models.Response{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("Image must not be larger than %d bytes or %d pixels", maxSize, media.MaxPixels),
+		}
+	case media.ErrUnsupportedType:
+		return &models.Response{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: "Image must be a JPEG, PNG or GIF",
+		}
+	case media.ErrInvalidImage:
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Image cannot be decoded",
+		}
+	}
+	return nil
+}
+
+func productVariantNotFoundResponse() *models.Response {
+	return &models.Response{
+		Code:    http.StatusNotFound,
+		Message: "Product variant not exist",
+	}
+}
+
+// validatePrice refuses negative prices and prices in another currency than the shop one
+func validatePrice(price money.Money) *models.Response {
+	if price.IsNegative() {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Price must not be negative",
+		}
+	}
+	if c := price.Currency(); c != "" && c != money.DefaultCurrency() {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Price must be in " + money.DefaultCurrency().String(),
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"math"
 	"mvp-shop-backend/models"
 	"mvp-shop-backend/pkg/utils"
@@ -19,6 +20,7 @@ type ProductCategoryServiceInterface interface {
 	CreateProductCategory(productCategory *models.ProductCategory) (res *models.Response, err error)
 	GetProductCategories(filter map[string][]string) (res *models.Response, err error)
 	GetProductCategoryById(id string) (res *models.Response, err error)
+	GetProductCategoryTree() (res *models.Response, err error)
 	UpdateProductCategory(productCategory *models.ProductCategoryUpdate) (res *models.Response, err error)
 	DeleteProductCategory(productCategory *models.ProductCategoryUpdate) (res *models.Response, err error)
 }
@@ -30,11 +32,16 @@ func NewProductCategoryService(productCategoryRepository repositories.ProductCat
 }
 
 func (ps *productCategoryService) CreateProductCategory(productCategory *models.ProductCategory) (res *models.Response, err error) {
+	if res := validateAttributeSchema(productCategory.AttributeSchema); res != nil {
+		return res, nil
+	}
 	productCategory.ID = uuid.New().String()
-	productCategory.CreatedBy = "admin"
 	productCategory.Status = models.StatusActive
 	err = ps.productCategoryRepository.CreateProductCategory(productCategory)
 	if err != nil {
+		if res := categoryHierarchyResponse(err); res != nil {
+			return res, nil
+		}
 		return nil, err
 	}
 
@@ -94,9 +101,34 @@ func (ps *productCategoryService) GetProductCategoryById(id string) (res *models
 	}, nil
 }
 
+// GetProductCategoryTree returns the categories nested under their parents
+func (ps *productCategoryService) GetProductCategoryTree() (res *models.Response, err error) {
+	categories, err := ps.productCategoryRepository.GetProductCategoryTree()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "ProductCategory tree successfully",
+		Data:    models.CategoryTree(categories),
+	}, nil
+}
+
+// UpdateProductCategory replaces the category, an empty parent makes it a root category. Products keep their
+// attributes until they are updated
 func (ps *productCategoryService) UpdateProductCategory(productCategory *models.ProductCategoryUpdate) (res *models.Response, err error) {
+	if res := validateAttributeSchema(productCategory.AttributeSchema); res != nil {
+		return res, nil
+	}
+	if productCategory.ParentID != nil && *productCategory.ParentID == "" {
+		productCategory.ParentID = nil
+	}
 	err = ps.productCategoryRepository.UpdateProductCategory(productCategory)
 	if err != nil {
+		if res := categoryHierarchyResponse(err); res != nil {
+			return res, nil
+		}
 		return nil, err
 	}
 
@@ -109,6 +141,9 @@ func (ps *productCategoryService) UpdateProductCategory(productCategory *models.
 func (ps *productCategoryService) DeleteProductCategory(productCategory *models.ProductCategoryUpdate) (res *models.Response, err error) {
 	err = ps.productCategoryRepository.DeleteProductCategory(productCategory)
 	if err != nil {
+		if res := categoryHierarchyResponse(err); res != nil {
+			return res, nil
+		}
 		return nil, err
 	}
 
@@ -117,3 +152,30 @@ func (ps *productCategoryService) DeleteProductCategory(productCategory *models.
 		Message: "ProductCategory deleted successfully",
 	}, nil
 }
+
+func validateAttributeSchema(schema models.AttributeSchema) *models.Response {
+	if err := schema.Check(); err != nil {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid attribute schema, " + err.Error(),
+		}
+	}
+	return nil
+}
+
+// categoryHierarchyResponse maps the errors of the category repository, nil is returned for other errors
+func categoryHierarchyResponse(err error) *models.Response {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &models.Response{Code: http.StatusNotFound, Message: "ProductCategory not exist"}
+	case errors.Is(err, repositories.ErrParentCategoryNotFound):
+		return &models.Response{Code: http.StatusUnprocessableEntity, Message: "Parent category not exist"}
+	case errors.Is(err, repositories.ErrCategoryCycle):
+		return &models.Response{Code: http.StatusBadRequest, Message: "Parent category cannot be the category or one of its subcategories"}
+	case errors.Is(err, repositories.ErrCategoryHasChildren):
+		return &models.Response{Code: http.StatusConflict, Message: "ProductCategory has subcategories"}
+	case errors.Is(err, repositories.ErrCategoryHasProducts):
+		return &models.Response{Code: http.StatusConflict, Message: "ProductCategory has products"}
+	}
+	return nil
+}
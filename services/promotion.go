@@ -0,0 +1,199 @@
+package services
+
+import (
+	"math"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/money"
+	"mvp-shop-backend/pkg/utils"
+	"mvp-shop-backend/repositories"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type promotionService struct {
+	promotionRepository repositories.PromotionRepositoryInterface
+}
+
+type PromotionServiceInterface interface {
+	CreatePromotion(promotion *models.Promotion) (res *models.Response, err error)
+	GetPromotions(filter map[string][]string) (res *models.Response, err error)
+	GetPromotionById(id string) (res *models.Response, err error)
+	UpdatePromotion(promotion *models.Promotion) (res *models.Response, err error)
+	DeletePromotion(id string, updatedBy string) (res *models.Response, err error)
+}
+
+func NewPromotionService(promotionRepository repositories.PromotionRepositoryInterface) PromotionServiceInterface {
+	return &promotionService{
+		promotionRepository: promotionRepository,
+	}
+}
+
+func (ps *promotionService) CreatePromotion(promotion *models.Promotion) (res *models.Response, err error) {
+	promotion.ID = uuid.New().String()
+	promotion.Status = models.StatusActive
+	if res := validatePromotion(promotion); res != nil {
+		return res, nil
+	}
+
+	err = ps.promotionRepository.CreatePromotion(promotion)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Coupon code already exist", "Product or category not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Promotion created successfully",
+		Data:    promotion,
+	}, nil
+}
+
+func (ps *promotionService) GetPromotions(filter map[string][]string) (res *models.Response, err error) {
+	pagination, search := utils.GeneratePaginationFromRequest(filter)
+	promotions, count, err := ps.promotionRepository.GetPromotions(pagination, search)
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return &models.Response{
+			Code:    http.StatusNotFound,
+			Message: http.StatusText(http.StatusNotFound),
+		}, nil
+	}
+
+	data := models.ListPromotion{
+		Page:       pagination.Page,
+		Limit:      pagination.Limit,
+		Total:      int(count),
+		TotalPage:  int(math.Ceil(float64(count) / float64(pagination.Limit))),
+		Promotions: promotions,
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Promotion list successfully",
+		Data:    data,
+	}, nil
+}
+
+func (ps *promotionService) GetPromotionById(id string) (res *models.Response, err error) {
+	promotion, err := ps.promotionRepository.GetPromotionById(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Promotion not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Promotion get successfully",
+		Data:    promotion,
+	}, nil
+}
+
+// UpdatePromotion replaces the promotion promotion.ID, orders keep the discount they were created with
+func (ps *promotionService) UpdatePromotion(promotion *models.Promotion) (res *models.Response, err error) {
+	if res := validatePromotion(promotion); res != nil {
+		return res, nil
+	}
+
+	err = ps.promotionRepository.UpdatePromotion(promotion)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Promotion not exist",
+			}, nil
+		}
+		if res, ok := constraintResponse(err, "Coupon code already exist", "Product or category not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Promotion updated successfully",
+	}, nil
+}
+
+func (ps *promotionService) DeletePromotion(id string, updatedBy string) (res *models.Response, err error) {
+	err = ps.promotionRepository.DeletePromotion(id, updatedBy)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Promotion not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Promotion deleted successfully",
+	}, nil
+}
+
+// validatePromotion upper-cases the code of promotion and checks the fields its type needs
+func validatePromotion(promotion *models.Promotion) *models.Response {
+	badRequest := func(message string) *models.Response {
+		return &models.Response{
+			Code:    http.StatusBadRequest,
+			Message: message,
+		}
+	}
+
+	promotion.Code = strings.ToUpper(strings.TrimSpace(promotion.Code))
+	for _, r := range promotion.Code {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return badRequest("Code can only have letters, digits, - and _")
+		}
+	}
+
+	switch promotion.Type {
+	case models.PromotionTypePercentage:
+		if promotion.Percent.IsZero() || promotion.Percent.Cmp(money.MustParsePercent("100")) > 0 {
+			return badRequest("Percent must be over 0 and at most 100")
+		}
+	case models.PromotionTypeFixed:
+		if !promotion.Amount.IsPositive() {
+			return badRequest("Amount must be positive")
+		}
+	case models.PromotionTypeBuyXGetY:
+		if promotion.BuyQty <= 0 || promotion.GetQty <= 0 {
+			return badRequest("Buy qty and get qty must be positive")
+		}
+	default:
+		return badRequest("Type must be percentage, fixed or buy_x_get_y")
+	}
+
+	for _, amount := range []money.Money{promotion.Amount, promotion.MinSpend} {
+		if amount.IsNegative() {
+			return badRequest("Amount and min spend must not be negative")
+		}
+		if c := amount.Currency(); c != "" && c != money.DefaultCurrency() {
+			return badRequest("Amount and min spend must be in " + money.DefaultCurrency().String())
+		}
+	}
+
+	if promotion.StartsAt != nil && promotion.EndsAt != nil && !promotion.EndsAt.After(*promotion.StartsAt) {
+		return badRequest("Ends at must be after starts at")
+	}
+	for _, limit := range []*int{promotion.UsageLimit, promotion.UsageLimitPerCustomer} {
+		if limit != nil && *limit <= 0 {
+			return badRequest("Usage limits must be positive")
+		}
+	}
+	return nil
+}
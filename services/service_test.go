@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/pkg/mailer"
+	"mvp-shop-backend/pkg/utils"
+	"mvp-shop-backend/repositories"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestMain(m *testing.M) {
+	os.Setenv("JWT_ALGORITHM", "HS256")
+	os.Setenv("SECRET_KEY", "services-test-secret-of-at-least-32-bytes")
+	os.Exit(m.Run())
+}
+
+// fakeCustomerRepository keeps customers by id, the methods a test doesn't need panic through the embedded interface
+type fakeCustomerRepository struct {
+	repositories.CustomerRepositoryInterface
+	mu        sync.Mutex
+	customers map[string]models.Customer
+}
+
+func (fr *fakeCustomerRepository) GetCustomerByEmail(email string) (models.Customer, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	for _, customer := range fr.customers {
+		if customer.Email == email {
+			return customer, nil
+		}
+	}
+	return models.Customer{}, gorm.ErrRecordNotFound
+}
+
+func (fr *fakeCustomerRepository) GetCustomerById(id string) (models.Customer, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	customer, ok := fr.customers[id]
+	if !ok {
+		return models.Customer{}, gorm.ErrRecordNotFound
+	}
+	return customer, nil
+}
+
+func (fr *fakeCustomerRepository) GetCustomerPassword(id string) (string, error) {
+	customer, err := fr.GetCustomerById(id)
+	if err != nil || customer.Status == models.StatusDeleted {
+		return "", gorm.ErrRecordNotFound
+	}
+	return customer.Password, nil
+}
+
+func (fr *fakeCustomerRepository) UpdateCustomerPassword(id string, password string, updatedBy string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.setPassword(id, password)
+	return nil
+}
+
+// setPassword sets the password of the customer of id and bumps their token version, fr.mu has to be held
+func (fr *fakeCustomerRepository) setPassword(id string, password string) {
+	customer := fr.customers[id]
+	customer.Password = password
+	customer.TokenVersion++
+	fr.customers[id] = customer
+}
+
+func (fr *fakeCustomerRepository) RehashCustomerPassword(id string, oldPassword string, password string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	if customer, ok := fr.customers[id]; ok && customer.Password == oldPassword {
+		customer.Password = password
+		fr.customers[id] = customer
+	}
+	return nil
+}
+
+// fakeTokenRepository keeps refresh tokens by hash and the deny-listed access tokens by jti, the token versions are
+// read from customerRepository
+type fakeTokenRepository struct {
+	mu                 sync.Mutex
+	refreshTokens      map[string]models.RefreshToken
+	revoked            map[string]time.Time
+	customerRepository *fakeCustomerRepository
+}
+
+func newFakeTokenRepository(customerRepository *fakeCustomerRepository) *fakeTokenRepository {
+	return &fakeTokenRepository{
+		refreshTokens:      map[string]models.RefreshToken{},
+		revoked:            map[string]time.Time{},
+		customerRepository: customerRepository,
+	}
+}
+
+func (fr *fakeTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.refreshTokens[token.TokenHash] = *token
+	return nil
+}
+
+func (fr *fakeTokenRepository) RotateRefreshToken(tokenHash string, next *models.RefreshToken) (models.RefreshToken, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	current, ok := fr.refreshTokens[tokenHash]
+	if !ok {
+		return current, gorm.ErrRecordNotFound
+	}
+	if current.RevokedAt != nil {
+		return current, repositories.ErrRefreshTokenReused
+	}
+	now := time.Now()
+	current.RevokedAt = &now
+	current.ReplacedBy = &next.ID
+	fr.refreshTokens[tokenHash] = current
+
+	next.CustomerID = current.CustomerID
+	next.FamilyID = current.FamilyID
+	fr.refreshTokens[next.TokenHash] = *next
+	return current, nil
+}
+
+func (fr *fakeTokenRepository) RevokeRefreshTokenFamily(tokenHash string, customerID string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	current, ok := fr.refreshTokens[tokenHash]
+	if !ok || current.CustomerID != customerID {
+		return gorm.ErrRecordNotFound
+	}
+	fr.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.FamilyID == current.FamilyID })
+	return nil
+}
+
+func (fr *fakeTokenRepository) RevokeCustomerRefreshTokens(customerID string) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.CustomerID == customerID })
+	return nil
+}
+
+func (fr *fakeTokenRepository) revokeRefreshTokens(match func(token models.RefreshToken) bool) {
+	now := time.Now()
+	for hash, token := range fr.refreshTokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			fr.refreshTokens[hash] = token
+		}
+	}
+}
+
+func (fr *fakeTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.revoked[jti] = expiresAt
+	return nil
+}
+
+func (fr *fakeTokenRepository) IsRevoked(token *models.CustomerClaims) (bool, error) {
+	fr.mu.Lock()
+	_, ok := fr.revoked[token.Id]
+	fr.mu.Unlock()
+	if ok {
+		return true, nil
+	}
+
+	customer, err := fr.customerRepository.GetCustomerById(token.ID)
+	if err != nil {
+		return false, nil
+	}
+	return customer.TokenVersion > token.TokenVersion, nil
+}
+
+// fakeCustomerTokenRepository keeps customer tokens by hash, a password reset is written to customerRepository
+type fakeCustomerTokenRepository struct {
+	mu                 sync.Mutex
+	tokens             map[string]models.CustomerToken
+	customerRepository *fakeCustomerRepository
+}
+
+func (fr *fakeCustomerTokenRepository) CreateCustomerToken(token *models.CustomerToken) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	now := time.Now()
+	for hash, other := range fr.tokens {
+		if other.CustomerID == token.CustomerID && other.Purpose == token.Purpose && other.UsedAt == nil {
+			other.UsedAt = &now
+			fr.tokens[hash] = other
+		}
+	}
+	fr.tokens[token.TokenHash] = *token
+	return nil
+}
+
+func (fr *fakeCustomerTokenRepository) use(tokenHash string, purpose models.CustomerTokenPurpose) (models.CustomerToken, error) {
+	token, ok := fr.tokens[tokenHash]
+	if !ok || token.Purpose != purpose || token.UsedAt != nil || token.ExpiresAt.Before(time.Now()) {
+		return token, repositories.ErrCustomerTokenInvalid
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	fr.tokens[tokenHash] = token
+	return token, nil
+}
+
+func (fr *fakeCustomerTokenRepository) TransactionVerifyEmail(tokenHash string) (models.CustomerToken, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.use(tokenHash, models.CustomerTokenVerifyEmail)
+}
+
+func (fr *fakeCustomerTokenRepository) TransactionResetPassword(tokenHash string, password string) (models.CustomerToken, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	token, err := fr.use(tokenHash, models.CustomerTokenResetPassword)
+	if err != nil {
+		return token, err
+	}
+
+	fr.customerRepository.mu.Lock()
+	defer fr.customerRepository.mu.Unlock()
+	fr.customerRepository.setPassword(token.CustomerID, password)
+	return token, nil
+}
+
+// recordingMailer keeps the messages it is asked to send
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (rm *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.messages = append(rm.messages, msg)
+	return nil
+}
+
+type fakeLoginAuditRepository struct{}
+
+func (fakeLoginAuditRepository) CreateLoginAudit(audit *models.LoginAudit) error {
+	return nil
+}
+
+// testCustomer returns an active customer of password whose email is already verified
+func testCustomer(t *testing.T, id string, password string) models.Customer {
+	t.Helper()
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifiedAt := time.Now()
+	return models.Customer{
+		ID:              id,
+		Email:           id + "@example.com",
+		Name:            id,
+		Password:        hash,
+		Role:            models.RoleCustomer,
+		Status:          models.StatusActive,
+		CreatedBy:       "test",
+		EmailVerifiedAt: &verifiedAt,
+	}
+}
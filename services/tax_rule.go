@@ -0,0 +1,135 @@
+package services
+
+import (
+	"mvp-shop-backend/models"
+	"mvp-shop-backend/repositories"
+	"net/http"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type taxRuleService struct {
+	taxRuleRepository repositories.TaxRuleRepositoryInterface
+}
+
+type TaxRuleServiceInterface interface {
+	GetTaxRules() (res *models.Response, err error)
+	CreateTaxRule(rule *models.TaxRule) (res *models.Response, err error)
+	UpdateTaxRule(rule *models.TaxRule) (res *models.Response, err error)
+	DeleteTaxRule(id string) (res *models.Response, err error)
+}
+
+func NewTaxRuleService(taxRuleRepository repositories.TaxRuleRepositoryInterface) TaxRuleServiceInterface {
+	return &taxRuleService{
+		taxRuleRepository: taxRuleRepository,
+	}
+}
+
+func (ts *taxRuleService) GetTaxRules() (res *models.Response, err error) {
+	rules, err := ts.taxRuleRepository.GetTaxRules()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Tax rule list successfully",
+		Data:    rules,
+	}, nil
+}
+
+func (ts *taxRuleService) CreateTaxRule(rule *models.TaxRule) (res *models.Response, err error) {
+	rule.ID = uuid.New().String()
+	if res, err := ts.validateTaxRule(rule); res != nil || err != nil {
+		return res, err
+	}
+
+	err = ts.taxRuleRepository.CreateTaxRule(rule)
+	if err != nil {
+		if res, ok := constraintResponse(err, "Tax rule already exist", "Product category not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusCreated,
+		Message: "Tax rule created successfully",
+		Data:    rule,
+	}, nil
+}
+
+// UpdateTaxRule replaces the rule rule.ID, orders keep the tax they were created with
+func (ts *taxRuleService) UpdateTaxRule(rule *models.TaxRule) (res *models.Response, err error) {
+	if res, err := ts.validateTaxRule(rule); res != nil || err != nil {
+		return res, err
+	}
+
+	err = ts.taxRuleRepository.UpdateTaxRule(rule)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Tax rule not exist",
+			}, nil
+		}
+		if res, ok := constraintResponse(err, "Tax rule already exist", "Product category not exist"); ok {
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Tax rule updated successfully",
+	}, nil
+}
+
+func (ts *taxRuleService) DeleteTaxRule(id string) (res *models.Response, err error) {
+	err = ts.taxRuleRepository.DeleteTaxRule(id)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &models.Response{
+				Code:    http.StatusNotFound,
+				Message: "Tax rule not exist",
+			}, nil
+		}
+		return nil, err
+	}
+
+	return &models.Response{
+		Code:    http.StatusOK,
+		Message: "Tax rule deleted successfully",
+	}, nil
+}
+
+// validateTaxRule normalizes the region of rule and refuses a second rule for the same region and category, the
+// rule of a product would then depend on the order they are read in
+func (ts *taxRuleService) validateTaxRule(rule *models.TaxRule) (res *models.Response, err error) {
+	if rule.Region != nil {
+		region, err := models.ParseRegion(*rule.Region)
+		if err != nil {
+			return &models.Response{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			}, nil
+		}
+		rule.Region = &region
+	}
+	if rule.CategoryID != nil && *rule.CategoryID == "" {
+		rule.CategoryID = nil
+	}
+
+	exists, err := ts.taxRuleRepository.TaxRuleExists(rule)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return &models.Response{
+			Code:    http.StatusConflict,
+			Message: "Tax rule already exist",
+		}, nil
+	}
+	return nil, nil
+}